@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"github.com/tmozzze/SasPosts/internal/archive"
+	"github.com/tmozzze/SasPosts/internal/config"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/cache"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+	"github.com/tmozzze/SasPosts/internal/repository/postgres"
+	"github.com/tmozzze/SasPosts/internal/repository/sqlite"
+)
+
+const usage = `usage:
+  saspostsctl export [-o file]
+  saspostsctl import [-i file] [-on-conflict skip|overwrite]
+
+Хранилище выбирается так же, как у сервера: DB_TYPE, PG_URL, REDIS_URL, SQLITE_PATH`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed load config %v", err)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		output := flags.String("o", "", "file to write, stdout by default")
+		flags.Parse(os.Args[2:])
+
+		postRepo, commentRepo, closeRepos := openRepositories(ctx, cfg)
+		defer closeRepos()
+
+		w := io.Writer(os.Stdout)
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				log.Fatalf("failed create %s %v", *output, err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		stats, err := archive.Export(ctx, w, postRepo, commentRepo)
+		if err != nil {
+			log.Fatalf("failed export %v", err)
+		}
+		log.Printf("exported %d posts, %d comments", stats.Posts, stats.Comments)
+
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		input := flags.String("i", "", "file to read, stdin by default")
+		onConflict := flags.String("on-conflict", string(archive.ConflictSkip), "skip or overwrite existing posts")
+		flags.Parse(os.Args[2:])
+
+		conflict, err := archive.ParseConflict(*onConflict)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r := io.Reader(os.Stdin)
+		if *input != "" {
+			file, err := os.Open(*input)
+			if err != nil {
+				log.Fatalf("failed open %s %v", *input, err)
+			}
+			defer file.Close()
+			r = file
+		}
+
+		postRepo, commentRepo, closeRepos := openRepositories(ctx, cfg)
+		defer closeRepos()
+
+		stats, err := archive.Import(ctx, r, postRepo, commentRepo, conflict)
+		if err != nil {
+			log.Fatalf("failed import %v", err)
+		}
+		log.Printf("imported %d posts, %d comments, skipped %d posts, %d comments",
+			stats.Posts, stats.Comments, stats.SkippedPosts, stats.SkippedComments)
+
+	default:
+		log.Fatal(usage)
+	}
+}
+
+// openRepositories открывает хранилище по DB_TYPE. Кэш сервера сбрасывается
+// через тот же Redis, чтобы после импорта не отдавались старые страницы
+func openRepositories(ctx context.Context, cfg *config.Config) (repository.PostRepository, repository.CommentRepository, func()) {
+	switch cfg.DBType {
+	case "postgres":
+		dbpool, err := pgxpool.New(ctx, cfg.PGURL)
+		if err != nil {
+			log.Fatalf("failed connect to postgres %v", err)
+		}
+		if err := dbpool.Ping(ctx); err != nil {
+			log.Fatalf("failed connect to postgres %v", err)
+		}
+
+		var postRepo repository.PostRepository = postgres.NewPostgresPostRepository(dbpool)
+		var commentRepo repository.CommentRepository = postgres.NewPostgresCommentRepository(dbpool)
+		closeRepos := dbpool.Close
+
+		if cfg.CacheEnabled {
+			opt, err := redis.ParseURL(cfg.RedisURL)
+			if err != nil {
+				log.Fatalf("Could not parse Redis URL %v", err)
+			}
+			redisClient := redis.NewClient(opt)
+			repoCache := cache.NewCache(redisClient, cfg.CacheTTL)
+			postRepo = cache.NewCachedPostRepository(postRepo, repoCache)
+			commentRepo = cache.NewCachedCommentRepository(commentRepo, repoCache)
+			closeRepos = func() {
+				redisClient.Close()
+				dbpool.Close()
+			}
+		}
+		return postRepo, commentRepo, closeRepos
+
+	case "sqlite":
+		db, err := sqlite.Open(ctx, cfg.SQLitePath)
+		if err != nil {
+			log.Fatalf("failed open sqlite %v", err)
+		}
+		return sqlite.NewSQLitePostRepository(db), sqlite.NewSQLiteCommentRepository(db), func() {
+			db.Close()
+		}
+
+	default:
+		if cfg.InMemoryDataDir == "" {
+			log.Println("use in-memory: data lives only in this process, set INMEMORY_DATA_DIR")
+			postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+			return postRepo, commentRepo, func() {}
+		}
+
+		// каталог, открытый сервером, заблокирован: OpenStore вернет ErrStoreLocked
+		policy, err := inmemory.ParseSyncPolicy(cfg.InMemoryFsync)
+		if err != nil {
+			log.Fatalf("failed parse fsync policy %v", err)
+		}
+		store, err := inmemory.OpenStore(cfg.InMemoryDataDir, policy)
+		if err != nil {
+			log.Fatalf("failed open in-memory store %v", err)
+		}
+		return store.Posts, store.Comments, func() {
+			if err := store.Close(); err != nil {
+				log.Printf("failed close in-memory store %v", err)
+			}
+		}
+	}
+}
@@ -2,25 +2,44 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/tmozzze/SasPosts/graph"
 	"github.com/tmozzze/SasPosts/graph/generated"
 	"github.com/tmozzze/SasPosts/internal/config"
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/markdown"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+	"github.com/tmozzze/SasPosts/internal/ranking"
+	"github.com/tmozzze/SasPosts/internal/ratelimit"
 	myRedis "github.com/tmozzze/SasPosts/internal/redis"
 	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/cache"
 	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
 	"github.com/tmozzze/SasPosts/internal/repository/postgres"
+	"github.com/tmozzze/SasPosts/internal/repository/sqlite"
+	"github.com/tmozzze/SasPosts/internal/scheduler"
+	"github.com/tmozzze/SasPosts/internal/webhook"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -45,6 +64,16 @@ func main() {
 
 	var postRepo repository.PostRepository
 	var commentRepo repository.CommentRepository
+	var units repository.UnitOfWork
+	var notificationRepo repository.NotificationRepository
+	var webhookRepo repository.WebhookRepository
+	var schedulerLock scheduler.Locker
+	var ranker ranking.Ranker
+	var limiter ratelimit.Limiter
+	var idempotencyStore idempotency.Store
+	var apqCache graphql.Cache[string]
+	var store *inmemory.Store
+	storeDone := make(chan struct{})
 
 	switch cfg.DBType {
 	case "postgres":
@@ -63,23 +92,189 @@ func main() {
 
 		postRepo = postgres.NewPostgresPostRepository(dbpool)
 		commentRepo = postgres.NewPostgresCommentRepository(dbpool)
+		units = postgres.NewUnitOfWork(dbpool)
+		notificationRepo = postgres.NewPostgresNotificationRepository(dbpool)
+		webhookRepo = postgres.NewPostgresWebhookRepository(dbpool)
+		// публикует только та реплика, которая взяла блокировку
+		schedulerLock = postgres.NewAdvisoryLock(dbpool, scheduler.LockKey)
+
+		if cfg.CacheEnabled {
+			repoCache := cache.NewCache(redisClient, cfg.CacheTTL)
+			postRepo = cache.NewCachedPostRepository(postRepo, repoCache)
+			commentRepo = cache.NewCachedCommentRepository(commentRepo, repoCache)
+			// статистика не для публичного доступа, включается только для отладки
+			if cfg.CacheDebug {
+				http.Handle("/debug/cache", repoCache)
+			}
+		}
+
+		limiter = ratelimit.NewRedisLimiter(redisClient)
+		idempotencyStore = idempotency.NewRedisStore(redisClient)
+		ranker = ranking.NewRedisRanker(redisClient)
+		apqCache = myRedis.NewQueryCache(redisClient, cfg.APQTTL)
+
+	case "sqlite":
+		log.Println("Use sqlite")
+		db, err := sqlite.Open(ctx, cfg.SQLitePath)
+		if err != nil {
+			log.Fatalf("failed open sqlite %v", err)
+		}
+		defer db.Close()
+
+		log.Printf("Posts and comments are stored in %s", cfg.SQLitePath)
+		postRepo = sqlite.NewSQLitePostRepository(db)
+		commentRepo = sqlite.NewSQLiteCommentRepository(db)
+		units = sqlite.NewUnitOfWork(db)
+		// остальное хранится в памяти, как у in-memory: база рассчитана на один процесс
+		notificationRepo = inmemory.NewInMemoryNotificationRepository()
+		webhookRepo = inmemory.NewInMemoryWebhookRepository()
+		schedulerLock = &scheduler.LocalLocker{}
+		limiter = ratelimit.NewMemoryLimiter()
+		idempotencyStore = idempotency.NewMemoryStore()
+		ranker = ranking.NewMemoryRanker()
+		apqCache = lru.New[string](cfg.APQCacheSize)
 
 	default:
 		log.Println("use in-memory")
-		postRepo = inmemory.NewInMemoryPostRepository()
-		commentRepo = inmemory.NewInMemoryCommentRepository()
+		var posts *inmemory.InMemoryPostRepository
+		var comments *inmemory.InMemoryCommentRepository
+		if cfg.InMemoryDataDir != "" {
+			policy, err := inmemory.ParseSyncPolicy(cfg.InMemoryFsync)
+			if err != nil {
+				log.Fatalf("failed parse fsync policy %v", err)
+			}
+			store, err = inmemory.OpenStore(cfg.InMemoryDataDir, policy)
+			if err != nil {
+				log.Fatalf("failed open in-memory store %v", err)
+			}
+			go func() {
+				defer close(storeDone)
+				store.Run(ctx, cfg.InMemorySyncInterval, cfg.InMemorySnapshotInterval)
+			}()
+
+			log.Printf("Posts and comments are persisted to %s", cfg.InMemoryDataDir)
+			posts, comments = store.Posts, store.Comments
+		} else {
+			posts, comments = inmemory.NewInMemoryRepositories()
+		}
+		postRepo, commentRepo = posts, comments
+		units = inmemory.NewUnitOfWork(posts)
+		notificationRepo = inmemory.NewInMemoryNotificationRepository()
+		webhookRepo = inmemory.NewInMemoryWebhookRepository()
+		schedulerLock = &scheduler.LocalLocker{}
+		limiter = ratelimit.NewMemoryLimiter()
+		idempotencyStore = idempotency.NewMemoryStore()
+		ranker = ranking.NewMemoryRanker()
+		apqCache = lru.New[string](cfg.APQCacheSize)
+	}
+
+	limits, err := ratelimit.ParseLimits(cfg.RateLimits)
+	if err != nil {
+		log.Fatalf("failed parse rate limits %v", err)
+	}
+
+	renderer, err := markdown.NewRenderer(cfg.MarkdownCacheSize)
+	if err != nil {
+		log.Fatalf("failed create markdown renderer %v", err)
+	}
+
+	resolver := graph.NewResolver(postRepo, commentRepo, redisPublisher,
+		graph.WithRateLimit(ratelimit.NewPolicy(limiter, limits)),
+		graph.WithMarkdown(renderer),
+		graph.WithNotifications(notificationRepo),
+		graph.WithWebhooks(webhookRepo),
+		graph.WithRanking(ranker),
+		graph.WithMaxPins(cfg.MaxPinsPerPost),
+		graph.WithUnitOfWork(units),
+		graph.WithIdempotency(idempotency.NewKeys(idempotencyStore, cfg.IdempotencyTTL)),
+	)
+
+	if cfg.WebhookWorkerEnabled {
+		worker := webhook.NewWorker(webhookRepo, webhook.WorkerConfig{
+			PollInterval: cfg.WebhookPollInterval,
+			BatchSize:    cfg.WebhookBatchSize,
+			MaxAttempts:  cfg.WebhookMaxAttempts,
+			BaseBackoff:  cfg.WebhookBackoffBase,
+			MaxBackoff:   cfg.WebhookBackoffMax,
+			Timeout:      cfg.WebhookTimeout,
+		})
+		go worker.Run(ctx)
 	}
 
-	resolver := graph.NewResolver(postRepo, commentRepo, redisPublisher)
+	postScheduler := scheduler.New(postRepo, schedulerLock, cfg.PostSchedulerInterval, resolver.PostPublished)
+	go postScheduler.Run(ctx)
 
-	server := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	server := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolver,
+		Complexity: graph.Complexity(cfg.MaxPageLimit),
+	}))
+	server.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		// браузер не может передать X-Author при подключении,
+		// поэтому автор для подписок берется из connection_init
+		InitFunc: func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+			if author := initPayload.GetString("author"); author != "" {
+				ctx = middleware.WithViewer(ctx, author)
+			}
+			return ctx, &initPayload, nil
+		},
+	})
+	server.AddTransport(transport.Options{})
+	server.AddTransport(transport.GET{})
+	server.AddTransport(transport.POST{})
+	server.AddTransport(transport.MultipartForm{})
+
+	server.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 	server.SetErrorPresenter(graph.ErrorPresenter)
 
+	if cfg.PersistedQueriesManifest != "" {
+		manifest, err := graph.LoadManifest(cfg.PersistedQueriesManifest)
+		if err != nil {
+			log.Fatalf("failed load persisted queries %v", err)
+		}
+		log.Printf("Loaded %d trusted operations", len(manifest))
+		server.Use(graph.TrustedDocuments{Manifest: manifest, Strict: cfg.PersistedQueriesStrict})
+	} else if cfg.PersistedQueriesStrict {
+		log.Fatal("PERSISTED_QUERIES_STRICT requires PERSISTED_QUERIES_MANIFEST")
+	}
+
+	// в строгом режиме клиенты не могут регистрировать свои запросы
+	if cfg.APQEnabled && !cfg.PersistedQueriesStrict {
+		server.Use(extension.AutomaticPersistedQuery{Cache: apqCache})
+	}
+	server.Use(extension.Introspection{})
+
+	server.Use(graph.StatsLoader{})
+	server.Use(graph.QueryLimits{MaxDepth: cfg.MaxQueryDepth, MaxPageLimit: cfg.MaxPageLimit})
+	server.Use(extension.FixedComplexityLimit(cfg.MaxQueryComplexity))
+
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", server)
+	http.Handle("/query", middleware.ClientIP(cfg.TrustProxy, middleware.Viewer(middleware.IdempotencyKey(server))))
+
+	httpServer := &http.Server{Addr: ":" + cfg.Port}
+	go func() {
+		log.Printf("Server on %s/ for GraphQL", cfg.Port)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("failed serve http %v", err)
+			stop()
+		}
+	}()
 
-	address := ":" + cfg.Port
-	log.Printf("Server on %s/ for GraphQL", cfg.Port)
-	log.Fatal(http.ListenAndServe(address, nil))
+	<-ctx.Done()
+	log.Println("Shutting down")
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed shutdown http server %v", err)
+	}
+
+	// после остановки запросов журнал больше не пишется, можно сжать его в снимок
+	if store != nil {
+		// Run не должен сжимать журнал одновременно с Close
+		<-storeDone
+		if err := store.Close(); err != nil {
+			log.Printf("failed close in-memory store %v", err)
+		}
+	}
 }
@@ -0,0 +1,343 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+)
+
+// defaultContextDepth - сколько предков показывать по постоянной ссылке на комментарий
+const defaultContextDepth = 3
+
+// checkThreadSettings проверяет новый комментарий по настройкам ветки поста
+// и помечает его как ожидающий одобрения, если пост этого требует
+func (r *Resolver) checkThreadSettings(ctx context.Context, comment *domain.Comment) error {
+	post, err := r.PostRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return err
+	}
+	// в архивный пост писать нельзя, а неопубликованного для комментаторов нет
+	switch post.Status {
+	case domain.PostArchived:
+		return domain.ErrCommentsOff
+	case domain.PostDraft, domain.PostScheduled:
+		return domain.ErrPostNotFound
+	}
+	settings := post.Settings
+
+	depth := 0
+	if comment.ParentID != nil {
+		parent, err := r.CommentRepo.GetByID(ctx, *comment.ParentID)
+		if err != nil {
+			if errors.Is(err, domain.ErrCommentNotFound) {
+				return domain.ErrParentCommentNotFound
+			}
+			return err
+		}
+		// неодобренный комментарий виден только автору поста
+		if parent.Pending && middleware.ViewerFromContext(ctx) != post.Author {
+			return domain.ErrParentCommentNotFound
+		}
+		depth = parent.Depth + 1
+	}
+
+	var lastCommentAt time.Time
+	if settings.SlowModeSeconds > 0 {
+		lastCommentAt, err = r.CommentRepo.LastCommentTime(ctx, comment.PostID, comment.Author)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := settings.CheckComment(comment.Content, depth, lastCommentAt, time.Now()); err != nil {
+		return err
+	}
+
+	comment.Pending = settings.RequireApproval
+	return nil
+}
+
+// updateThreadSettings меняет настройки ветки от имени автора поста
+func (r *Resolver) updateThreadSettings(ctx context.Context, postID string, input model.ThreadSettingsInput) (*domain.Post, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := r.ownPost(ctx, postID, author, domain.ErrPostNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := post.Settings
+	if input.MaxReplyDepth != nil {
+		settings.MaxReplyDepth = *input.MaxReplyDepth
+	}
+	if input.MaxCommentLength != nil {
+		settings.MaxCommentLength = *input.MaxCommentLength
+	}
+	if input.SlowModeSeconds != nil {
+		settings.SlowModeSeconds = *input.SlowModeSeconds
+	}
+	if input.RequireApproval != nil {
+		settings.RequireApproval = *input.RequireApproval
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := r.PostRepo.UpdateSettings(ctx, postID, settings); err != nil {
+		return nil, err
+	}
+	return r.PostRepo.GetByID(ctx, postID)
+}
+
+// approveComment снимает комментарий с модерации от имени автора поста
+func (r *Resolver) approveComment(ctx context.Context, commentID string) (*domain.Comment, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.CommentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ownPost(ctx, comment.PostID, author, domain.ErrCommentNotFound); err != nil {
+		return nil, err
+	}
+
+	comment, approved, err := r.CommentRepo.Approve(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// повторное одобрение не рассылает комментарий еще раз
+	if approved {
+		r.publishComment(ctx, comment)
+	}
+
+	return comment, nil
+}
+
+// publishComment рассылает видимый комментарий подписчикам поста,
+// уведомляет авторов и ставит в очередь вебхуки
+func (r *Resolver) publishComment(ctx context.Context, comment *domain.Comment) {
+	channelName := fmt.Sprintf("comments:%s", comment.PostID)
+	r.PubSub.Publish(ctx, channelName, comment)
+
+	r.notifyAuthors(ctx, comment)
+	r.publishPostWebhook(ctx, domain.EventCommentCreated, comment.PostID, comment)
+	r.rankComment(ctx, comment)
+}
+
+func commentMovedChannel(postID string) string {
+	return fmt.Sprintf("comments:moved:%s", postID)
+}
+
+// moveComment переносит ветку комментариев от имени автора постов
+// и оповещает подписчиков старого и нового поста
+func (r *Resolver) moveComment(ctx context.Context, commentID string, newParentID *string, newPostID *string) (*domain.Comment, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if (newParentID == nil) == (newPostID == nil) {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{
+			{Field: "newParentId", Message: "exactly one of newParentId and newPostId is required"},
+		}}
+	}
+
+	var move *model.CommentMove
+	var comment *domain.Comment
+	err = r.atomically(ctx, func(ctx context.Context) error {
+		// репозиторий в памяти меняет комментарий на месте, поэтому старое место копируется
+		old, err := r.CommentRepo.GetByID(ctx, commentID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.ownPost(ctx, old.PostID, author, domain.ErrCommentNotFound); err != nil {
+			return err
+		}
+		move = &model.CommentMove{FromPostID: old.PostID, FromParentID: old.ParentID}
+
+		postID := ""
+		if newPostID != nil {
+			postID = *newPostID
+		}
+		if err := r.checkMoveTarget(ctx, old, newParentID, postID, author); err != nil {
+			return err
+		}
+
+		comment, err = r.CommentRepo.Move(ctx, commentID, newParentID, postID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	move.Comment = comment
+
+	r.PubSub.Publish(ctx, commentMovedChannel(move.FromPostID), move)
+	if comment.PostID != move.FromPostID {
+		r.PubSub.Publish(ctx, commentMovedChannel(comment.PostID), move)
+	}
+	return comment, nil
+}
+
+// ownPost возвращает пост, если его автор - author. Чужой невидимый пост
+// считается ненайденным и дает notFound
+func (r *Resolver) ownPost(ctx context.Context, postID, author string, notFound error) (*domain.Post, error) {
+	post, err := r.PostRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(author) {
+		return nil, notFound
+	}
+	if post.Author != author {
+		return nil, domain.ErrForbidden
+	}
+	return post, nil
+}
+
+// checkMoveTarget проверяет новое место ветки так же, как новый ответ:
+// пост открыт для комментариев, ветка не закрыта и не глубже MaxReplyDepth
+func (r *Resolver) checkMoveTarget(ctx context.Context, comment *domain.Comment, newParentID *string, postID, author string) error {
+	var parent *domain.Comment
+	if newParentID != nil {
+		var err error
+		parent, err = r.CommentRepo.GetByID(ctx, *newParentID)
+		if errors.Is(err, domain.ErrCommentNotFound) || (err == nil && parent.Pending) {
+			return domain.ErrParentCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		postID = parent.PostID
+	}
+
+	// цикл проверяется и в репозитории, здесь - чтобы не сообщать о глубине вместо него
+	if _, _, err := comment.MoveUnder(parent); err != nil {
+		return err
+	}
+
+	post, err := r.ownPost(ctx, postID, author, domain.ErrPostNotFound)
+	if err != nil {
+		return err
+	}
+	switch post.Status {
+	case domain.PostArchived:
+		return domain.ErrCommentsOff
+	case domain.PostDraft, domain.PostScheduled:
+		return domain.ErrPostNotFound
+	}
+	if !post.AllowComments {
+		return domain.ErrCommentsOff
+	}
+
+	if parent == nil {
+		return nil
+	}
+
+	locked, err := r.CommentRepo.ThreadLocked(ctx, parent.ID)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return domain.ErrThreadLocked
+	}
+
+	maxDepth := post.Settings.MaxReplyDepth
+	if maxDepth <= 0 {
+		return nil
+	}
+	height, err := r.subtreeHeight(ctx, comment)
+	if err != nil {
+		return err
+	}
+	if parent.Depth+1+height > maxDepth {
+		return &domain.ReplyTooDeepError{MaxDepth: maxDepth}
+	}
+	return nil
+}
+
+// subtreeHeight возвращает, на сколько уровней ответы уходят ниже comment
+func (r *Resolver) subtreeHeight(ctx context.Context, comment *domain.Comment) (int, error) {
+	comments, err := r.CommentRepo.ListByPost(ctx, comment.PostID)
+	if err != nil {
+		return 0, err
+	}
+
+	height := 0
+	for _, c := range comments {
+		if strings.HasPrefix(c.Path, comment.Path+".") && c.Depth-comment.Depth > height {
+			height = c.Depth - comment.Depth
+		}
+	}
+	return height, nil
+}
+
+// lockThread закрывает или открывает ветку от имени автора поста
+func (r *Resolver) lockThread(ctx context.Context, commentID string, locked bool) (*domain.Comment, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.CommentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ownPost(ctx, comment.PostID, author, domain.ErrCommentNotFound); err != nil {
+		return nil, err
+	}
+
+	return r.CommentRepo.SetLocked(ctx, commentID, locked)
+}
+
+// pinComment закрепляет или открепляет комментарий от имени автора поста
+// и оповещает подписчиков поста
+func (r *Resolver) pinComment(ctx context.Context, commentID string, pinned bool) (*domain.Comment, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.CommentRepo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Pending {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	if _, err := r.ownPost(ctx, comment.PostID, author, domain.ErrCommentNotFound); err != nil {
+		return nil, err
+	}
+
+	if pinned {
+		maxPins := r.MaxPins
+		if maxPins <= 0 {
+			maxPins = domain.DefaultMaxPins
+		}
+		comment, err = r.CommentRepo.Pin(ctx, commentID, maxPins)
+	} else {
+		comment, err = r.CommentRepo.Unpin(ctx, commentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// подписчики commentAdded получают комментарий с новым isPinned
+	channelName := fmt.Sprintf("comments:%s", comment.PostID)
+	r.PubSub.Publish(ctx, channelName, comment)
+	return comment, nil
+}
@@ -0,0 +1,625 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+	redisMocks "github.com/tmozzze/SasPosts/internal/redis/mocks"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func TestQuery_Comment(t *testing.T) {
+	ctx := context.Background()
+	postRepo := inmemory.NewInMemoryPostRepository()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	resolver := &Resolver{PostRepo: postRepo, CommentRepo: commentRepo}
+
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	// ветка глубиной 5: thread[0] - корень
+	var thread []*domain.Comment
+	var parentID *string
+	for i := 0; i < 5; i++ {
+		comment, err := domain.NewComment(post.ID, "a", parentID, "text")
+		require.NoError(t, err)
+		require.NoError(t, commentRepo.Create(ctx, comment))
+		thread = append(thread, comment)
+		parentID = &comment.ID
+	}
+	leaf := thread[4]
+
+	t.Run("comment with context", func(t *testing.T) {
+		depth := 2
+		result, err := resolver.Query().Comment(ctx, leaf.ID, &depth)
+		require.NoError(t, err)
+
+		assert.Equal(t, leaf, result.Comment)
+		assert.Equal(t, []*domain.Comment{thread[2], thread[3]}, result.Ancestors)
+		assert.True(t, result.HasMoreAncestors)
+	})
+
+	t.Run("context deeper than thread", func(t *testing.T) {
+		depth := 10
+		result, err := resolver.Query().Comment(ctx, thread[1].ID, &depth)
+		require.NoError(t, err)
+
+		assert.Equal(t, []*domain.Comment{thread[0]}, result.Ancestors)
+		assert.False(t, result.HasMoreAncestors)
+	})
+
+	t.Run("comment fields", func(t *testing.T) {
+		parent, err := resolver.Comment().Parent(ctx, leaf)
+		require.NoError(t, err)
+		assert.Equal(t, thread[3], parent)
+
+		ancestors, err := resolver.Comment().Ancestors(ctx, leaf)
+		require.NoError(t, err)
+		assert.Equal(t, thread[:4], ancestors)
+
+		found, err := resolver.Comment().Post(ctx, leaf)
+		require.NoError(t, err)
+		assert.Equal(t, post.ID, found.ID)
+
+		parent, err = resolver.Comment().Parent(ctx, thread[0])
+		require.NoError(t, err)
+		assert.Nil(t, parent)
+	})
+
+	t.Run("error, if comment is hidden", func(t *testing.T) {
+		pending, err := domain.NewComment(post.ID, "a", nil, "text")
+		require.NoError(t, err)
+		pending.Pending = true
+		require.NoError(t, commentRepo.Create(ctx, pending))
+
+		_, err = resolver.Query().Comment(ctx, pending.ID, nil)
+		assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+		_, err = resolver.Query().Comment(ctx, "missing", nil)
+		assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	})
+}
+
+func TestMutation_MoveComment(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	postRepo := inmemory.NewInMemoryPostRepository()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	resolver := &Resolver{PostRepo: postRepo, CommentRepo: commentRepo, PubSub: mockPublisher}
+
+	post := testPost()
+	other := testPost()
+	other.ID = "post-456"
+	require.NoError(t, postRepo.Create(ctx, post))
+	require.NoError(t, postRepo.Create(ctx, other))
+
+	create := func(postID string, parentID *string, author string) *domain.Comment {
+		comment, err := domain.NewComment(postID, author, parentID, "text")
+		require.NoError(t, err)
+		require.NoError(t, commentRepo.Create(ctx, comment))
+		return comment
+	}
+	// репозиторий отдает копии, поэтому после переноса комментарии перечитываются
+	reload := func(comment *domain.Comment) *domain.Comment {
+		current, err := commentRepo.GetByID(ctx, comment.ID)
+		require.NoError(t, err)
+		return current
+	}
+	root := create(post.ID, nil, "a")
+	branch := create(post.ID, &root.ID, "b")
+	leaf := create(post.ID, &branch.ID, "c")
+	target := create(post.ID, nil, "a")
+
+	t.Run("error, if viewer is not author of both posts", func(t *testing.T) {
+		_, err := resolver.Mutation().MoveComment(middleware.WithViewer(context.Background(), "b"), branch.ID, &target.ID, nil)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		foreign := testPost()
+		foreign.ID, foreign.Author = "post-foreign", "b"
+		require.NoError(t, postRepo.Create(ctx, foreign))
+		_, err = resolver.Mutation().MoveComment(ctx, branch.ID, nil, &foreign.ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("error, if target does not accept replies", func(t *testing.T) {
+		closed := testPost()
+		closed.ID, closed.AllowComments = "post-closed", false
+		require.NoError(t, postRepo.Create(ctx, closed))
+		result, err := resolver.Mutation().MoveComment(ctx, branch.ID, nil, &closed.ID)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "COMMENT_OFF", result.UserErrors[0].Code)
+
+		lockedPost := testPost()
+		lockedPost.ID = "post-locked"
+		require.NoError(t, postRepo.Create(ctx, lockedPost))
+		locked := create(lockedPost.ID, nil, "a")
+		_, err = commentRepo.SetLocked(ctx, locked.ID, true)
+		require.NoError(t, err)
+		result, err = resolver.Mutation().MoveComment(ctx, branch.ID, &locked.ID, nil)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "THREAD_LOCKED", result.UserErrors[0].Code)
+
+		shallow := testPost()
+		shallow.ID = "post-shallow"
+		shallow.Settings.MaxReplyDepth = 1
+		require.NoError(t, postRepo.Create(ctx, shallow))
+		shallowRoot := create(shallow.ID, nil, "a")
+		// leaf окажется на глубине 2
+		result, err = resolver.Mutation().MoveComment(ctx, branch.ID, &shallowRoot.ID, nil)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "REPLY_TOO_DEEP", result.UserErrors[0].Code)
+	})
+
+	t.Run("error, if moved under own reply", func(t *testing.T) {
+		result, err := resolver.Mutation().MoveComment(ctx, branch.ID, &leaf.ID, nil)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "COMMENT_CYCLE", result.UserErrors[0].Code)
+
+		result, err = resolver.Mutation().MoveComment(ctx, branch.ID, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "VALIDATION_FAILED", result.UserErrors[0].Code)
+	})
+
+	t.Run("move subtree under new parent", func(t *testing.T) {
+		mockPublisher.On("Publish", mock.Anything, "comments:moved:"+post.ID, mock.AnythingOfType("*model.CommentMove")).Return(nil).Once()
+
+		result, err := resolver.Mutation().MoveComment(ctx, branch.ID, &target.ID, nil)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+
+		branch, leaf = reload(branch), reload(leaf)
+		assert.Equal(t, target.Path+"."+branch.ID, branch.Path)
+		assert.Equal(t, branch.Path+"."+leaf.ID, leaf.Path)
+		assert.Equal(t, 2, leaf.Depth)
+
+		stats, err := commentRepo.CommentStats(ctx, root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{}, stats)
+		stats, err = commentRepo.CommentStats(ctx, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 2}, stats)
+	})
+
+	t.Run("split subtree into another post", func(t *testing.T) {
+		mockPublisher.On("Publish", mock.Anything, "comments:moved:"+post.ID, mock.AnythingOfType("*model.CommentMove")).Return(nil).Once()
+		mockPublisher.On("Publish", mock.Anything, "comments:moved:"+other.ID, mock.AnythingOfType("*model.CommentMove")).Return(nil).Once()
+
+		result, err := resolver.Mutation().MoveComment(ctx, branch.ID, nil, &other.ID)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+
+		branch, leaf = reload(branch), reload(leaf)
+		assert.Nil(t, branch.ParentID)
+		assert.Equal(t, 0, branch.Depth)
+		assert.Equal(t, other.ID, leaf.PostID)
+		assert.Equal(t, 1, leaf.Depth)
+
+		postStats, err := commentRepo.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, postStats.CommentCount)
+		assert.Equal(t, 1, postStats.ParticipantCount)
+		otherStats, err := commentRepo.PostStats(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, otherStats.CommentCount)
+		assert.Equal(t, 2, otherStats.ParticipantCount)
+	})
+}
+
+func TestMutation_LockThread(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	postRepo := inmemory.NewInMemoryPostRepository()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	resolver := &Resolver{PostRepo: postRepo, CommentRepo: commentRepo, PubSub: mockPublisher}
+
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	create := func(parentID *string) *domain.Comment {
+		comment, err := domain.NewComment(post.ID, "a", parentID, "text")
+		require.NoError(t, err)
+		require.NoError(t, commentRepo.Create(ctx, comment))
+		return comment
+	}
+	root := create(nil)
+	branch := create(&root.ID)
+	leaf := create(&branch.ID)
+
+	locked, err := resolver.Mutation().LockThread(ctx, branch.ID)
+	require.NoError(t, err)
+	assert.True(t, locked.Locked)
+
+	t.Run("lock is inherited", func(t *testing.T) {
+		isLocked, err := resolver.Comment().IsLocked(ctx, leaf)
+		require.NoError(t, err)
+		assert.True(t, isLocked)
+
+		isLocked, err = resolver.Comment().IsLocked(ctx, root)
+		require.NoError(t, err)
+		assert.False(t, isLocked)
+	})
+
+	t.Run("error, if reply is under locked comment", func(t *testing.T) {
+		result, err := resolver.Mutation().CreateComment(ctx, model.NewCommentInput{PostID: post.ID, ParentID: &leaf.ID, Author: "b", Content: "text"})
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "THREAD_LOCKED", result.UserErrors[0].Code)
+	})
+
+	t.Run("reply after unlock", func(t *testing.T) {
+		mockPublisher.On("Publish", mock.Anything, mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
+
+		_, err := resolver.Mutation().UnlockThread(ctx, branch.ID)
+		require.NoError(t, err)
+
+		result, err := resolver.Mutation().CreateComment(ctx, model.NewCommentInput{PostID: post.ID, ParentID: &leaf.ID, Author: "b", Content: "text"})
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.Equal(t, 3, result.Comment.Depth)
+	})
+
+	t.Run("error, if comment does not exist", func(t *testing.T) {
+		_, err := resolver.Mutation().LockThread(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	})
+
+	t.Run("error, if viewer is not post author", func(t *testing.T) {
+		_, err := resolver.Mutation().LockThread(middleware.WithViewer(context.Background(), "b"), root.ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		_, err = resolver.Mutation().UnlockThread(context.Background(), root.ID)
+		assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	})
+}
+
+func TestMutation_PinComment(t *testing.T) {
+	authorCtx := middleware.WithViewer(context.Background(), "a")
+	otherCtx := middleware.WithViewer(context.Background(), "b")
+	postRepo := inmemory.NewInMemoryPostRepository()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	resolver := &Resolver{PostRepo: postRepo, CommentRepo: commentRepo, PubSub: mockPublisher, MaxPins: 2}
+
+	post := testPost()
+	require.NoError(t, postRepo.Create(authorCtx, post))
+
+	var comments []*domain.Comment
+	for i := 0; i < 3; i++ {
+		comment, err := domain.NewComment(post.ID, "b", nil, "text")
+		require.NoError(t, err)
+		require.NoError(t, commentRepo.Create(authorCtx, comment))
+		comments = append(comments, comment)
+	}
+
+	t.Run("error, if viewer is not post author", func(t *testing.T) {
+		_, err := resolver.Mutation().PinComment(otherCtx, comments[0].ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("pinned comments in pin order", func(t *testing.T) {
+		mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Times(2)
+
+		for _, comment := range []*domain.Comment{comments[2], comments[0]} {
+			result, err := resolver.Mutation().PinComment(authorCtx, comment.ID)
+			require.NoError(t, err)
+			require.Empty(t, result.UserErrors)
+
+			isPinned, err := resolver.Comment().IsPinned(authorCtx, result.Comment)
+			require.NoError(t, err)
+			assert.True(t, isPinned)
+		}
+
+		pinned, err := resolver.Post().PinnedComments(authorCtx, post)
+		require.NoError(t, err)
+		assert.Equal(t, []string{comments[2].ID, comments[0].ID}, commentIDs(pinned))
+	})
+
+	t.Run("error, if pin limit is reached", func(t *testing.T) {
+		result, err := resolver.Mutation().PinComment(authorCtx, comments[1].ID)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "TOO_MANY_PINS", result.UserErrors[0].Code)
+	})
+
+	t.Run("unpin frees a slot", func(t *testing.T) {
+		mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Times(2)
+
+		result, err := resolver.Mutation().UnpinComment(authorCtx, comments[2].ID)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.Nil(t, result.Comment.PinnedAt)
+
+		result, err = resolver.Mutation().PinComment(authorCtx, comments[1].ID)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+
+		pinned, err := resolver.Post().PinnedComments(authorCtx, post)
+		require.NoError(t, err)
+		assert.Equal(t, []string{comments[0].ID, comments[1].ID}, commentIDs(pinned))
+	})
+}
+
+// racyPostRepository запускает toggleComments сразу после проверки
+// allowComments и ждет его, пока тот не упрется в блокировку
+type racyPostRepository struct {
+	repository.PostRepository
+	afterCheck func()
+}
+
+func (r *racyPostRepository) CheckAllowedComments(ctx context.Context, postID string) (bool, error) {
+	allowed, err := r.PostRepository.CheckAllowedComments(ctx, postID)
+	r.afterCheck()
+	return allowed, err
+}
+
+func TestMutation_CreateCommentAtomic(t *testing.T) {
+	ctx := context.Background()
+	postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	toggled := make(chan error, 1)
+	slipped := false
+	racy := &racyPostRepository{PostRepository: postRepo, afterCheck: func() {
+		go func() {
+			toggled <- postRepo.ToggleComments(ctx, post.ID, false)
+		}()
+		select {
+		case err := <-toggled:
+			toggled <- err
+			slipped = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}}
+
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Once()
+	resolver := &Resolver{
+		PostRepo:    racy,
+		CommentRepo: commentRepo,
+		PubSub:      mockPublisher,
+		Units:       inmemory.NewUnitOfWork(postRepo),
+	}
+
+	input := model.NewCommentInput{PostID: post.ID, Author: "b", Content: "text"}
+	result, err := resolver.Mutation().CreateComment(ctx, input)
+	require.NoError(t, err)
+	require.Empty(t, result.UserErrors)
+	assert.False(t, slipped, "toggleComments finished between the check and the insert")
+
+	// toggleComments выполняется после единицы работы, и следующий комментарий отклоняется
+	require.NoError(t, <-toggled)
+	racy.afterCheck = func() {}
+	result, err = resolver.Mutation().CreateComment(ctx, input)
+	require.NoError(t, err)
+	require.Len(t, result.UserErrors, 1)
+	assert.Equal(t, "COMMENT_OFF", result.UserErrors[0].Code)
+}
+
+func TestMutation_CreateCommentIdempotent(t *testing.T) {
+	ctx := middleware.WithIdempotencyKey(context.Background(), "retry-1")
+	postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Times(4)
+	resolver := &Resolver{
+		PostRepo:    postRepo,
+		CommentRepo: commentRepo,
+		PubSub:      mockPublisher,
+		Idempotency: idempotency.NewKeys(idempotency.NewMemoryStore(), time.Hour),
+	}
+
+	input := model.NewCommentInput{PostID: post.ID, Author: "b", Content: "text"}
+	first, err := resolver.Mutation().CreateComment(ctx, input)
+	require.NoError(t, err)
+	require.Empty(t, first.UserErrors)
+
+	t.Run("retry returns original comment", func(t *testing.T) {
+		retry, err := resolver.Mutation().CreateComment(ctx, input)
+		require.NoError(t, err)
+		require.Empty(t, retry.UserErrors)
+		assert.Equal(t, first.Comment.ID, retry.Comment.ID)
+
+		stats, err := commentRepo.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.CommentCount)
+	})
+
+	t.Run("error, if key is reused with another payload", func(t *testing.T) {
+		changed := input
+		changed.Content = "other text"
+		_, err := resolver.Mutation().CreateComment(ctx, changed)
+		assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+
+		gqlErr := ErrorPresenter(ctx, err)
+		assert.Equal(t, "IDEMPOTENCY_KEY_REUSED", gqlErr.Extensions["code"])
+		assert.Equal(t, "idempotencyKey", gqlErr.Extensions["field"])
+	})
+
+	t.Run("input field overrides header", func(t *testing.T) {
+		key := "retry-2"
+		withKey := input
+		withKey.IdempotencyKey = &key
+		result, err := resolver.Mutation().CreateComment(ctx, withKey)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.NotEqual(t, first.Comment.ID, result.Comment.ID)
+
+		retry, err := resolver.Mutation().CreateComment(context.Background(), withKey)
+		require.NoError(t, err)
+		assert.Equal(t, result.Comment.ID, retry.Comment.ID)
+	})
+	t.Run("viewers do not share keys", func(t *testing.T) {
+		shared := model.NewCommentInput{PostID: post.ID, Author: "c", Content: "same text"}
+		ctxC := middleware.WithViewer(ctx, "c")
+		fromC, err := resolver.Mutation().CreateComment(ctxC, shared)
+		require.NoError(t, err)
+		require.Empty(t, fromC.UserErrors)
+
+		shared.Author = "d"
+		ctxD := middleware.WithViewer(ctx, "d")
+		fromD, err := resolver.Mutation().CreateComment(ctxD, shared)
+		require.NoError(t, err)
+		require.Empty(t, fromD.UserErrors)
+		assert.NotEqual(t, fromC.Comment.ID, fromD.Comment.ID)
+		assert.Equal(t, "d", fromD.Comment.Author)
+	})
+}
+
+func TestMutation_ApproveComment(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	comment, err := domain.NewComment(post.ID, "b", nil, "text")
+	require.NoError(t, err)
+	comment.Pending = true
+	require.NoError(t, commentRepo.Create(ctx, comment))
+
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Once()
+	resolver := &Resolver{PostRepo: postRepo, CommentRepo: commentRepo, PubSub: mockPublisher}
+
+	approved, err := resolver.Mutation().ApproveComment(ctx, comment.ID)
+	require.NoError(t, err)
+	assert.False(t, approved.Pending)
+
+	t.Run("repeated approve is not published again", func(t *testing.T) {
+		again, err := resolver.Mutation().ApproveComment(ctx, comment.ID)
+		require.NoError(t, err)
+		assert.False(t, again.Pending)
+	})
+
+	t.Run("error, if viewer is not post author", func(t *testing.T) {
+		held, err := domain.NewComment(post.ID, "c", nil, "text")
+		require.NoError(t, err)
+		held.Pending = true
+		require.NoError(t, commentRepo.Create(ctx, held))
+
+		_, err = resolver.Mutation().ApproveComment(middleware.WithViewer(ctx, "b"), held.ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		current, err := commentRepo.GetByID(ctx, held.ID)
+		require.NoError(t, err)
+		assert.True(t, current.Pending)
+	})
+
+	t.Run("error, if viewer is unknown", func(t *testing.T) {
+		_, err := resolver.Mutation().ApproveComment(context.Background(), comment.ID)
+		assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	})
+
+	t.Run("reply under pending comment", func(t *testing.T) {
+		held, err := domain.NewComment(post.ID, "c", nil, "text")
+		require.NoError(t, err)
+		held.Pending = true
+		require.NoError(t, commentRepo.Create(ctx, held))
+		input := model.NewCommentInput{PostID: post.ID, ParentID: &held.ID, Author: "b", Content: "text"}
+
+		_, err = resolver.Mutation().CreateComment(middleware.WithViewer(ctx, "b"), input)
+		assert.ErrorIs(t, err, domain.ErrParentCommentNotFound)
+
+		mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Once()
+		input.Author = "a"
+		result, err := resolver.Mutation().CreateComment(ctx, input)
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.Equal(t, 1, result.Comment.Depth)
+	})
+}
+
+func TestMutation_CreateCommentSlowModeConcurrent(t *testing.T) {
+	ctx := context.Background()
+	postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+	post := testPost()
+	post.Settings.SlowModeSeconds = 60
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, "comments:"+post.ID, mock.AnythingOfType("*domain.Comment")).Return(nil).Once()
+	resolver := &Resolver{
+		PostRepo:    postRepo,
+		CommentRepo: commentRepo,
+		PubSub:      mockPublisher,
+		Units:       inmemory.NewUnitOfWork(postRepo),
+	}
+
+	const attempts = 8
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			input := model.NewCommentInput{PostID: post.ID, Author: "b", Content: "text"}
+			_, errs[i] = resolver.Mutation().CreateComment(ctx, input)
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		var slowModeErr *domain.SlowModeError
+		assert.ErrorAs(t, err, &slowModeErr)
+	}
+	assert.Equal(t, 1, created, "slow mode must let only one comment through")
+}
+
+func TestMutation_UpdateThreadSettings(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	postRepo := inmemory.NewInMemoryPostRepository()
+	resolver := &Resolver{PostRepo: postRepo}
+
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+
+	slowMode := 30
+	updated, err := resolver.Mutation().UpdateThreadSettings(ctx, post.ID, model.ThreadSettingsInput{SlowModeSeconds: &slowMode})
+	require.NoError(t, err)
+	assert.Equal(t, 30, updated.Settings.SlowModeSeconds)
+
+	t.Run("error, if viewer is not post author", func(t *testing.T) {
+		other := 60
+		_, err := resolver.Mutation().UpdateThreadSettings(middleware.WithViewer(ctx, "b"), post.ID, model.ThreadSettingsInput{SlowModeSeconds: &other})
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		current, err := postRepo.GetByID(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 30, current.Settings.SlowModeSeconds)
+	})
+
+	t.Run("error, if viewer is unknown", func(t *testing.T) {
+		_, err := resolver.Mutation().UpdateThreadSettings(context.Background(), post.ID, model.ThreadSettingsInput{SlowModeSeconds: &slowMode})
+		assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	})
+}
+
+func commentIDs(comments []*domain.Comment) []string {
+	ids := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
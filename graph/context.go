@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+)
+
+// clientIP возвращает субъект для rate limit по ip клиента
+func clientIP(ctx context.Context) string {
+	ip := middleware.ClientIPFromContext(ctx)
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
+
+// viewer возвращает автора, от имени которого выполняется запрос
+func viewer(ctx context.Context) (string, error) {
+	author := middleware.ViewerFromContext(ctx)
+	if author == "" {
+		return "", domain.ErrUnauthenticated
+	}
+	return author, nil
+}
+
+// idempotencyKey возвращает ключ из input, а если его нет - из заголовка
+func idempotencyKey(ctx context.Context, field *string) string {
+	if field != nil {
+		if key := strings.TrimSpace(*field); key != "" {
+			return key
+		}
+	}
+	return middleware.IdempotencyKeyFromContext(ctx)
+}
+
+// caller возвращает, чьи ключи идемпотентности использует запрос: автора
+// запроса, автора из input или ip клиента
+func caller(ctx context.Context, author string) string {
+	if v := middleware.ViewerFromContext(ctx); v != "" {
+		return v
+	}
+	if author != "" {
+		return author
+	}
+	return clientIP(ctx)
+}
@@ -3,48 +3,130 @@ package graph
 import (
 	"context"
 	"errors"
+	"math"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/ratelimit"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	extensions := errorExtensions(err)
+	if extensions == nil {
+		return graphql.DefaultErrorPresenter(ctx, err)
+	}
+
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	gqlErr.Message = err.Error()
+	gqlErr.Extensions = extensions
+	return gqlErr
+}
+
+// errorExtensions сопоставляет доменную ошибку с кодом и деталями для клиента.
+// Для неизвестных ошибок возвращает nil
+func errorExtensions(err error) map[string]interface{} {
+	var (
+		validationErr *domain.ValidationError
+		tooLongErr    *domain.CommentTooLongError
+		tooDeepErr    *domain.ReplyTooDeepError
+		slowModeErr   *domain.SlowModeError
+		mentionsErr   *domain.TooManyMentionsError
+		pinsErr       *domain.TooManyPinsError
+		limitErr      *ratelimit.LimitError
+	)
 
-	if errors.Is(err, domain.ErrCommentTooLong) {
-		return &gqlerror.Error{
-			Message: err.Error(),
-			Extensions: map[string]interface{}{
-				"code":      "COMMENT_TOO_LONG",
-				"maxLength": domain.MaxCommentLength,
-			},
+	switch {
+	case errors.As(err, &validationErr):
+		fields := make([]map[string]interface{}, 0, len(validationErr.Fields))
+		for _, f := range validationErr.Fields {
+			fields = append(fields, map[string]interface{}{
+				"field":   f.Field,
+				"message": f.Message,
+			})
 		}
-	}
-	if errors.Is(err, domain.ErrCommentsOff) {
-		return &gqlerror.Error{
-			Message: err.Error(),
-			Extensions: map[string]interface{}{
-				"code": "COMMENT_OFF",
-			},
+		return map[string]interface{}{
+			"code":   "VALIDATION_FAILED",
+			"field":  validationErr.Fields[0].Field,
+			"fields": fields,
 		}
-	}
 
-	if errors.Is(err, domain.ErrPostNotFound) {
-		return &gqlerror.Error{
-			Message: err.Error(),
-			Extensions: map[string]interface{}{
-				"code": "POST_NOT_FOUND",
-			},
+	case errors.Is(err, domain.ErrCommentTooLong):
+		maxLength := domain.MaxCommentLength
+		if errors.As(err, &tooLongErr) {
+			maxLength = tooLongErr.MaxLength
 		}
-	}
-	if errors.Is(err, domain.ErrParentCommentNotFound) {
-		return &gqlerror.Error{
-			Message: err.Error(),
-			Extensions: map[string]interface{}{
-				"code": "COMMENT_PARENT_NOT_FOUND",
-			},
+		return map[string]interface{}{
+			"code":      "COMMENT_TOO_LONG",
+			"field":     "content",
+			"maxLength": maxLength,
+		}
+
+	case errors.As(err, &tooDeepErr):
+		return map[string]interface{}{
+			"code":          "REPLY_TOO_DEEP",
+			"maxReplyDepth": tooDeepErr.MaxDepth,
+		}
+
+	case errors.As(err, &slowModeErr):
+		return map[string]interface{}{
+			"code":       "SLOW_MODE",
+			"retryAfter": int(math.Ceil(slowModeErr.RetryAfter.Seconds())),
+		}
+
+	case errors.As(err, &mentionsErr):
+		return map[string]interface{}{
+			"code":        "TOO_MANY_MENTIONS",
+			"field":       "content",
+			"maxMentions": mentionsErr.MaxMentions,
+		}
+
+	case errors.As(err, &pinsErr):
+		return map[string]interface{}{"code": "TOO_MANY_PINS", "maxPins": pinsErr.MaxPins}
+
+	case errors.Is(err, domain.ErrCommentsOff):
+		return map[string]interface{}{"code": "COMMENT_OFF"}
+
+	case errors.Is(err, domain.ErrPostNotFound):
+		return map[string]interface{}{"code": "POST_NOT_FOUND"}
+
+	case errors.Is(err, domain.ErrParentCommentNotFound):
+		return map[string]interface{}{"code": "COMMENT_PARENT_NOT_FOUND"}
+
+	case errors.Is(err, domain.ErrCommentNotFound):
+		return map[string]interface{}{"code": "COMMENT_NOT_FOUND"}
+
+	case errors.Is(err, domain.ErrThreadLocked):
+		return map[string]interface{}{"code": "THREAD_LOCKED"}
+
+	case errors.Is(err, domain.ErrCommentCycle):
+		return map[string]interface{}{"code": "COMMENT_CYCLE", "field": "newParentId"}
+
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		return map[string]interface{}{"code": "WEBHOOK_NOT_FOUND"}
+
+	case errors.Is(err, domain.ErrDeliveryNotFound):
+		return map[string]interface{}{"code": "DELIVERY_NOT_FOUND"}
+
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return map[string]interface{}{"code": "UNAUTHENTICATED"}
+
+	case errors.Is(err, domain.ErrForbidden):
+		return map[string]interface{}{"code": "FORBIDDEN"}
+
+	case errors.Is(err, idempotency.ErrKeyReused):
+		return map[string]interface{}{"code": "IDEMPOTENCY_KEY_REUSED", "field": "idempotencyKey"}
+
+	case errors.Is(err, idempotency.ErrInProgress):
+		return map[string]interface{}{"code": "REQUEST_IN_PROGRESS"}
+
+	case errors.As(err, &limitErr):
+		return map[string]interface{}{
+			"code":       "RATE_LIMITED",
+			"retryAfter": int(math.Ceil(limitErr.RetryAfter.Seconds())),
 		}
 	}
 
-	return graphql.DefaultErrorPresenter(ctx, err)
+	return nil
 }
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/ratelimit"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	t.Run("validation error lists fields", func(t *testing.T) {
+		_, err := domain.NewPost("", "", "author", true)
+
+		gqlErr := ErrorPresenter(context.Background(), err)
+		assert.Equal(t, "VALIDATION_FAILED", gqlErr.Extensions["code"])
+		assert.Equal(t, "title", gqlErr.Extensions["field"])
+		assert.Len(t, gqlErr.Extensions["fields"], 2)
+	})
+
+	t.Run("comment not found", func(t *testing.T) {
+		gqlErr := ErrorPresenter(context.Background(), domain.ErrCommentNotFound)
+		assert.Equal(t, "COMMENT_NOT_FOUND", gqlErr.Extensions["code"])
+	})
+}
+
+func TestToUserErrors(t *testing.T) {
+	t.Run("input error becomes user error", func(t *testing.T) {
+		userErrors, err := toUserErrors(&domain.CommentTooLongError{MaxLength: 5})
+		require.NoError(t, err)
+		require.Len(t, userErrors, 1)
+		assert.Equal(t, "COMMENT_TOO_LONG", userErrors[0].Code)
+		assert.Equal(t, 5, *userErrors[0].MaxLength)
+	})
+
+	t.Run("rate limit, auth and not found stay top-level", func(t *testing.T) {
+		for _, want := range []error{
+			&ratelimit.LimitError{RetryAfter: time.Minute},
+			domain.ErrUnauthenticated,
+			domain.ErrForbidden,
+			domain.ErrPostNotFound,
+			idempotency.ErrInProgress,
+		} {
+			userErrors, err := toUserErrors(want)
+			assert.ErrorIs(t, err, want)
+			assert.Nil(t, userErrors)
+		}
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/introspection"
 	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
 	gqlparser "github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 )
@@ -35,9 +36,12 @@ type Config struct {
 type ResolverRoot interface {
 	Comment() CommentResolver
 	Mutation() MutationResolver
+	Notification() NotificationResolver
 	Post() PostResolver
 	Query() QueryResolver
 	Subscription() SubscriptionResolver
+	Webhook() WebhookResolver
+	WebhookDelivery() WebhookDeliveryResolver
 }
 
 type DirectiveRoot struct {
@@ -45,226 +49,1258 @@ type DirectiveRoot struct {
 
 type ComplexityRoot struct {
 	Comment struct {
-		Author    func(childComplexity int) int
-		Children  func(childComplexity int, limit *int, offset *int) int
-		Content   func(childComplexity int) int
+		Ancestors       func(childComplexity int) int
+		Author          func(childComplexity int) int
+		Children        func(childComplexity int, limit *int, offset *int) int
+		Content         func(childComplexity int) int
+		ContentHTML     func(childComplexity int) int
+		CreatedAt       func(childComplexity int) int
+		DescendantCount func(childComplexity int) int
+		ID              func(childComplexity int) int
+		IsLocked        func(childComplexity int) int
+		IsPinned        func(childComplexity int) int
+		Mentions        func(childComplexity int) int
+		Parent          func(childComplexity int) int
+		ParentID        func(childComplexity int) int
+		Pending         func(childComplexity int) int
+		PinnedAt        func(childComplexity int) int
+		PlainText       func(childComplexity int) int
+		Post            func(childComplexity int) int
+		PostID          func(childComplexity int) int
+		ReplyCount      func(childComplexity int) int
+	}
+
+	CommentMove struct {
+		Comment      func(childComplexity int) int
+		FromParentID func(childComplexity int) int
+		FromPostID   func(childComplexity int) int
+	}
+
+	CommentThread struct {
+		Ancestors        func(childComplexity int) int
+		Comment          func(childComplexity int) int
+		HasMoreAncestors func(childComplexity int) int
+	}
+
+	CreateCommentPayload struct {
+		Comment    func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	CreatePostPayload struct {
+		Post       func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	Mention struct {
+		Author func(childComplexity int) int
+		End    func(childComplexity int) int
+		Start  func(childComplexity int) int
+	}
+
+	MoveCommentPayload struct {
+		Comment    func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	Mutation struct {
+		ApproveComment        func(childComplexity int, commentID string) int
+		CreateComment         func(childComplexity int, input model.NewCommentInput) int
+		CreatePost            func(childComplexity int, input model.NewPostInput) int
+		CreateWebhook         func(childComplexity int, input model.NewWebhookInput) int
+		DeleteWebhook         func(childComplexity int, id string) int
+		LockThread            func(childComplexity int, commentID string) int
+		MarkNotificationsRead func(childComplexity int, ids []string) int
+		MoveComment           func(childComplexity int, commentID string, newParentID *string, newPostID *string) int
+		PinComment            func(childComplexity int, commentID string) int
+		RedeliverWebhook      func(childComplexity int, deliveryID string) int
+		ToggleComments        func(childComplexity int, postID string, allow bool) int
+		UnlockThread          func(childComplexity int, commentID string) int
+		UnpinComment          func(childComplexity int, commentID string) int
+		UpdatePost            func(childComplexity int, id string, input model.UpdatePostInput) int
+		UpdateThreadSettings  func(childComplexity int, postID string, input model.ThreadSettingsInput) int
+		VotePost              func(childComplexity int, postID string, value int) int
+	}
+
+	Notification struct {
+		Actor     func(childComplexity int) int
+		Comment   func(childComplexity int) int
+		CommentID func(childComplexity int) int
 		CreatedAt func(childComplexity int) int
 		ID        func(childComplexity int) int
-		ParentID  func(childComplexity int) int
+		Kind      func(childComplexity int) int
+		Post      func(childComplexity int) int
 		PostID    func(childComplexity int) int
+		Read      func(childComplexity int) int
+		ReadAt    func(childComplexity int) int
+	}
+
+	NotificationConnection struct {
+		Edges    func(childComplexity int) int
+		PageInfo func(childComplexity int) int
+	}
+
+	NotificationEdge struct {
+		Cursor func(childComplexity int) int
+		Node   func(childComplexity int) int
+	}
+
+	PageInfo struct {
+		EndCursor   func(childComplexity int) int
+		HasNextPage func(childComplexity int) int
+	}
+
+	PinCommentPayload struct {
+		Comment    func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	Post struct {
+		AllowComments    func(childComplexity int) int
+		Author           func(childComplexity int) int
+		CommentCount     func(childComplexity int) int
+		Comments         func(childComplexity int, limit *int, offset *int) int
+		Content          func(childComplexity int) int
+		ContentHTML      func(childComplexity int) int
+		ID               func(childComplexity int) int
+		LastCommentAt    func(childComplexity int) int
+		ParticipantCount func(childComplexity int) int
+		PinnedComments   func(childComplexity int) int
+		PlainText        func(childComplexity int) int
+		PublishAt        func(childComplexity int) int
+		Settings         func(childComplexity int) int
+		Status           func(childComplexity int) int
+		Tags             func(childComplexity int) int
+		Title            func(childComplexity int) int
+		VoteScore        func(childComplexity int) int
+	}
+
+	Query struct {
+		Comment                 func(childComplexity int, id string, contextDepth *int) int
+		Notifications           func(childComplexity int, unreadOnly *bool, first *int, after *string) int
+		Post                    func(childComplexity int, id string) int
+		Posts                   func(childComplexity int, status *domain.PostStatus, tags []string, tagMatch *domain.TagMatch, sort *model.PostSort) int
+		Tags                    func(childComplexity int, limit *int) int
+		UnreadNotificationCount func(childComplexity int) int
+		Webhooks                func(childComplexity int) int
+	}
+
+	Subscription struct {
+		CommentAdded      func(childComplexity int, postID string) int
+		CommentMoved      func(childComplexity int, postID string) int
+		NotificationAdded func(childComplexity int) int
+		PostPublished     func(childComplexity int) int
+	}
+
+	Tag struct {
+		Name      func(childComplexity int) int
+		PostCount func(childComplexity int) int
+	}
+
+	ThreadSettings struct {
+		MaxCommentLength func(childComplexity int) int
+		MaxReplyDepth    func(childComplexity int) int
+		RequireApproval  func(childComplexity int) int
+		SlowModeSeconds  func(childComplexity int) int
+	}
+
+	ToggleCommentsPayload struct {
+		Post       func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	UpdatePostPayload struct {
+		Post       func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	UserError struct {
+		Code        func(childComplexity int) int
+		Field       func(childComplexity int) int
+		MaxLength   func(childComplexity int) int
+		MaxMentions func(childComplexity int) int
+		Message     func(childComplexity int) int
+	}
+
+	VotePostPayload struct {
+		Post       func(childComplexity int) int
+		UserErrors func(childComplexity int) int
+	}
+
+	Webhook struct {
+		CreatedAt  func(childComplexity int) int
+		Deliveries func(childComplexity int, status *domain.DeliveryStatus, limit *int) int
+		Events     func(childComplexity int) int
+		ID         func(childComplexity int) int
+		Owner      func(childComplexity int) int
+		URL        func(childComplexity int) int
 	}
 
-	Mutation struct {
-		CreateComment  func(childComplexity int, input model.NewCommentInput) int
-		CreatePost     func(childComplexity int, input model.NewPostInput) int
-		ToggleComments func(childComplexity int, postID string, allow bool) int
-	}
+	WebhookDelivery struct {
+		Attempts       func(childComplexity int) int
+		CreatedAt      func(childComplexity int) int
+		DeliveredAt    func(childComplexity int) int
+		Event          func(childComplexity int) int
+		ID             func(childComplexity int) int
+		LastError      func(childComplexity int) int
+		NextAttemptAt  func(childComplexity int) int
+		Payload        func(childComplexity int) int
+		ResponseStatus func(childComplexity int) int
+		Status         func(childComplexity int) int
+	}
+}
+
+type executableSchema struct {
+	schema     *ast.Schema
+	resolvers  ResolverRoot
+	directives DirectiveRoot
+	complexity ComplexityRoot
+}
+
+func (e *executableSchema) Schema() *ast.Schema {
+	if e.schema != nil {
+		return e.schema
+	}
+	return parsedSchema
+}
+
+func (e *executableSchema) Complexity(ctx context.Context, typeName, field string, childComplexity int, rawArgs map[string]any) (int, bool) {
+	ec := executionContext{nil, e, 0, 0, nil}
+	_ = ec
+	switch typeName + "." + field {
+
+	case "Comment.ancestors":
+		if e.complexity.Comment.Ancestors == nil {
+			break
+		}
+
+		return e.complexity.Comment.Ancestors(childComplexity), true
+
+	case "Comment.author":
+		if e.complexity.Comment.Author == nil {
+			break
+		}
+
+		return e.complexity.Comment.Author(childComplexity), true
+
+	case "Comment.children":
+		if e.complexity.Comment.Children == nil {
+			break
+		}
+
+		args, err := ec.field_Comment_children_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Comment.Children(childComplexity, args["limit"].(*int), args["offset"].(*int)), true
+
+	case "Comment.content":
+		if e.complexity.Comment.Content == nil {
+			break
+		}
+
+		return e.complexity.Comment.Content(childComplexity), true
+
+	case "Comment.contentHTML":
+		if e.complexity.Comment.ContentHTML == nil {
+			break
+		}
+
+		return e.complexity.Comment.ContentHTML(childComplexity), true
+
+	case "Comment.createdAt":
+		if e.complexity.Comment.CreatedAt == nil {
+			break
+		}
+
+		return e.complexity.Comment.CreatedAt(childComplexity), true
+
+	case "Comment.descendantCount":
+		if e.complexity.Comment.DescendantCount == nil {
+			break
+		}
+
+		return e.complexity.Comment.DescendantCount(childComplexity), true
+
+	case "Comment.id":
+		if e.complexity.Comment.ID == nil {
+			break
+		}
+
+		return e.complexity.Comment.ID(childComplexity), true
+
+	case "Comment.isLocked":
+		if e.complexity.Comment.IsLocked == nil {
+			break
+		}
+
+		return e.complexity.Comment.IsLocked(childComplexity), true
+
+	case "Comment.isPinned":
+		if e.complexity.Comment.IsPinned == nil {
+			break
+		}
+
+		return e.complexity.Comment.IsPinned(childComplexity), true
+
+	case "Comment.mentions":
+		if e.complexity.Comment.Mentions == nil {
+			break
+		}
+
+		return e.complexity.Comment.Mentions(childComplexity), true
+
+	case "Comment.parent":
+		if e.complexity.Comment.Parent == nil {
+			break
+		}
+
+		return e.complexity.Comment.Parent(childComplexity), true
+
+	case "Comment.parentID":
+		if e.complexity.Comment.ParentID == nil {
+			break
+		}
+
+		return e.complexity.Comment.ParentID(childComplexity), true
+
+	case "Comment.pending":
+		if e.complexity.Comment.Pending == nil {
+			break
+		}
+
+		return e.complexity.Comment.Pending(childComplexity), true
+
+	case "Comment.pinnedAt":
+		if e.complexity.Comment.PinnedAt == nil {
+			break
+		}
+
+		return e.complexity.Comment.PinnedAt(childComplexity), true
+
+	case "Comment.plainText":
+		if e.complexity.Comment.PlainText == nil {
+			break
+		}
+
+		return e.complexity.Comment.PlainText(childComplexity), true
+
+	case "Comment.post":
+		if e.complexity.Comment.Post == nil {
+			break
+		}
+
+		return e.complexity.Comment.Post(childComplexity), true
+
+	case "Comment.postID":
+		if e.complexity.Comment.PostID == nil {
+			break
+		}
+
+		return e.complexity.Comment.PostID(childComplexity), true
+
+	case "Comment.replyCount":
+		if e.complexity.Comment.ReplyCount == nil {
+			break
+		}
+
+		return e.complexity.Comment.ReplyCount(childComplexity), true
+
+	case "CommentMove.comment":
+		if e.complexity.CommentMove.Comment == nil {
+			break
+		}
+
+		return e.complexity.CommentMove.Comment(childComplexity), true
+
+	case "CommentMove.fromParentId":
+		if e.complexity.CommentMove.FromParentID == nil {
+			break
+		}
+
+		return e.complexity.CommentMove.FromParentID(childComplexity), true
+
+	case "CommentMove.fromPostId":
+		if e.complexity.CommentMove.FromPostID == nil {
+			break
+		}
+
+		return e.complexity.CommentMove.FromPostID(childComplexity), true
+
+	case "CommentThread.ancestors":
+		if e.complexity.CommentThread.Ancestors == nil {
+			break
+		}
+
+		return e.complexity.CommentThread.Ancestors(childComplexity), true
+
+	case "CommentThread.comment":
+		if e.complexity.CommentThread.Comment == nil {
+			break
+		}
+
+		return e.complexity.CommentThread.Comment(childComplexity), true
+
+	case "CommentThread.hasMoreAncestors":
+		if e.complexity.CommentThread.HasMoreAncestors == nil {
+			break
+		}
+
+		return e.complexity.CommentThread.HasMoreAncestors(childComplexity), true
+
+	case "CreateCommentPayload.comment":
+		if e.complexity.CreateCommentPayload.Comment == nil {
+			break
+		}
+
+		return e.complexity.CreateCommentPayload.Comment(childComplexity), true
+
+	case "CreateCommentPayload.userErrors":
+		if e.complexity.CreateCommentPayload.UserErrors == nil {
+			break
+		}
+
+		return e.complexity.CreateCommentPayload.UserErrors(childComplexity), true
+
+	case "CreatePostPayload.post":
+		if e.complexity.CreatePostPayload.Post == nil {
+			break
+		}
+
+		return e.complexity.CreatePostPayload.Post(childComplexity), true
+
+	case "CreatePostPayload.userErrors":
+		if e.complexity.CreatePostPayload.UserErrors == nil {
+			break
+		}
+
+		return e.complexity.CreatePostPayload.UserErrors(childComplexity), true
+
+	case "Mention.author":
+		if e.complexity.Mention.Author == nil {
+			break
+		}
+
+		return e.complexity.Mention.Author(childComplexity), true
+
+	case "Mention.end":
+		if e.complexity.Mention.End == nil {
+			break
+		}
+
+		return e.complexity.Mention.End(childComplexity), true
+
+	case "Mention.start":
+		if e.complexity.Mention.Start == nil {
+			break
+		}
+
+		return e.complexity.Mention.Start(childComplexity), true
+
+	case "MoveCommentPayload.comment":
+		if e.complexity.MoveCommentPayload.Comment == nil {
+			break
+		}
+
+		return e.complexity.MoveCommentPayload.Comment(childComplexity), true
+
+	case "MoveCommentPayload.userErrors":
+		if e.complexity.MoveCommentPayload.UserErrors == nil {
+			break
+		}
+
+		return e.complexity.MoveCommentPayload.UserErrors(childComplexity), true
+
+	case "Mutation.approveComment":
+		if e.complexity.Mutation.ApproveComment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_approveComment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.ApproveComment(childComplexity, args["commentId"].(string)), true
+
+	case "Mutation.createComment":
+		if e.complexity.Mutation.CreateComment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_createComment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.CreateComment(childComplexity, args["input"].(model.NewCommentInput)), true
+
+	case "Mutation.createPost":
+		if e.complexity.Mutation.CreatePost == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_createPost_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.CreatePost(childComplexity, args["input"].(model.NewPostInput)), true
+
+	case "Mutation.createWebhook":
+		if e.complexity.Mutation.CreateWebhook == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_createWebhook_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.CreateWebhook(childComplexity, args["input"].(model.NewWebhookInput)), true
+
+	case "Mutation.deleteWebhook":
+		if e.complexity.Mutation.DeleteWebhook == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_deleteWebhook_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.DeleteWebhook(childComplexity, args["id"].(string)), true
+
+	case "Mutation.lockThread":
+		if e.complexity.Mutation.LockThread == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_lockThread_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.LockThread(childComplexity, args["commentId"].(string)), true
+
+	case "Mutation.markNotificationsRead":
+		if e.complexity.Mutation.MarkNotificationsRead == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_markNotificationsRead_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.MarkNotificationsRead(childComplexity, args["ids"].([]string)), true
+
+	case "Mutation.moveComment":
+		if e.complexity.Mutation.MoveComment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_moveComment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.MoveComment(childComplexity, args["commentId"].(string), args["newParentId"].(*string), args["newPostId"].(*string)), true
+
+	case "Mutation.pinComment":
+		if e.complexity.Mutation.PinComment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_pinComment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.PinComment(childComplexity, args["commentId"].(string)), true
+
+	case "Mutation.redeliverWebhook":
+		if e.complexity.Mutation.RedeliverWebhook == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_redeliverWebhook_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.RedeliverWebhook(childComplexity, args["deliveryId"].(string)), true
+
+	case "Mutation.toggleComments":
+		if e.complexity.Mutation.ToggleComments == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_toggleComments_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.ToggleComments(childComplexity, args["postId"].(string), args["allow"].(bool)), true
+
+	case "Mutation.unlockThread":
+		if e.complexity.Mutation.UnlockThread == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_unlockThread_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.UnlockThread(childComplexity, args["commentId"].(string)), true
+
+	case "Mutation.unpinComment":
+		if e.complexity.Mutation.UnpinComment == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_unpinComment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.UnpinComment(childComplexity, args["commentId"].(string)), true
+
+	case "Mutation.updatePost":
+		if e.complexity.Mutation.UpdatePost == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_updatePost_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.UpdatePost(childComplexity, args["id"].(string), args["input"].(model.UpdatePostInput)), true
+
+	case "Mutation.updateThreadSettings":
+		if e.complexity.Mutation.UpdateThreadSettings == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_updateThreadSettings_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.UpdateThreadSettings(childComplexity, args["postId"].(string), args["input"].(model.ThreadSettingsInput)), true
+
+	case "Mutation.votePost":
+		if e.complexity.Mutation.VotePost == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_votePost_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Mutation.VotePost(childComplexity, args["postId"].(string), args["value"].(int)), true
+
+	case "Notification.actor":
+		if e.complexity.Notification.Actor == nil {
+			break
+		}
+
+		return e.complexity.Notification.Actor(childComplexity), true
+
+	case "Notification.comment":
+		if e.complexity.Notification.Comment == nil {
+			break
+		}
+
+		return e.complexity.Notification.Comment(childComplexity), true
+
+	case "Notification.commentID":
+		if e.complexity.Notification.CommentID == nil {
+			break
+		}
+
+		return e.complexity.Notification.CommentID(childComplexity), true
+
+	case "Notification.createdAt":
+		if e.complexity.Notification.CreatedAt == nil {
+			break
+		}
+
+		return e.complexity.Notification.CreatedAt(childComplexity), true
+
+	case "Notification.id":
+		if e.complexity.Notification.ID == nil {
+			break
+		}
+
+		return e.complexity.Notification.ID(childComplexity), true
+
+	case "Notification.kind":
+		if e.complexity.Notification.Kind == nil {
+			break
+		}
+
+		return e.complexity.Notification.Kind(childComplexity), true
+
+	case "Notification.post":
+		if e.complexity.Notification.Post == nil {
+			break
+		}
+
+		return e.complexity.Notification.Post(childComplexity), true
+
+	case "Notification.postID":
+		if e.complexity.Notification.PostID == nil {
+			break
+		}
+
+		return e.complexity.Notification.PostID(childComplexity), true
+
+	case "Notification.read":
+		if e.complexity.Notification.Read == nil {
+			break
+		}
+
+		return e.complexity.Notification.Read(childComplexity), true
+
+	case "Notification.readAt":
+		if e.complexity.Notification.ReadAt == nil {
+			break
+		}
+
+		return e.complexity.Notification.ReadAt(childComplexity), true
+
+	case "NotificationConnection.edges":
+		if e.complexity.NotificationConnection.Edges == nil {
+			break
+		}
+
+		return e.complexity.NotificationConnection.Edges(childComplexity), true
+
+	case "NotificationConnection.pageInfo":
+		if e.complexity.NotificationConnection.PageInfo == nil {
+			break
+		}
+
+		return e.complexity.NotificationConnection.PageInfo(childComplexity), true
+
+	case "NotificationEdge.cursor":
+		if e.complexity.NotificationEdge.Cursor == nil {
+			break
+		}
+
+		return e.complexity.NotificationEdge.Cursor(childComplexity), true
+
+	case "NotificationEdge.node":
+		if e.complexity.NotificationEdge.Node == nil {
+			break
+		}
+
+		return e.complexity.NotificationEdge.Node(childComplexity), true
+
+	case "PageInfo.endCursor":
+		if e.complexity.PageInfo.EndCursor == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.EndCursor(childComplexity), true
+
+	case "PageInfo.hasNextPage":
+		if e.complexity.PageInfo.HasNextPage == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.HasNextPage(childComplexity), true
+
+	case "PinCommentPayload.comment":
+		if e.complexity.PinCommentPayload.Comment == nil {
+			break
+		}
+
+		return e.complexity.PinCommentPayload.Comment(childComplexity), true
+
+	case "PinCommentPayload.userErrors":
+		if e.complexity.PinCommentPayload.UserErrors == nil {
+			break
+		}
+
+		return e.complexity.PinCommentPayload.UserErrors(childComplexity), true
+
+	case "Post.allowComments":
+		if e.complexity.Post.AllowComments == nil {
+			break
+		}
+
+		return e.complexity.Post.AllowComments(childComplexity), true
+
+	case "Post.author":
+		if e.complexity.Post.Author == nil {
+			break
+		}
+
+		return e.complexity.Post.Author(childComplexity), true
+
+	case "Post.commentCount":
+		if e.complexity.Post.CommentCount == nil {
+			break
+		}
+
+		return e.complexity.Post.CommentCount(childComplexity), true
+
+	case "Post.comments":
+		if e.complexity.Post.Comments == nil {
+			break
+		}
+
+		args, err := ec.field_Post_comments_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Post.Comments(childComplexity, args["limit"].(*int), args["offset"].(*int)), true
+
+	case "Post.content":
+		if e.complexity.Post.Content == nil {
+			break
+		}
+
+		return e.complexity.Post.Content(childComplexity), true
+
+	case "Post.contentHTML":
+		if e.complexity.Post.ContentHTML == nil {
+			break
+		}
+
+		return e.complexity.Post.ContentHTML(childComplexity), true
+
+	case "Post.id":
+		if e.complexity.Post.ID == nil {
+			break
+		}
+
+		return e.complexity.Post.ID(childComplexity), true
+
+	case "Post.lastCommentAt":
+		if e.complexity.Post.LastCommentAt == nil {
+			break
+		}
+
+		return e.complexity.Post.LastCommentAt(childComplexity), true
+
+	case "Post.participantCount":
+		if e.complexity.Post.ParticipantCount == nil {
+			break
+		}
+
+		return e.complexity.Post.ParticipantCount(childComplexity), true
+
+	case "Post.pinnedComments":
+		if e.complexity.Post.PinnedComments == nil {
+			break
+		}
+
+		return e.complexity.Post.PinnedComments(childComplexity), true
+
+	case "Post.plainText":
+		if e.complexity.Post.PlainText == nil {
+			break
+		}
+
+		return e.complexity.Post.PlainText(childComplexity), true
+
+	case "Post.publishAt":
+		if e.complexity.Post.PublishAt == nil {
+			break
+		}
+
+		return e.complexity.Post.PublishAt(childComplexity), true
+
+	case "Post.settings":
+		if e.complexity.Post.Settings == nil {
+			break
+		}
+
+		return e.complexity.Post.Settings(childComplexity), true
+
+	case "Post.status":
+		if e.complexity.Post.Status == nil {
+			break
+		}
+
+		return e.complexity.Post.Status(childComplexity), true
+
+	case "Post.tags":
+		if e.complexity.Post.Tags == nil {
+			break
+		}
+
+		return e.complexity.Post.Tags(childComplexity), true
+
+	case "Post.title":
+		if e.complexity.Post.Title == nil {
+			break
+		}
+
+		return e.complexity.Post.Title(childComplexity), true
+
+	case "Post.voteScore":
+		if e.complexity.Post.VoteScore == nil {
+			break
+		}
+
+		return e.complexity.Post.VoteScore(childComplexity), true
+
+	case "Query.comment":
+		if e.complexity.Query.Comment == nil {
+			break
+		}
+
+		args, err := ec.field_Query_comment_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.Comment(childComplexity, args["id"].(string), args["contextDepth"].(*int)), true
+
+	case "Query.notifications":
+		if e.complexity.Query.Notifications == nil {
+			break
+		}
+
+		args, err := ec.field_Query_notifications_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.Notifications(childComplexity, args["unreadOnly"].(*bool), args["first"].(*int), args["after"].(*string)), true
+
+	case "Query.post":
+		if e.complexity.Query.Post == nil {
+			break
+		}
+
+		args, err := ec.field_Query_post_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.Post(childComplexity, args["id"].(string)), true
 
-	Post struct {
-		AllowComments func(childComplexity int) int
-		Author        func(childComplexity int) int
-		Comments      func(childComplexity int, limit *int, offset *int) int
-		Content       func(childComplexity int) int
-		ID            func(childComplexity int) int
-		Title         func(childComplexity int) int
-	}
+	case "Query.posts":
+		if e.complexity.Query.Posts == nil {
+			break
+		}
 
-	Query struct {
-		Post  func(childComplexity int, id string) int
-		Posts func(childComplexity int) int
-	}
+		args, err := ec.field_Query_posts_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
 
-	Subscription struct {
-		CommentAdded func(childComplexity int, postID string) int
-	}
-}
+		return e.complexity.Query.Posts(childComplexity, args["status"].(*domain.PostStatus), args["tags"].([]string), args["tagMatch"].(*domain.TagMatch), args["sort"].(*model.PostSort)), true
 
-type executableSchema struct {
-	schema     *ast.Schema
-	resolvers  ResolverRoot
-	directives DirectiveRoot
-	complexity ComplexityRoot
-}
+	case "Query.tags":
+		if e.complexity.Query.Tags == nil {
+			break
+		}
 
-func (e *executableSchema) Schema() *ast.Schema {
-	if e.schema != nil {
-		return e.schema
-	}
-	return parsedSchema
-}
+		args, err := ec.field_Query_tags_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
 
-func (e *executableSchema) Complexity(ctx context.Context, typeName, field string, childComplexity int, rawArgs map[string]any) (int, bool) {
-	ec := executionContext{nil, e, 0, 0, nil}
-	_ = ec
-	switch typeName + "." + field {
+		return e.complexity.Query.Tags(childComplexity, args["limit"].(*int)), true
 
-	case "Comment.author":
-		if e.complexity.Comment.Author == nil {
+	case "Query.unreadNotificationCount":
+		if e.complexity.Query.UnreadNotificationCount == nil {
 			break
 		}
 
-		return e.complexity.Comment.Author(childComplexity), true
+		return e.complexity.Query.UnreadNotificationCount(childComplexity), true
 
-	case "Comment.children":
-		if e.complexity.Comment.Children == nil {
+	case "Query.webhooks":
+		if e.complexity.Query.Webhooks == nil {
 			break
 		}
 
-		args, err := ec.field_Comment_children_args(ctx, rawArgs)
+		return e.complexity.Query.Webhooks(childComplexity), true
+
+	case "Subscription.commentAdded":
+		if e.complexity.Subscription.CommentAdded == nil {
+			break
+		}
+
+		args, err := ec.field_Subscription_commentAdded_args(ctx, rawArgs)
 		if err != nil {
 			return 0, false
 		}
 
-		return e.complexity.Comment.Children(childComplexity, args["limit"].(*int), args["offset"].(*int)), true
+		return e.complexity.Subscription.CommentAdded(childComplexity, args["postId"].(string)), true
 
-	case "Comment.content":
-		if e.complexity.Comment.Content == nil {
+	case "Subscription.commentMoved":
+		if e.complexity.Subscription.CommentMoved == nil {
 			break
 		}
 
-		return e.complexity.Comment.Content(childComplexity), true
+		args, err := ec.field_Subscription_commentMoved_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
 
-	case "Comment.createdAt":
-		if e.complexity.Comment.CreatedAt == nil {
+		return e.complexity.Subscription.CommentMoved(childComplexity, args["postId"].(string)), true
+
+	case "Subscription.notificationAdded":
+		if e.complexity.Subscription.NotificationAdded == nil {
 			break
 		}
 
-		return e.complexity.Comment.CreatedAt(childComplexity), true
+		return e.complexity.Subscription.NotificationAdded(childComplexity), true
 
-	case "Comment.id":
-		if e.complexity.Comment.ID == nil {
+	case "Subscription.postPublished":
+		if e.complexity.Subscription.PostPublished == nil {
 			break
 		}
 
-		return e.complexity.Comment.ID(childComplexity), true
+		return e.complexity.Subscription.PostPublished(childComplexity), true
 
-	case "Comment.parentID":
-		if e.complexity.Comment.ParentID == nil {
+	case "Tag.name":
+		if e.complexity.Tag.Name == nil {
 			break
 		}
 
-		return e.complexity.Comment.ParentID(childComplexity), true
+		return e.complexity.Tag.Name(childComplexity), true
 
-	case "Comment.postID":
-		if e.complexity.Comment.PostID == nil {
+	case "Tag.postCount":
+		if e.complexity.Tag.PostCount == nil {
 			break
 		}
 
-		return e.complexity.Comment.PostID(childComplexity), true
+		return e.complexity.Tag.PostCount(childComplexity), true
 
-	case "Mutation.createComment":
-		if e.complexity.Mutation.CreateComment == nil {
+	case "ThreadSettings.maxCommentLength":
+		if e.complexity.ThreadSettings.MaxCommentLength == nil {
 			break
 		}
 
-		args, err := ec.field_Mutation_createComment_args(ctx, rawArgs)
-		if err != nil {
-			return 0, false
+		return e.complexity.ThreadSettings.MaxCommentLength(childComplexity), true
+
+	case "ThreadSettings.maxReplyDepth":
+		if e.complexity.ThreadSettings.MaxReplyDepth == nil {
+			break
 		}
 
-		return e.complexity.Mutation.CreateComment(childComplexity, args["input"].(model.NewCommentInput)), true
+		return e.complexity.ThreadSettings.MaxReplyDepth(childComplexity), true
 
-	case "Mutation.createPost":
-		if e.complexity.Mutation.CreatePost == nil {
+	case "ThreadSettings.requireApproval":
+		if e.complexity.ThreadSettings.RequireApproval == nil {
 			break
 		}
 
-		args, err := ec.field_Mutation_createPost_args(ctx, rawArgs)
-		if err != nil {
-			return 0, false
+		return e.complexity.ThreadSettings.RequireApproval(childComplexity), true
+
+	case "ThreadSettings.slowModeSeconds":
+		if e.complexity.ThreadSettings.SlowModeSeconds == nil {
+			break
 		}
 
-		return e.complexity.Mutation.CreatePost(childComplexity, args["input"].(model.NewPostInput)), true
+		return e.complexity.ThreadSettings.SlowModeSeconds(childComplexity), true
 
-	case "Mutation.toggleComments":
-		if e.complexity.Mutation.ToggleComments == nil {
+	case "ToggleCommentsPayload.post":
+		if e.complexity.ToggleCommentsPayload.Post == nil {
 			break
 		}
 
-		args, err := ec.field_Mutation_toggleComments_args(ctx, rawArgs)
-		if err != nil {
-			return 0, false
+		return e.complexity.ToggleCommentsPayload.Post(childComplexity), true
+
+	case "ToggleCommentsPayload.userErrors":
+		if e.complexity.ToggleCommentsPayload.UserErrors == nil {
+			break
 		}
 
-		return e.complexity.Mutation.ToggleComments(childComplexity, args["postId"].(string), args["allow"].(bool)), true
+		return e.complexity.ToggleCommentsPayload.UserErrors(childComplexity), true
 
-	case "Post.allowComments":
-		if e.complexity.Post.AllowComments == nil {
+	case "UpdatePostPayload.post":
+		if e.complexity.UpdatePostPayload.Post == nil {
 			break
 		}
 
-		return e.complexity.Post.AllowComments(childComplexity), true
+		return e.complexity.UpdatePostPayload.Post(childComplexity), true
 
-	case "Post.author":
-		if e.complexity.Post.Author == nil {
+	case "UpdatePostPayload.userErrors":
+		if e.complexity.UpdatePostPayload.UserErrors == nil {
 			break
 		}
 
-		return e.complexity.Post.Author(childComplexity), true
+		return e.complexity.UpdatePostPayload.UserErrors(childComplexity), true
 
-	case "Post.comments":
-		if e.complexity.Post.Comments == nil {
+	case "UserError.code":
+		if e.complexity.UserError.Code == nil {
 			break
 		}
 
-		args, err := ec.field_Post_comments_args(ctx, rawArgs)
-		if err != nil {
-			return 0, false
+		return e.complexity.UserError.Code(childComplexity), true
+
+	case "UserError.field":
+		if e.complexity.UserError.Field == nil {
+			break
 		}
 
-		return e.complexity.Post.Comments(childComplexity, args["limit"].(*int), args["offset"].(*int)), true
+		return e.complexity.UserError.Field(childComplexity), true
 
-	case "Post.content":
-		if e.complexity.Post.Content == nil {
+	case "UserError.maxLength":
+		if e.complexity.UserError.MaxLength == nil {
 			break
 		}
 
-		return e.complexity.Post.Content(childComplexity), true
+		return e.complexity.UserError.MaxLength(childComplexity), true
 
-	case "Post.id":
-		if e.complexity.Post.ID == nil {
+	case "UserError.maxMentions":
+		if e.complexity.UserError.MaxMentions == nil {
 			break
 		}
 
-		return e.complexity.Post.ID(childComplexity), true
+		return e.complexity.UserError.MaxMentions(childComplexity), true
 
-	case "Post.title":
-		if e.complexity.Post.Title == nil {
+	case "UserError.message":
+		if e.complexity.UserError.Message == nil {
 			break
 		}
 
-		return e.complexity.Post.Title(childComplexity), true
+		return e.complexity.UserError.Message(childComplexity), true
 
-	case "Query.post":
-		if e.complexity.Query.Post == nil {
+	case "VotePostPayload.post":
+		if e.complexity.VotePostPayload.Post == nil {
 			break
 		}
 
-		args, err := ec.field_Query_post_args(ctx, rawArgs)
-		if err != nil {
-			return 0, false
+		return e.complexity.VotePostPayload.Post(childComplexity), true
+
+	case "VotePostPayload.userErrors":
+		if e.complexity.VotePostPayload.UserErrors == nil {
+			break
 		}
 
-		return e.complexity.Query.Post(childComplexity, args["id"].(string)), true
+		return e.complexity.VotePostPayload.UserErrors(childComplexity), true
 
-	case "Query.posts":
-		if e.complexity.Query.Posts == nil {
+	case "Webhook.createdAt":
+		if e.complexity.Webhook.CreatedAt == nil {
 			break
 		}
 
-		return e.complexity.Query.Posts(childComplexity), true
+		return e.complexity.Webhook.CreatedAt(childComplexity), true
 
-	case "Subscription.commentAdded":
-		if e.complexity.Subscription.CommentAdded == nil {
+	case "Webhook.deliveries":
+		if e.complexity.Webhook.Deliveries == nil {
 			break
 		}
 
-		args, err := ec.field_Subscription_commentAdded_args(ctx, rawArgs)
+		args, err := ec.field_Webhook_deliveries_args(ctx, rawArgs)
 		if err != nil {
 			return 0, false
 		}
 
-		return e.complexity.Subscription.CommentAdded(childComplexity, args["postId"].(string)), true
+		return e.complexity.Webhook.Deliveries(childComplexity, args["status"].(*domain.DeliveryStatus), args["limit"].(*int)), true
+
+	case "Webhook.events":
+		if e.complexity.Webhook.Events == nil {
+			break
+		}
+
+		return e.complexity.Webhook.Events(childComplexity), true
+
+	case "Webhook.id":
+		if e.complexity.Webhook.ID == nil {
+			break
+		}
+
+		return e.complexity.Webhook.ID(childComplexity), true
+
+	case "Webhook.owner":
+		if e.complexity.Webhook.Owner == nil {
+			break
+		}
+
+		return e.complexity.Webhook.Owner(childComplexity), true
+
+	case "Webhook.url":
+		if e.complexity.Webhook.URL == nil {
+			break
+		}
+
+		return e.complexity.Webhook.URL(childComplexity), true
+
+	case "WebhookDelivery.attempts":
+		if e.complexity.WebhookDelivery.Attempts == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.Attempts(childComplexity), true
+
+	case "WebhookDelivery.createdAt":
+		if e.complexity.WebhookDelivery.CreatedAt == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.CreatedAt(childComplexity), true
+
+	case "WebhookDelivery.deliveredAt":
+		if e.complexity.WebhookDelivery.DeliveredAt == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.DeliveredAt(childComplexity), true
+
+	case "WebhookDelivery.event":
+		if e.complexity.WebhookDelivery.Event == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.Event(childComplexity), true
+
+	case "WebhookDelivery.id":
+		if e.complexity.WebhookDelivery.ID == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.ID(childComplexity), true
+
+	case "WebhookDelivery.lastError":
+		if e.complexity.WebhookDelivery.LastError == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.LastError(childComplexity), true
+
+	case "WebhookDelivery.nextAttemptAt":
+		if e.complexity.WebhookDelivery.NextAttemptAt == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.NextAttemptAt(childComplexity), true
+
+	case "WebhookDelivery.payload":
+		if e.complexity.WebhookDelivery.Payload == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.Payload(childComplexity), true
+
+	case "WebhookDelivery.responseStatus":
+		if e.complexity.WebhookDelivery.ResponseStatus == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.ResponseStatus(childComplexity), true
+
+	case "WebhookDelivery.status":
+		if e.complexity.WebhookDelivery.Status == nil {
+			break
+		}
+
+		return e.complexity.WebhookDelivery.Status(childComplexity), true
 
 	}
 	return 0, false
@@ -276,6 +1312,9 @@ func (e *executableSchema) Exec(ctx context.Context) graphql.ResponseHandler {
 	inputUnmarshalMap := graphql.BuildUnmarshalerMap(
 		ec.unmarshalInputNewCommentInput,
 		ec.unmarshalInputNewPostInput,
+		ec.unmarshalInputNewWebhookInput,
+		ec.unmarshalInputThreadSettingsInput,
+		ec.unmarshalInputUpdatePostInput,
 	)
 	first := true
 
@@ -394,30 +1433,212 @@ var sources = []*ast.Source{
 
 scalar Time
 
+type Tag {
+  name: String!
+  postCount: Int!
+}
+
+# ANY - пост с любым из тегов (OR), ALL - со всеми тегами (AND)
+enum TagMatch {
+  ANY
+  ALL
+}
+
+enum PostSort {
+  # сначала недавно опубликованные
+  NEW
+  # по активности комментариев и голосам с затуханием по возрасту
+  HOT
+}
+
+enum PostStatus {
+  DRAFT
+  SCHEDULED
+  PUBLISHED
+  ARCHIVED
+}
+
 type Post {
   id: ID!
   title: String!
   content: String!
+  contentHTML: String!
+  plainText: String!
   author: String!
   allowComments: Boolean!
+  status: PostStatus!
+  publishAt: Time
+  tags: [String!]!
+  voteScore: Int!
+  settings: ThreadSettings!
+  # счетчики учитывают только видимые комментарии
+  commentCount: Int!
+  participantCount: Int!
+  lastCommentAt: Time
+  # закрепленные автором комментарии в порядке закрепления
+  pinnedComments: [Comment!]!
   comments(limit: Int, offset: Int): [Comment!]!
 }
 
+type ThreadSettings {
+  maxReplyDepth: Int!
+  maxCommentLength: Int!
+  slowModeSeconds: Int!
+  requireApproval: Boolean!
+}
+
 type Comment {
   id: ID!
   postID: ID!
   parentID: ID
   author: String!
   content: String!
+  contentHTML: String!
+  plainText: String!
   createdAt: Time!
+  pending: Boolean!
+  mentions: [Mention!]!
+  replyCount: Int!
+  descendantCount: Int!
+  # ветка закрыта на этом комментарии или выше
+  isLocked: Boolean!
+  isPinned: Boolean!
+  pinnedAt: Time
+  post: Post!
+  parent: Comment
+  # все предки от корня ветки
+  ancestors: [Comment!]!
   children(limit: Int, offset: Int): [Comment!]!
 }
 
+# комментарий по постоянной ссылке вместе с контекстом ветки
+type CommentThread {
+  comment: Comment!
+  # не больше contextDepth ближайших предков, от корня ветки
+  ancestors: [Comment!]!
+  # выше показанных есть еще предки - можно показать "продолжить ветку"
+  hasMoreAncestors: Boolean!
+}
+
+# перенос ветки комментариев, рассылается подписчикам старого и нового поста
+type CommentMove {
+  comment: Comment!
+  fromPostId: ID!
+  fromParentId: ID
+}
+
+type Mention {
+  author: String!
+  start: Int!
+  end: Int!
+}
+
+enum NotificationKind {
+  MENTION
+  REPLY
+  POST_COMMENT
+}
+
+type Notification {
+  id: ID!
+  kind: NotificationKind!
+  actor: String!
+  postID: ID!
+  commentID: ID!
+  post: Post
+  comment: Comment
+  createdAt: Time!
+  readAt: Time
+  read: Boolean!
+}
+
+type NotificationEdge {
+  cursor: String!
+  node: Notification!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  endCursor: String
+}
+
+type NotificationConnection {
+  edges: [NotificationEdge!]!
+  pageInfo: PageInfo!
+}
+
+enum WebhookEvent {
+  POST_CREATED
+  COMMENT_CREATED
+  COMMENTS_TOGGLED
+}
+
+enum WebhookDeliveryStatus {
+  PENDING
+  SUCCEEDED
+  FAILED
+  DEAD
+}
+
+type Webhook {
+  id: ID!
+  # автор, который зарегистрировал вебхук и получает события своих постов
+  owner: String!
+  url: String!
+  events: [WebhookEvent!]!
+  createdAt: Time!
+  deliveries(status: WebhookDeliveryStatus, limit: Int): [WebhookDelivery!]!
+}
+
+type WebhookDelivery {
+  id: ID!
+  event: WebhookEvent!
+  status: WebhookDeliveryStatus!
+  attempts: Int!
+  nextAttemptAt: Time!
+  lastError: String!
+  # 0, если ответа не было
+  responseStatus: Int!
+  payload: String!
+  createdAt: Time!
+  deliveredAt: Time
+}
+
+input NewWebhookInput {
+  url: String!
+  events: [WebhookEvent!]!
+  # ключ подписи HMAC-SHA256, не короче 16 символов
+  secret: String!
+}
+
 input NewPostInput {
   title: String!
   content: String!
   author: String!
   allowComments: Boolean!
+  status: PostStatus = PUBLISHED
+  # обязателен для SCHEDULED
+  publishAt: Time
+  tags: [String!]
+  # повтор с тем же ключом вернет созданный пост, заменяет заголовок Idempotency-Key
+  idempotencyKey: String
+}
+
+input UpdatePostInput {
+  title: String
+  content: String
+  allowComments: Boolean
+  status: PostStatus
+  publishAt: Time
+  # заменяет все теги поста
+  tags: [String!]
+}
+
+input ThreadSettingsInput {
+  maxReplyDepth: Int
+  maxCommentLength: Int
+  slowModeSeconds: Int
+  requireApproval: Boolean
 }
 
 input NewCommentInput {
@@ -425,21 +1646,100 @@ input NewCommentInput {
   parentID: ID
   author: String!
   content: String!
+  # повтор с тем же ключом вернет созданный комментарий, заменяет заголовок Idempotency-Key
+  idempotencyKey: String
+}
+
+# ошибка ввода, которую можно показать рядом с формой. Лимиты, авторизация
+# и ненайденные сущности приходят в errors ответа
+type UserError {
+  field: String
+  code: String!
+  message: String!
+  maxLength: Int
+  maxMentions: Int
+}
+
+type CreatePostPayload {
+  post: Post
+  userErrors: [UserError!]!
+}
+
+type CreateCommentPayload {
+  comment: Comment
+  userErrors: [UserError!]!
+}
+
+type UpdatePostPayload {
+  post: Post
+  userErrors: [UserError!]!
+}
+
+type VotePostPayload {
+  post: Post
+  userErrors: [UserError!]!
+}
+
+type MoveCommentPayload {
+  comment: Comment
+  userErrors: [UserError!]!
+}
+
+type PinCommentPayload {
+  comment: Comment
+  userErrors: [UserError!]!
+}
+
+type ToggleCommentsPayload {
+  post: Post
+  userErrors: [UserError!]!
 }
 
 type Query {
-  posts: [Post!]!
+  # черновики и запланированные посты видны только автору из X-Author
+  posts(status: PostStatus = PUBLISHED, tags: [String!], tagMatch: TagMatch = ANY, sort: PostSort = NEW): [Post!]!
+  tags(limit: Int): [Tag!]!
   post(id: ID!): Post
+  comment(id: ID!, contextDepth: Int = 3): CommentThread!
+  # уведомления автора из заголовка X-Author
+  notifications(unreadOnly: Boolean = false, first: Int = 20, after: String): NotificationConnection!
+  unreadNotificationCount: Int!
+  webhooks: [Webhook!]!
 }
 
 type Mutation {
-  createPost(input: NewPostInput!): Post!
-  createComment(input: NewCommentInput!): Comment!
-  toggleComments(postId: ID!, allow: Boolean!): Post!
+  createPost(input: NewPostInput!): CreatePostPayload!
+  updatePost(id: ID!, input: UpdatePostInput!): UpdatePostPayload!
+  # value: 1 - за, -1 - против, 0 - отозвать голос
+  votePost(postId: ID!, value: Int!): VotePostPayload!
+  createComment(input: NewCommentInput!): CreateCommentPayload!
+  toggleComments(postId: ID!, allow: Boolean!): ToggleCommentsPayload!
+  # настройки ветки меняет только автор поста
+  updateThreadSettings(postId: ID!, input: ThreadSettingsInput!): Post!
+  # одобряет комментарий на модерации, доступно только автору поста
+  approveComment(commentId: ID!): Comment!
+  # без ids отмечает прочитанными все уведомления, пустой список - ничего
+  markNotificationsRead(ids: [ID!]): Int!
+  createWebhook(input: NewWebhookInput!): Webhook!
+  deleteWebhook(id: ID!): Boolean!
+  # возвращает доставку в очередь, в том числе из DEAD
+  redeliverWebhook(deliveryId: ID!): WebhookDelivery!
+  # переносит комментарий с ответами под newParentId или в корень поста newPostId
+  moveComment(commentId: ID!, newParentId: ID, newPostId: ID): MoveCommentPayload!
+  # закрывает ветку: ответы ниже комментария отклоняются с THREAD_LOCKED.
+  # Закрывать и открывать ветки может только автор поста
+  lockThread(commentId: ID!): Comment!
+  unlockThread(commentId: ID!): Comment!
+  # закреплять может только автор поста, не больше MAX_PINS_PER_POST комментариев
+  pinComment(commentId: ID!): PinCommentPayload!
+  unpinComment(commentId: ID!): PinCommentPayload!
 }
 
 type Subscription {
   commentAdded(postId: ID!): Comment!
+  commentMoved(postId: ID!): CommentMove!
+  notificationAdded: Notification!
+  postPublished: Post!
 }`, BuiltIn: false},
 }
 var parsedSchema = gqlparser.MustLoadSchema(sources...)
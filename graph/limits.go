@@ -0,0 +1,191 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tmozzze/SasPosts/graph/generated"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const defaultPageLimit = 10
+
+// pageArguments - аргументы пагинации, значения которых ограничены сверху
+var pageArguments = []string{"limit", "first"}
+
+// Complexity считает стоимость списков с пагинацией: стоимость
+// дочерних полей умножается на запрошенный limit
+func Complexity(maxPageLimit int) generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	pageComplexity := func(childComplexity int, limit *int, defaultLimit int) int {
+		lim := defaultLimit
+		if limit != nil {
+			lim = *limit
+		}
+		if lim < 1 {
+			lim = 1
+		}
+		if maxPageLimit > 0 && lim > maxPageLimit {
+			lim = maxPageLimit
+		}
+		return 1 + lim*childComplexity
+	}
+
+	c.Post.Comments = func(childComplexity int, limit *int, offset *int) int {
+		return pageComplexity(childComplexity, limit, defaultPageLimit)
+	}
+	c.Comment.Children = func(childComplexity int, limit *int, offset *int) int {
+		return pageComplexity(childComplexity, limit, defaultPageLimit)
+	}
+	c.Webhook.Deliveries = func(childComplexity int, status *domain.DeliveryStatus, limit *int) int {
+		return pageComplexity(childComplexity, limit, defaultPageLimit)
+	}
+	c.Query.Notifications = func(childComplexity int, unreadOnly *bool, first *int, after *string) int {
+		return pageComplexity(childComplexity, first, defaultNotificationsPage)
+	}
+	c.Query.Tags = func(childComplexity int, limit *int) int {
+		return pageComplexity(childComplexity, limit, defaultPageLimit)
+	}
+
+	return c
+}
+
+// QueryLimits отклоняет операции со слишком большой глубиной вложенности
+// и со значениями limit больше допустимого
+type QueryLimits struct {
+	MaxDepth     int
+	MaxPageLimit int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = QueryLimits{}
+
+func (QueryLimits) ExtensionName() string {
+	return "QueryLimits"
+}
+
+func (QueryLimits) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (l QueryLimits) MutateOperationContext(ctx context.Context, oc *graphql.OperationContext) *gqlerror.Error {
+	if oc.Operation == nil {
+		return nil
+	}
+
+	depth, err := l.walk(oc, oc.Operation.SelectionSet, 1)
+	if err != nil {
+		return err
+	}
+
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return &gqlerror.Error{
+			Message: "operation is nested too deeply",
+			Extensions: map[string]interface{}{
+				"code":     "QUERY_TOO_DEEP",
+				"depth":    depth,
+				"maxDepth": l.MaxDepth,
+			},
+		}
+	}
+
+	return nil
+}
+
+// walk возвращает глубину набора полей и проверяет аргументы пагинации
+func (l QueryLimits) walk(oc *graphql.OperationContext, set ast.SelectionSet, level int) (int, *gqlerror.Error) {
+	depth := 0
+
+	for _, sel := range set {
+		var childSet ast.SelectionSet
+		childLevel := level
+
+		switch s := sel.(type) {
+		case *ast.Field:
+			// интроспекция глубокая сама по себе и не ходит в базу
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			if err := l.checkPageArguments(oc, s); err != nil {
+				return 0, err
+			}
+			if level > depth {
+				depth = level
+			}
+			childSet = s.SelectionSet
+			childLevel = level + 1
+		case *ast.InlineFragment:
+			childSet = s.SelectionSet
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				childSet = s.Definition.SelectionSet
+			}
+		}
+
+		if len(childSet) == 0 {
+			continue
+		}
+
+		childDepth, err := l.walk(oc, childSet, childLevel)
+		if err != nil {
+			return 0, err
+		}
+		if childDepth > depth {
+			depth = childDepth
+		}
+	}
+
+	return depth, nil
+}
+
+func (l QueryLimits) checkPageArguments(oc *graphql.OperationContext, field *ast.Field) *gqlerror.Error {
+	if field.Definition == nil {
+		return nil
+	}
+
+	args := field.ArgumentMap(oc.Variables)
+	for _, name := range pageArguments {
+		value, ok := toInt(args[name])
+		if !ok {
+			continue
+		}
+
+		if value < 0 || (l.MaxPageLimit > 0 && value > int64(l.MaxPageLimit)) {
+			return &gqlerror.Error{
+				Message: "argument " + name + " of " + field.Name + " is out of range",
+				Extensions: map[string]interface{}{
+					"code":     "LIMIT_OUT_OF_RANGE",
+					"field":    field.Name,
+					"argument": name,
+					"maxLimit": l.MaxPageLimit,
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
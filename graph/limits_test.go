@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/generated"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func newLimitedClient(t *testing.T) *client.Client {
+	resolver := NewResolver(inmemory.NewInMemoryPostRepository(), inmemory.NewInMemoryCommentRepository(), nil)
+
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolver,
+		Complexity: Complexity(50),
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(ErrorPresenter)
+	srv.Use(QueryLimits{MaxDepth: 5, MaxPageLimit: 50})
+	srv.Use(extension.FixedComplexityLimit(1000))
+
+	return client.New(srv)
+}
+
+func errorCode(t *testing.T, err error) interface{} {
+	t.Helper()
+	require.Error(t, err)
+
+	var raw client.RawJsonError
+	require.ErrorAs(t, err, &raw)
+
+	var list gqlerror.List
+	require.NoError(t, json.Unmarshal(raw.RawMessage, &list))
+	require.NotEmpty(t, list)
+
+	return list[0].Extensions["code"]
+}
+
+func TestQueryLimits(t *testing.T) {
+	c := newLimitedClient(t)
+	var resp map[string]interface{}
+
+	t.Run("shallow query passes", func(t *testing.T) {
+		err := c.Post(`{ posts { id comments(limit: 5) { id children(limit: 5) { id } } } }`, &resp)
+		assert.NoError(t, err)
+	})
+
+	t.Run("error, if query is too deep", func(t *testing.T) {
+		err := c.Post(`{ posts { comments { children { children { children { children { id } } } } } } }`, &resp)
+		assert.Equal(t, "QUERY_TOO_DEEP", errorCode(t, err))
+	})
+
+	t.Run("error, if limit is above the cap", func(t *testing.T) {
+		err := c.Post(`query($limit: Int) { posts { comments(limit: $limit) { id } } }`, &resp, client.Var("limit", 1000))
+		assert.Equal(t, "LIMIT_OUT_OF_RANGE", errorCode(t, err))
+	})
+
+	t.Run("error, if complexity is too high", func(t *testing.T) {
+		err := c.Post(`{ posts { comments(limit: 50) { id author children(limit: 50) { id author } } } }`, &resp)
+		assert.Equal(t, "COMPLEXITY_LIMIT_EXCEEDED", errorCode(t, err))
+	})
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+// resolveMentions разбирает упоминания в комментарии и оставляет только
+// тех, кто уже писал посты или комментарии
+func (r *Resolver) resolveMentions(ctx context.Context, comment *domain.Comment) error {
+	mentions, err := domain.ParseMentions(comment.Content)
+	if err != nil {
+		return err
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	known, err := r.knownAuthors(ctx, domain.MentionedAuthors(mentions))
+	if err != nil {
+		return err
+	}
+
+	comment.Mentions = nil
+	for _, m := range mentions {
+		if known[m.Author] {
+			comment.Mentions = append(comment.Mentions, m)
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) knownAuthors(ctx context.Context, authors []string) (map[string]bool, error) {
+	known := make(map[string]bool, len(authors))
+
+	fromPosts, err := r.PostRepo.KnownAuthors(ctx, authors)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range fromPosts {
+		known[author] = true
+	}
+
+	var rest []string
+	for _, author := range authors {
+		if !known[author] {
+			rest = append(rest, author)
+		}
+	}
+	if len(rest) == 0 {
+		return known, nil
+	}
+
+	fromComments, err := r.CommentRepo.KnownAuthors(ctx, rest)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range fromComments {
+		known[author] = true
+	}
+
+	return known, nil
+}
@@ -2,21 +2,89 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+type CommentMove struct {
+	Comment      *domain.Comment `json:"comment"`
+	FromPostID   string          `json:"fromPostId"`
+	FromParentID *string         `json:"fromParentId,omitempty"`
+}
+
+type CommentThread struct {
+	Comment          *domain.Comment   `json:"comment"`
+	Ancestors        []*domain.Comment `json:"ancestors"`
+	HasMoreAncestors bool              `json:"hasMoreAncestors"`
+}
+
+type CreateCommentPayload struct {
+	Comment    *domain.Comment `json:"comment,omitempty"`
+	UserErrors []*UserError    `json:"userErrors"`
+}
+
+type CreatePostPayload struct {
+	Post       *domain.Post `json:"post,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+type MoveCommentPayload struct {
+	Comment    *domain.Comment `json:"comment,omitempty"`
+	UserErrors []*UserError    `json:"userErrors"`
+}
+
 type Mutation struct {
 }
 
 type NewCommentInput struct {
-	PostID   string  `json:"postID"`
-	ParentID *string `json:"parentID,omitempty"`
-	Author   string  `json:"author"`
-	Content  string  `json:"content"`
+	PostID         string  `json:"postID"`
+	ParentID       *string `json:"parentID,omitempty"`
+	Author         string  `json:"author"`
+	Content        string  `json:"content"`
+	IdempotencyKey *string `json:"idempotencyKey,omitempty"`
 }
 
 type NewPostInput struct {
-	Title         string `json:"title"`
-	Content       string `json:"content"`
-	Author        string `json:"author"`
-	AllowComments bool   `json:"allowComments"`
+	Title          string             `json:"title"`
+	Content        string             `json:"content"`
+	Author         string             `json:"author"`
+	AllowComments  bool               `json:"allowComments"`
+	Status         *domain.PostStatus `json:"status,omitempty"`
+	PublishAt      *time.Time         `json:"publishAt,omitempty"`
+	Tags           []string           `json:"tags,omitempty"`
+	IdempotencyKey *string            `json:"idempotencyKey,omitempty"`
+}
+
+type NewWebhookInput struct {
+	URL    string                `json:"url"`
+	Events []domain.WebhookEvent `json:"events"`
+	Secret string                `json:"secret"`
+}
+
+type NotificationConnection struct {
+	Edges    []*NotificationEdge `json:"edges"`
+	PageInfo *PageInfo           `json:"pageInfo"`
+}
+
+type NotificationEdge struct {
+	Cursor string               `json:"cursor"`
+	Node   *domain.Notification `json:"node"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
+}
+
+type PinCommentPayload struct {
+	Comment    *domain.Comment `json:"comment,omitempty"`
+	UserErrors []*UserError    `json:"userErrors"`
 }
 
 type Query struct {
@@ -24,3 +92,97 @@ type Query struct {
 
 type Subscription struct {
 }
+
+type ThreadSettingsInput struct {
+	MaxReplyDepth    *int  `json:"maxReplyDepth,omitempty"`
+	MaxCommentLength *int  `json:"maxCommentLength,omitempty"`
+	SlowModeSeconds  *int  `json:"slowModeSeconds,omitempty"`
+	RequireApproval  *bool `json:"requireApproval,omitempty"`
+}
+
+type ToggleCommentsPayload struct {
+	Post       *domain.Post `json:"post,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+type UpdatePostInput struct {
+	Title         *string            `json:"title,omitempty"`
+	Content       *string            `json:"content,omitempty"`
+	AllowComments *bool              `json:"allowComments,omitempty"`
+	Status        *domain.PostStatus `json:"status,omitempty"`
+	PublishAt     *time.Time         `json:"publishAt,omitempty"`
+	Tags          []string           `json:"tags,omitempty"`
+}
+
+type UpdatePostPayload struct {
+	Post       *domain.Post `json:"post,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+type UserError struct {
+	Field       *string `json:"field,omitempty"`
+	Code        string  `json:"code"`
+	Message     string  `json:"message"`
+	MaxLength   *int    `json:"maxLength,omitempty"`
+	MaxMentions *int    `json:"maxMentions,omitempty"`
+}
+
+type VotePostPayload struct {
+	Post       *domain.Post `json:"post,omitempty"`
+	UserErrors []*UserError `json:"userErrors"`
+}
+
+type PostSort string
+
+const (
+	PostSortNew PostSort = "NEW"
+	PostSortHot PostSort = "HOT"
+)
+
+var AllPostSort = []PostSort{
+	PostSortNew,
+	PostSortHot,
+}
+
+func (e PostSort) IsValid() bool {
+	switch e {
+	case PostSortNew, PostSortHot:
+		return true
+	}
+	return false
+}
+
+func (e PostSort) String() string {
+	return string(e)
+}
+
+func (e *PostSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PostSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PostSort", str)
+	}
+	return nil
+}
+
+func (e PostSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PostSort) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PostSort) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,270 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/ranking"
+)
+
+// Мутации с payload возвращают доменные ошибки в userErrors,
+// поэтому сама логика вынесена сюда и возвращает обычную ошибку
+
+func (r *Resolver) createPost(ctx context.Context, input model.NewPostInput) (*domain.Post, error) {
+	// ключ не входит в хеш запроса
+	request := input
+	request.IdempotencyKey = nil
+
+	var post *domain.Post
+	id, replayed, err := r.Idempotency.Do(ctx, "createPost", caller(ctx, input.Author), idempotencyKey(ctx, input.IdempotencyKey), request, func() (string, error) {
+		var err error
+		post, err = r.insertPost(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return post.ID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if replayed {
+		return r.PostRepo.GetByID(ctx, id)
+	}
+	return post, nil
+}
+
+func (r *Resolver) insertPost(ctx context.Context, input model.NewPostInput) (*domain.Post, error) {
+	if err := r.RateLimit.Check(ctx, "createPost", "author:"+input.Author, clientIP(ctx)); err != nil {
+		return nil, err
+	}
+
+	post, err := domain.NewPost(
+		input.Title,
+		input.Content,
+		input.Author,
+		input.AllowComments,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Tags != nil {
+		post.Tags, err = domain.NormalizeTags(input.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := domain.PostPublished
+	if input.Status != nil {
+		status = *input.Status
+	}
+	// пост создается черновиком и переводится в запрошенный статус
+	post.Status = domain.PostDraft
+	post.PublishAt = nil
+	published, err := post.SetStatus(status, input.PublishAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.PostRepo.Create(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	if published {
+		r.PostPublished(ctx, post)
+	}
+	return post, nil
+}
+
+func (r *Resolver) updatePost(ctx context.Context, id string, input model.UpdatePostInput) (*domain.Post, error) {
+	author, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.PostRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !current.VisibleTo(author) {
+		return nil, domain.ErrPostNotFound
+	}
+	if current.Author != author {
+		return nil, domain.ErrForbidden
+	}
+
+	// изменения применяются к копии, чтобы не испортить пост в кэше репозитория
+	post := *current
+	if input.Title != nil {
+		post.Title = *input.Title
+	}
+	if input.Content != nil {
+		post.Content = *input.Content
+	}
+	if input.AllowComments != nil {
+		post.AllowComments = *input.AllowComments
+	}
+	if err := post.Validate(); err != nil {
+		return nil, err
+	}
+	if input.Tags != nil {
+		post.Tags, err = domain.NormalizeTags(input.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	published := false
+	if input.Status != nil || input.PublishAt != nil {
+		status := post.Status
+		if input.Status != nil {
+			status = *input.Status
+		}
+		publishAt := input.PublishAt
+		if publishAt == nil && status == domain.PostScheduled && post.Status == domain.PostScheduled {
+			publishAt = post.PublishAt
+		}
+		published, err = post.SetStatus(status, publishAt, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.PostRepo.Update(ctx, &post); err != nil {
+		return nil, err
+	}
+
+	if published {
+		r.PostPublished(ctx, &post)
+	}
+	return &post, nil
+}
+
+func (r *Resolver) createComment(ctx context.Context, input model.NewCommentInput) (*domain.Comment, error) {
+	request := input
+	request.IdempotencyKey = nil
+
+	var comment *domain.Comment
+	id, replayed, err := r.Idempotency.Do(ctx, "createComment", caller(ctx, input.Author), idempotencyKey(ctx, input.IdempotencyKey), request, func() (string, error) {
+		var err error
+		comment, err = r.insertComment(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return comment.ID, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if replayed {
+		return r.CommentRepo.GetByID(ctx, id)
+	}
+	return comment, nil
+}
+
+func (r *Resolver) insertComment(ctx context.Context, input model.NewCommentInput) (*domain.Comment, error) {
+	if err := r.RateLimit.Check(ctx, "createComment", "author:"+input.Author, clientIP(ctx)); err != nil {
+		return nil, err
+	}
+
+	// проверки и вставка - одна единица работы: toggleComments, перенос
+	// или удаление родителя не пройдут между ними
+	var comment *domain.Comment
+	err := r.atomically(ctx, func(ctx context.Context) error {
+		allowed, err := r.PostRepo.CheckAllowedComments(ctx, input.PostID)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return domain.ErrCommentsOff
+		}
+
+		comment, err = domain.NewComment(
+			input.PostID,
+			input.Author,
+			input.ParentID,
+			input.Content,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := r.checkThreadSettings(ctx, comment); err != nil {
+			return err
+		}
+
+		if err := r.resolveMentions(ctx, comment); err != nil {
+			return err
+		}
+
+		return r.CommentRepo.Create(ctx, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// комментарий на модерации появится у подписчиков после одобрения
+	if !comment.Pending {
+		r.publishComment(ctx, comment)
+	}
+
+	return comment, nil
+}
+
+func (r *Resolver) votePost(ctx context.Context, postID string, value int) (*domain.Post, error) {
+	voter, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.RateLimit.Check(ctx, "votePost", "author:"+voter, clientIP(ctx)); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateVote(value); err != nil {
+		return nil, err
+	}
+
+	post, err := r.PostRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(voter) {
+		return nil, domain.ErrPostNotFound
+	}
+
+	delta, err := r.PostRepo.Vote(ctx, postID, voter, value)
+	if err != nil {
+		return nil, err
+	}
+	if delta != 0 && post.Status == domain.PostPublished {
+		r.rank(ctx, post, ranking.Event{At: time.Now(), Votes: delta})
+	}
+
+	return r.PostRepo.GetByID(ctx, postID)
+}
+
+func (r *Resolver) toggleComments(ctx context.Context, postID string, allow bool) (*domain.Post, error) {
+	if err := r.RateLimit.Check(ctx, "toggleComments", clientIP(ctx)); err != nil {
+		return nil, err
+	}
+
+	if err := r.PostRepo.ToggleComments(ctx, postID, allow); err != nil {
+		return nil, err
+	}
+
+	post, err := r.PostRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Webhooks.Publish(ctx, domain.EventCommentsToggled, post.Author, map[string]interface{}{
+		"postId":        postID,
+		"allowComments": allow,
+	})
+	return post, nil
+}
@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const defaultNotificationsPage = 20
+
+func notificationsChannel(recipient string) string {
+	return "notifications:" + recipient
+}
+
+// notifyAuthors уведомляет автора родительского комментария или поста
+// и упомянутых авторов. Комментарий к этому моменту уже сохранен,
+// поэтому ошибки только логируются
+func (r *Resolver) notifyAuthors(ctx context.Context, comment *domain.Comment) {
+	if r.NotificationRepo == nil {
+		return
+	}
+
+	// каждый получает не больше одного уведомления, себе не шлем
+	notified := map[string]bool{comment.Author: true}
+
+	kind, recipient, err := r.replyRecipient(ctx, comment)
+	if err != nil {
+		log.Printf("failed get reply recipient %v", err)
+	} else {
+		r.notify(ctx, kind, comment, recipient, notified)
+	}
+
+	for _, author := range domain.MentionedAuthors(comment.Mentions) {
+		r.notify(ctx, domain.NotificationMention, comment, author, notified)
+	}
+}
+
+func (r *Resolver) replyRecipient(ctx context.Context, comment *domain.Comment) (domain.NotificationKind, string, error) {
+	if comment.ParentID != nil {
+		parent, err := r.CommentRepo.GetByID(ctx, *comment.ParentID)
+		if err != nil {
+			return "", "", err
+		}
+		return domain.NotificationReply, parent.Author, nil
+	}
+
+	post, err := r.PostRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return "", "", err
+	}
+	return domain.NotificationPostComment, post.Author, nil
+}
+
+func (r *Resolver) notify(ctx context.Context, kind domain.NotificationKind, comment *domain.Comment, recipient string, notified map[string]bool) {
+	if notified[recipient] {
+		return
+	}
+	notified[recipient] = true
+
+	notification := domain.NewNotification(kind, comment, recipient)
+	if err := r.NotificationRepo.Create(ctx, notification); err != nil {
+		log.Printf("failed create notification %v", err)
+		return
+	}
+
+	r.PubSub.Publish(ctx, notificationsChannel(recipient), notification)
+}
+
+// encodeCursor кодирует позицию в ленте в непрозрачную строку
+func encodeCursor(c domain.NotificationCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*domain.NotificationCursor, error) {
+	invalid := &domain.ValidationError{Fields: []domain.FieldError{{Field: "after", Message: "invalid cursor"}}}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, invalid
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &domain.NotificationCursor{CreatedAt: t, ID: id}, nil
+}
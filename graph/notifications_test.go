@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+	redisMocks "github.com/tmozzze/SasPosts/internal/redis/mocks"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+	"github.com/tmozzze/SasPosts/internal/repository/mocks"
+)
+
+func TestNotifyAuthors_Reply(t *testing.T) {
+	mockCommentRepo := mocks.NewCommentRepository(t)
+	mockPublisher := redisMocks.NewPubSub(t)
+	notifications := inmemory.NewInMemoryNotificationRepository()
+
+	parentID := "parent-1"
+	reply, err := domain.NewComment("post-123", "bob", &parentID, "@alice thanks")
+	require.NoError(t, err)
+	reply.Mentions = []domain.Mention{{Author: "alice", Start: 0, End: 6}}
+
+	mockCommentRepo.On("GetByID", mock.Anything, parentID).Return(&domain.Comment{ID: parentID, Author: "alice"}, nil)
+	mockPublisher.On("Publish", mock.Anything, "notifications:alice", mock.Anything).Return(nil).Once()
+
+	resolver := &Resolver{CommentRepo: mockCommentRepo, NotificationRepo: notifications, PubSub: mockPublisher}
+	resolver.notifyAuthors(context.Background(), reply)
+
+	list, err := notifications.List(context.Background(), "alice", domain.NotificationFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list, 1, "reply with mention must produce one notification")
+	assert.Equal(t, domain.NotificationReply, list[0].Kind)
+}
+
+func TestQuery_Notifications(t *testing.T) {
+	notifications := inmemory.NewInMemoryNotificationRepository()
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		n := domain.NewNotification(domain.NotificationReply, &domain.Comment{ID: "c", PostID: "p", Author: "bob"}, "alice")
+		n.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		require.NoError(t, notifications.Create(context.Background(), n))
+	}
+
+	resolver := &Resolver{NotificationRepo: notifications}
+	ctx := middleware.WithViewer(context.Background(), "alice")
+	first := 2
+
+	page, err := resolver.Query().Notifications(ctx, nil, &first, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Edges, 2)
+	assert.True(t, page.PageInfo.HasNextPage)
+	assert.True(t, page.Edges[0].Node.CreatedAt.After(page.Edges[1].Node.CreatedAt))
+
+	next, err := resolver.Query().Notifications(ctx, nil, &first, page.PageInfo.EndCursor)
+	require.NoError(t, err)
+	require.Len(t, next.Edges, 1)
+	assert.False(t, next.PageInfo.HasNextPage)
+
+	marked, err := resolver.Mutation().MarkNotificationsRead(ctx, []string{next.Edges[0].Node.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	unread, err := resolver.Query().UnreadNotificationCount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, unread)
+
+	t.Run("empty list marks nothing", func(t *testing.T) {
+		marked, err := resolver.Mutation().MarkNotificationsRead(ctx, []string{})
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+	})
+
+	t.Run("nil marks all", func(t *testing.T) {
+		marked, err := resolver.Mutation().MarkNotificationsRead(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, marked)
+	})
+
+	t.Run("error, if viewer is unknown", func(t *testing.T) {
+		_, err := resolver.Query().Notifications(context.Background(), nil, nil, nil)
+		assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	})
+
+	t.Run("error, if cursor is invalid", func(t *testing.T) {
+		bad := "not-a-cursor"
+		_, err := resolver.Query().Notifications(ctx, nil, nil, &bad)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+}
@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"errors"
+
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+// userErrorCodes - ошибки ввода, которые клиент показывает рядом с формой.
+// Лимиты, авторизация, идемпотентность и ненайденные сущности остаются
+// в errors ответа с расширениями из ErrorPresenter
+var userErrorCodes = map[string]bool{
+	"VALIDATION_FAILED": true,
+	"COMMENT_TOO_LONG":  true,
+	"REPLY_TOO_DEEP":    true,
+	"TOO_MANY_MENTIONS": true,
+	"TOO_MANY_PINS":     true,
+	"COMMENT_OFF":       true,
+	"THREAD_LOCKED":     true,
+	"COMMENT_CYCLE":     true,
+}
+
+// toUserErrors переводит ошибку ввода мутации в userErrors.
+// Остальные ошибки возвращаются как есть и попадают в errors ответа
+func toUserErrors(err error) ([]*model.UserError, error) {
+	if err == nil {
+		return []*model.UserError{}, nil
+	}
+
+	extensions := errorExtensions(err)
+	if extensions == nil {
+		return nil, err
+	}
+	code := extensions["code"].(string)
+	if !userErrorCodes[code] {
+		return nil, err
+	}
+
+	// ошибка валидации разворачивается в отдельную запись на каждое поле
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		userErrors := make([]*model.UserError, 0, len(validationErr.Fields))
+		for _, f := range validationErr.Fields {
+			field := f.Field
+			userErrors = append(userErrors, &model.UserError{
+				Field:   &field,
+				Code:    code,
+				Message: f.Message,
+			})
+		}
+		return userErrors, nil
+	}
+
+	userErr := &model.UserError{Code: code, Message: err.Error()}
+	if field, ok := extensions["field"].(string); ok {
+		userErr.Field = &field
+	}
+	if maxLength, ok := extensions["maxLength"].(int); ok {
+		userErr.MaxLength = &maxLength
+	}
+	if maxMentions, ok := extensions["maxMentions"].(int); ok {
+		userErr.MaxMentions = &maxMentions
+	}
+
+	return []*model.UserError{userErr}, nil
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// LoadManifest читает манифест доверенных операций в формате {"<sha256>": "<query>"}
+func LoadManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed read persisted queries manifest %w", err)
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed parse persisted queries manifest %w", err)
+	}
+
+	for hash, query := range manifest {
+		if queryHash(query) != hash {
+			return nil, fmt.Errorf("persisted query %s does not match its hash", hash)
+		}
+	}
+
+	return manifest, nil
+}
+
+// TrustedDocuments подставляет текст операции из манифеста по sha256 хешу.
+// В строгом режиме выполняются только операции из манифеста
+type TrustedDocuments struct {
+	Manifest map[string]string
+	Strict   bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = TrustedDocuments{}
+
+func (TrustedDocuments) ExtensionName() string {
+	return "TrustedDocuments"
+}
+
+func (TrustedDocuments) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d TrustedDocuments) MutateOperationParameters(ctx context.Context, params *graphql.RawParams) *gqlerror.Error {
+	hash := persistedQueryHash(params)
+
+	if hash != "" {
+		query, exists := d.Manifest[hash]
+		if exists {
+			if params.Query != "" && params.Query != query {
+				return untrustedOperation("persisted query does not match the manifest")
+			}
+			params.Query = query
+			// текст уже взят из манифеста, APQ дальше проверять нечего
+			delete(params.Extensions, "persistedQuery")
+			return nil
+		}
+		if d.Strict {
+			return untrustedOperation("persisted query is not in the manifest")
+		}
+		return nil
+	}
+
+	if d.Strict {
+		if _, exists := d.Manifest[queryHash(params.Query)]; !exists {
+			return untrustedOperation("only operations from the manifest are allowed")
+		}
+	}
+
+	return nil
+}
+
+func persistedQueryHash(params *graphql.RawParams) string {
+	ext, ok := params.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	hash, _ := ext["sha256Hash"].(string)
+	return hash
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+func untrustedOperation(message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message: message,
+		Extensions: map[string]interface{}{
+			"code": "OPERATION_NOT_TRUSTED",
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/generated"
+	myRedis "github.com/tmozzze/SasPosts/internal/redis"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+const trustedQuery = `{ posts { id title } }`
+
+func newPersistedServer(t *testing.T, exts ...graphql.HandlerExtension) *client.Client {
+	resolver := NewResolver(inmemory.NewInMemoryPostRepository(), inmemory.NewInMemoryCommentRepository(), nil)
+
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(ErrorPresenter)
+	for _, ext := range exts {
+		srv.Use(ext)
+	}
+
+	return client.New(srv)
+}
+
+func persistedQuery(hash string) client.Option {
+	return client.Extensions(map[string]interface{}{
+		"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hash},
+	})
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("valid manifest", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.json")
+		data, _ := json.Marshal(map[string]string{queryHash(trustedQuery): trustedQuery})
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		manifest, err := LoadManifest(path)
+		require.NoError(t, err)
+		assert.Equal(t, trustedQuery, manifest[queryHash(trustedQuery)])
+	})
+
+	t.Run("error, if hash does not match query", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		data, _ := json.Marshal(map[string]string{"deadbeef": trustedQuery})
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		_, err := LoadManifest(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestTrustedDocuments_Strict(t *testing.T) {
+	manifest := map[string]string{queryHash(trustedQuery): trustedQuery}
+	c := newPersistedServer(t, TrustedDocuments{Manifest: manifest, Strict: true})
+	var resp map[string]interface{}
+
+	t.Run("operation by hash", func(t *testing.T) {
+		assert.NoError(t, c.Post("", &resp, persistedQuery(queryHash(trustedQuery))))
+	})
+
+	t.Run("raw query from manifest", func(t *testing.T) {
+		assert.NoError(t, c.Post(trustedQuery, &resp))
+	})
+
+	t.Run("error, if query is not in manifest", func(t *testing.T) {
+		err := c.Post(`{ posts { id } }`, &resp)
+		assert.Equal(t, "OPERATION_NOT_TRUSTED", errorCode(t, err))
+	})
+
+	t.Run("error, if hash is unknown", func(t *testing.T) {
+		err := c.Post("", &resp, persistedQuery(queryHash(`{ posts { id } }`)))
+		assert.Equal(t, "OPERATION_NOT_TRUSTED", errorCode(t, err))
+	})
+}
+
+func TestAutomaticPersistedQuery_Redis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := myRedis.NewQueryCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Hour)
+
+	c := newPersistedServer(t, extension.AutomaticPersistedQuery{Cache: cache})
+	hash := queryHash(trustedQuery)
+	var resp map[string]interface{}
+
+	err := c.Post("", &resp, persistedQuery(hash))
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(t, err))
+
+	require.NoError(t, c.Post(trustedQuery, &resp, persistedQuery(hash)))
+	assert.True(t, mr.Exists("apq:"+hash))
+
+	assert.NoError(t, c.Post("", &resp, persistedQuery(hash)))
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/ranking"
+)
+
+const postsPublishedChannel = "posts:published"
+
+// PostPublished рассылает только что опубликованный пост подписчикам
+// и ставит в очередь вебхук. Вызывается и планировщиком публикаций
+func (r *Resolver) PostPublished(ctx context.Context, post *domain.Post) {
+	r.PubSub.Publish(ctx, postsPublishedChannel, post)
+	r.Webhooks.Publish(ctx, domain.EventPostCreated, post.Author, post)
+	r.rank(ctx, post, ranking.Event{At: time.Now()})
+}
+
+// publishPostWebhook ставит событие поста postID в очередь вебхуков его автора
+func (r *Resolver) publishPostWebhook(ctx context.Context, event domain.WebhookEvent, postID string, data any) {
+	if r.Webhooks == nil {
+		return
+	}
+
+	post, err := r.PostRepo.GetByID(ctx, postID)
+	if err != nil {
+		log.Printf("failed get post for webhook %s %v", event, err)
+		return
+	}
+	r.Webhooks.Publish(ctx, event, post.Author, data)
+}
+
+// rank учитывает событие поста в hot-рейтинге. Ошибка рейтинга
+// не должна ломать мутацию, поэтому только логируется
+func (r *Resolver) rank(ctx context.Context, post *domain.Post, event ranking.Event) {
+	if r.Ranker == nil {
+		return
+	}
+	if err := r.Ranker.Record(ctx, post, event); err != nil {
+		log.Printf("failed rank post %s %v", post.ID, err)
+	}
+}
+
+// rankComment поднимает пост нового видимого комментария
+func (r *Resolver) rankComment(ctx context.Context, comment *domain.Comment) {
+	if r.Ranker == nil {
+		return
+	}
+
+	post, err := r.PostRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		log.Printf("failed rank post %s %v", comment.PostID, err)
+		return
+	}
+	r.rank(ctx, post, ranking.Event{At: comment.CreatedAt, Activity: ranking.CommentWeight})
+}
+
+func (r *Resolver) sortPosts(ctx context.Context, posts []*domain.Post, order model.PostSort) error {
+	if order == model.PostSortHot && r.Ranker != nil {
+		return ranking.SortHot(ctx, r.Ranker, posts)
+	}
+
+	publishedAt := func(post *domain.Post) time.Time {
+		if post.PublishAt != nil {
+			return *post.PublishAt
+		}
+		return post.CreatedAt
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return publishedAt(posts[i]).After(publishedAt(posts[j]))
+	})
+	return nil
+}
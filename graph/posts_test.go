@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+	"github.com/tmozzze/SasPosts/internal/ranking"
+	redisMocks "github.com/tmozzze/SasPosts/internal/redis/mocks"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func TestPostDrafts(t *testing.T) {
+	postRepo := inmemory.NewInMemoryPostRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	resolver := &Resolver{PostRepo: postRepo, PubSub: mockPublisher}
+
+	authorCtx := middleware.WithViewer(context.Background(), "a")
+	otherCtx := middleware.WithViewer(context.Background(), "b")
+
+	draftStatus := domain.PostDraft
+	created, err := resolver.Mutation().CreatePost(authorCtx, model.NewPostInput{
+		Title: "t", Content: "c", Author: "a", Status: &draftStatus,
+	})
+	require.NoError(t, err)
+	require.Empty(t, created.UserErrors)
+	draft := created.Post
+	assert.Nil(t, draft.PublishAt)
+
+	t.Run("draft is visible only to author", func(t *testing.T) {
+		_, err := resolver.Query().Post(otherCtx, draft.ID)
+		assert.ErrorIs(t, err, domain.ErrPostNotFound)
+
+		post, err := resolver.Query().Post(authorCtx, draft.ID)
+		require.NoError(t, err)
+		assert.Equal(t, draft.ID, post.ID)
+
+		posts, err := resolver.Query().Posts(authorCtx, nil, nil, nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+
+		posts, err = resolver.Query().Posts(otherCtx, &draftStatus, nil, nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+
+		posts, err = resolver.Query().Posts(authorCtx, &draftStatus, nil, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 1)
+	})
+
+	t.Run("error, if not author updates", func(t *testing.T) {
+		post, err := domain.NewPost("t", "c", "a", true)
+		require.NoError(t, err)
+		require.NoError(t, postRepo.Create(context.Background(), post))
+		title := "hacked"
+
+		_, err = resolver.Mutation().UpdatePost(otherCtx, post.ID, model.UpdatePostInput{Title: &title})
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("error, if scheduled in the past", func(t *testing.T) {
+		scheduled := domain.PostScheduled
+		past := time.Now().Add(-time.Hour)
+
+		result, err := resolver.Mutation().UpdatePost(authorCtx, draft.ID, model.UpdatePostInput{
+			Status: &scheduled, PublishAt: &past,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "publishAt", *result.UserErrors[0].Field)
+	})
+
+	t.Run("author publishes draft", func(t *testing.T) {
+		published := domain.PostPublished
+		mockPublisher.On("Publish", mock.Anything, "posts:published", mock.AnythingOfType("*domain.Post")).Return(nil).Once()
+
+		result, err := resolver.Mutation().UpdatePost(authorCtx, draft.ID, model.UpdatePostInput{Status: &published})
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.Equal(t, domain.PostPublished, result.Post.Status)
+		assert.NotNil(t, result.Post.PublishAt)
+
+		post, err := resolver.Query().Post(otherCtx, draft.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.PostPublished, post.Status)
+	})
+}
+
+func TestPostTags(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	postRepo := inmemory.NewInMemoryPostRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, "posts:published", mock.AnythingOfType("*domain.Post")).Return(nil)
+	resolver := &Resolver{PostRepo: postRepo, PubSub: mockPublisher}
+
+	create := func(tags ...string) *domain.Post {
+		result, err := resolver.Mutation().CreatePost(ctx, model.NewPostInput{Title: "t", Content: "c", Author: "a", Tags: tags})
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		return result.Post
+	}
+	goPost := create("Go", "backend")
+	create("go", "graphql")
+	create("rust")
+
+	assert.Equal(t, []string{"backend", "go"}, goPost.Tags)
+
+	t.Run("filter by any tag", func(t *testing.T) {
+		posts, err := resolver.Query().Posts(ctx, nil, []string{"graphql", "rust"}, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, posts, 2)
+	})
+
+	t.Run("filter by all tags", func(t *testing.T) {
+		all := domain.TagMatchAll
+		posts, err := resolver.Query().Posts(ctx, nil, []string{"GO", "backend"}, &all, nil)
+		require.NoError(t, err)
+		require.Len(t, posts, 1)
+		assert.Equal(t, goPost.ID, posts[0].ID)
+	})
+
+	t.Run("tags with usage counts", func(t *testing.T) {
+		tags, err := resolver.Query().Tags(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []*domain.Tag{
+			{Name: "go", PostCount: 2},
+			{Name: "backend", PostCount: 1},
+			{Name: "graphql", PostCount: 1},
+			{Name: "rust", PostCount: 1},
+		}, tags)
+	})
+
+	t.Run("update replaces tags", func(t *testing.T) {
+		result, err := resolver.Mutation().UpdatePost(ctx, goPost.ID, model.UpdatePostInput{Tags: []string{"news"}})
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		assert.Equal(t, []string{"news"}, result.Post.Tags)
+	})
+}
+
+func TestPostsHot(t *testing.T) {
+	ctx := context.Background()
+	postRepo := inmemory.NewInMemoryPostRepository()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	mockPublisher := redisMocks.NewPubSub(t)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	resolver := NewResolver(postRepo, commentRepo, mockPublisher, WithRanking(ranking.NewMemoryRanker()))
+
+	create := func(title string) *domain.Post {
+		result, err := resolver.Mutation().CreatePost(ctx, model.NewPostInput{Title: title, Content: "c", Author: "a", AllowComments: true})
+		require.NoError(t, err)
+		require.Empty(t, result.UserErrors)
+		return result.Post
+	}
+	older := create("older")
+	newer := create("newer")
+	newer.PublishAt = ptr(older.PublishAt.Add(time.Second))
+
+	hot := model.PostSortHot
+	ids := func(order *model.PostSort) []string {
+		posts, err := resolver.Query().Posts(ctx, nil, nil, nil, order)
+		require.NoError(t, err)
+		ids := make([]string, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+	assert.Equal(t, []string{newer.ID, older.ID}, ids(nil))
+
+	t.Run("comments lift post", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			result, err := resolver.Mutation().CreateComment(ctx, model.NewCommentInput{PostID: older.ID, Author: "b", Content: "hi"})
+			require.NoError(t, err)
+			require.Empty(t, result.UserErrors)
+		}
+
+		assert.Equal(t, []string{older.ID, newer.ID}, ids(&hot))
+		assert.Equal(t, []string{newer.ID, older.ID}, ids(nil))
+	})
+
+	t.Run("downvotes sink post", func(t *testing.T) {
+		for _, voter := range []string{"b", "c", "d", "e", "f", "g", "h", "i", "j", "k"} {
+			result, err := resolver.Mutation().VotePost(middleware.WithViewer(ctx, voter), older.ID, -1)
+			require.NoError(t, err)
+			require.Empty(t, result.UserErrors)
+		}
+
+		assert.Equal(t, []string{newer.ID, older.ID}, ids(&hot))
+	})
+
+	t.Run("vote is counted once per viewer", func(t *testing.T) {
+		voterCtx := middleware.WithViewer(ctx, "b")
+		result, err := resolver.Mutation().VotePost(voterCtx, newer.ID, 1)
+		require.NoError(t, err)
+		result, err = resolver.Mutation().VotePost(voterCtx, newer.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Post.VoteScore)
+
+		result, err = resolver.Mutation().VotePost(voterCtx, newer.ID, 2)
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "value", *result.UserErrors[0].Field)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMutation_CreatePostIdempotent(t *testing.T) {
+	ctx := middleware.WithIdempotencyKey(middleware.WithViewer(context.Background(), "a"), "post-1")
+	resolver := &Resolver{
+		PostRepo:    inmemory.NewInMemoryPostRepository(),
+		PubSub:      redisMocks.NewPubSub(t),
+		Idempotency: idempotency.NewKeys(idempotency.NewMemoryStore(), time.Hour),
+	}
+
+	draftStatus := domain.PostDraft
+	input := model.NewPostInput{Title: "t", Content: "c", Author: "a", Status: &draftStatus}
+	first, err := resolver.Mutation().CreatePost(ctx, input)
+	require.NoError(t, err)
+	require.Empty(t, first.UserErrors)
+
+	retry, err := resolver.Mutation().CreatePost(ctx, input)
+	require.NoError(t, err)
+	require.Empty(t, retry.UserErrors)
+	assert.Equal(t, first.Post.ID, retry.Post.ID)
+
+	input.Title = "other"
+	_, err = resolver.Mutation().CreatePost(ctx, input)
+	assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+}
@@ -3,8 +3,15 @@
 package graph
 
 import (
+	"context"
+
+	"github.com/tmozzze/SasPosts/internal/idempotency"
+	"github.com/tmozzze/SasPosts/internal/markdown"
+	"github.com/tmozzze/SasPosts/internal/ranking"
+	"github.com/tmozzze/SasPosts/internal/ratelimit"
 	myRedis "github.com/tmozzze/SasPosts/internal/redis"
 	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/webhook"
 )
 
 // This file will not be regenerated automatically.
@@ -15,12 +22,91 @@ type Resolver struct {
 	PostRepo    repository.PostRepository
 	CommentRepo repository.CommentRepository
 	PubSub      myRedis.PubSub
+	RateLimit   *ratelimit.Policy
+	Markdown    *markdown.Renderer
+	// NotificationRepo может быть nil в тестах: тогда уведомления не создаются
+	NotificationRepo repository.NotificationRepository
+	WebhookRepo      repository.WebhookRepository
+	Webhooks         *webhook.Dispatcher
+	// Ranker может быть nil: тогда HOT сортирует по времени публикации
+	Ranker ranking.Ranker
+	// MaxPins - лимит закрепленных комментариев в посте, 0 - domain.DefaultMaxPins
+	MaxPins int
+	// Units может быть nil в тестах: тогда проверки и запись не атомарны
+	Units repository.UnitOfWork
+	// Idempotency может быть nil: тогда ключи идемпотентности игнорируются
+	Idempotency *idempotency.Keys
+}
+
+type Option func(*Resolver)
+
+func WithRateLimit(policy *ratelimit.Policy) Option {
+	return func(r *Resolver) {
+		r.RateLimit = policy
+	}
+}
+
+func WithMarkdown(renderer *markdown.Renderer) Option {
+	return func(r *Resolver) {
+		r.Markdown = renderer
+	}
+}
+
+func WithNotifications(repo repository.NotificationRepository) Option {
+	return func(r *Resolver) {
+		r.NotificationRepo = repo
+	}
+}
+
+func WithWebhooks(repo repository.WebhookRepository) Option {
+	return func(r *Resolver) {
+		r.WebhookRepo = repo
+		r.Webhooks = webhook.NewDispatcher(repo)
+	}
+}
+
+func WithRanking(ranker ranking.Ranker) Option {
+	return func(r *Resolver) {
+		r.Ranker = ranker
+	}
+}
+
+func WithMaxPins(maxPins int) Option {
+	return func(r *Resolver) {
+		r.MaxPins = maxPins
+	}
+}
+
+func WithUnitOfWork(units repository.UnitOfWork) Option {
+	return func(r *Resolver) {
+		r.Units = units
+	}
+}
+
+func WithIdempotency(keys *idempotency.Keys) Option {
+	return func(r *Resolver) {
+		r.Idempotency = keys
+	}
 }
 
-func NewResolver(postRepo repository.PostRepository, commentRepo repository.CommentRepository, pubsub myRedis.PubSub) *Resolver {
-	return &Resolver{
+func NewResolver(postRepo repository.PostRepository, commentRepo repository.CommentRepository, pubsub myRedis.PubSub, opts ...Option) *Resolver {
+	r := &Resolver{
 		PostRepo:    postRepo,
 		CommentRepo: commentRepo,
 		PubSub:      pubsub,
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// atomically выполняет fn в единице работы, если она задана
+func (r *Resolver) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.Units == nil {
+		return fn(ctx)
+	}
+	return r.Units.Do(ctx, fn)
 }
@@ -7,16 +7,87 @@ package graph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/tmozzze/SasPosts/graph/generated"
 	"github.com/tmozzze/SasPosts/graph/model"
 	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/middleware"
 )
 
+// ContentHTML is the resolver for the contentHTML field.
+func (r *commentResolver) ContentHTML(ctx context.Context, obj *domain.Comment) (string, error) {
+	return r.Markdown.Render(obj.Content).HTML, nil
+}
+
+// PlainText is the resolver for the plainText field.
+func (r *commentResolver) PlainText(ctx context.Context, obj *domain.Comment) (string, error) {
+	return r.Markdown.Render(obj.Content).PlainText, nil
+}
+
+// ReplyCount is the resolver for the replyCount field.
+func (r *commentResolver) ReplyCount(ctx context.Context, obj *domain.Comment) (int, error) {
+	stats, err := r.commentStats(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return stats.ReplyCount, nil
+}
+
+// DescendantCount is the resolver for the descendantCount field.
+func (r *commentResolver) DescendantCount(ctx context.Context, obj *domain.Comment) (int, error) {
+	stats, err := r.commentStats(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return stats.DescendantCount, nil
+}
+
+// IsLocked is the resolver for the isLocked field.
+func (r *commentResolver) IsLocked(ctx context.Context, obj *domain.Comment) (bool, error) {
+	if obj.Locked || obj.ParentID == nil {
+		return obj.Locked, nil
+	}
+	// блокировка наследуется от любого предка
+	return r.CommentRepo.ThreadLocked(ctx, obj.ID)
+}
+
+// IsPinned is the resolver for the isPinned field.
+func (r *commentResolver) IsPinned(ctx context.Context, obj *domain.Comment) (bool, error) {
+	return obj.PinnedAt != nil, nil
+}
+
+// Post is the resolver for the post field.
+func (r *commentResolver) Post(ctx context.Context, obj *domain.Comment) (*domain.Post, error) {
+	return r.PostRepo.GetByID(ctx, obj.PostID)
+}
+
+// Parent is the resolver for the parent field.
+func (r *commentResolver) Parent(ctx context.Context, obj *domain.Comment) (*domain.Comment, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	return r.CommentRepo.GetByID(ctx, *obj.ParentID)
+}
+
+// Ancestors is the resolver for the ancestors field.
+func (r *commentResolver) Ancestors(ctx context.Context, obj *domain.Comment) ([]*domain.Comment, error) {
+	if obj.ParentID == nil {
+		return []*domain.Comment{}, nil
+	}
+
+	thread, err := r.CommentRepo.GetWithAncestors(ctx, obj.ID, obj.Depth)
+	if err != nil {
+		return nil, err
+	}
+	return thread[:len(thread)-1], nil
+}
+
 // Children is the resolver for the children field.
 func (r *commentResolver) Children(ctx context.Context, obj *domain.Comment, limit *int, offset *int) ([]*domain.Comment, error) {
-	lim := 10
+	lim := defaultPageLimit
 	if limit != nil {
 		lim = *limit
 	}
@@ -28,64 +99,233 @@ func (r *commentResolver) Children(ctx context.Context, obj *domain.Comment, lim
 }
 
 // CreatePost is the resolver for the createPost field.
-func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPostInput) (*domain.Post, error) {
-	post := domain.NewPost(
-		input.Title,
-		input.Content,
-		input.Author,
-		input.AllowComments,
-	)
+func (r *mutationResolver) CreatePost(ctx context.Context, input model.NewPostInput) (*model.CreatePostPayload, error) {
+	post, err := r.createPost(ctx, input)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatePostPayload{Post: post, UserErrors: userErrors}, nil
+}
 
-	err := r.PostRepo.Create(ctx, post)
+// UpdatePost is the resolver for the updatePost field.
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, input model.UpdatePostInput) (*model.UpdatePostPayload, error) {
+	post, err := r.updatePost(ctx, id, input)
+	userErrors, err := toUserErrors(err)
 	if err != nil {
 		return nil, err
 	}
+	return &model.UpdatePostPayload{Post: post, UserErrors: userErrors}, nil
+}
 
-	return post, nil
+// VotePost is the resolver for the votePost field.
+func (r *mutationResolver) VotePost(ctx context.Context, postID string, value int) (*model.VotePostPayload, error) {
+	post, err := r.votePost(ctx, postID, value)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.VotePostPayload{Post: post, UserErrors: userErrors}, nil
 }
 
 // CreateComment is the resolver for the createComment field.
-func (r *mutationResolver) CreateComment(ctx context.Context, input model.NewCommentInput) (*domain.Comment, error) {
-	allowed, err := r.PostRepo.CheckAllowedComments(ctx, input.PostID)
+func (r *mutationResolver) CreateComment(ctx context.Context, input model.NewCommentInput) (*model.CreateCommentPayload, error) {
+	comment, err := r.createComment(ctx, input)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreateCommentPayload{Comment: comment, UserErrors: userErrors}, nil
+}
+
+// ToggleComments is the resolver for the toggleComments field.
+func (r *mutationResolver) ToggleComments(ctx context.Context, postID string, allow bool) (*model.ToggleCommentsPayload, error) {
+	post, err := r.toggleComments(ctx, postID, allow)
+	userErrors, err := toUserErrors(err)
 	if err != nil {
 		return nil, err
 	}
-	if !allowed {
-		return nil, domain.ErrCommentsOff
+	return &model.ToggleCommentsPayload{Post: post, UserErrors: userErrors}, nil
+}
+
+// UpdateThreadSettings is the resolver for the updateThreadSettings field.
+func (r *mutationResolver) UpdateThreadSettings(ctx context.Context, postID string, input model.ThreadSettingsInput) (*domain.Post, error) {
+	return r.updateThreadSettings(ctx, postID, input)
+}
+
+// ApproveComment is the resolver for the approveComment field.
+func (r *mutationResolver) ApproveComment(ctx context.Context, commentID string) (*domain.Comment, error) {
+	return r.approveComment(ctx, commentID)
+}
+
+// MarkNotificationsRead is the resolver for the markNotificationsRead field.
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int, error) {
+	recipient, err := viewer(ctx)
+	if err != nil {
+		return 0, err
 	}
+	return r.NotificationRepo.MarkRead(ctx, recipient, ids)
+}
 
-	comment, err := domain.NewComment(
-		input.PostID,
-		input.Author,
-		input.ParentID,
-		input.Content,
-	)
+// CreateWebhook is the resolver for the createWebhook field.
+func (r *mutationResolver) CreateWebhook(ctx context.Context, input model.NewWebhookInput) (*domain.Webhook, error) {
+	owner, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	hook, err := domain.NewWebhook(owner, input.URL, input.Events, input.Secret)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := r.CommentRepo.Create(ctx, comment); err != nil {
+	if err := r.WebhookRepo.Create(ctx, hook); err != nil {
 		return nil, err
 	}
+	return hook, nil
+}
 
-	channelName := fmt.Sprintf("comments:%s", comment.PostID)
-	r.PubSub.Publish(ctx, channelName, comment)
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	if _, err := r.ownWebhook(ctx, id); err != nil {
+		return false, err
+	}
 
-	return comment, nil
+	if err := r.WebhookRepo.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// ToggleComments is the resolver for the toggleComments field.
-func (r *mutationResolver) ToggleComments(ctx context.Context, postID string, allow bool) (*domain.Post, error) {
-	if err := r.PostRepo.ToggleComments(ctx, postID, allow); err != nil {
+// RedeliverWebhook is the resolver for the redeliverWebhook field.
+func (r *mutationResolver) RedeliverWebhook(ctx context.Context, deliveryID string) (*domain.WebhookDelivery, error) {
+	delivery, err := r.WebhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
 		return nil, err
 	}
-	return r.PostRepo.GetByID(ctx, postID)
+	if _, err := r.ownWebhook(ctx, delivery.WebhookID); err != nil {
+		return nil, err
+	}
+
+	// повторная доставка начинает отсчет попыток заново
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+
+	if err := r.WebhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// MoveComment is the resolver for the moveComment field.
+func (r *mutationResolver) MoveComment(ctx context.Context, commentID string, newParentID *string, newPostID *string) (*model.MoveCommentPayload, error) {
+	comment, err := r.moveComment(ctx, commentID, newParentID, newPostID)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.MoveCommentPayload{Comment: comment, UserErrors: userErrors}, nil
+}
+
+// LockThread is the resolver for the lockThread field.
+func (r *mutationResolver) LockThread(ctx context.Context, commentID string) (*domain.Comment, error) {
+	return r.lockThread(ctx, commentID, true)
+}
+
+// UnlockThread is the resolver for the unlockThread field.
+func (r *mutationResolver) UnlockThread(ctx context.Context, commentID string) (*domain.Comment, error) {
+	return r.lockThread(ctx, commentID, false)
+}
+
+// PinComment is the resolver for the pinComment field.
+func (r *mutationResolver) PinComment(ctx context.Context, commentID string) (*model.PinCommentPayload, error) {
+	comment, err := r.pinComment(ctx, commentID, true)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PinCommentPayload{Comment: comment, UserErrors: userErrors}, nil
+}
+
+// UnpinComment is the resolver for the unpinComment field.
+func (r *mutationResolver) UnpinComment(ctx context.Context, commentID string) (*model.PinCommentPayload, error) {
+	comment, err := r.pinComment(ctx, commentID, false)
+	userErrors, err := toUserErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PinCommentPayload{Comment: comment, UserErrors: userErrors}, nil
+}
+
+// Post is the resolver for the post field.
+func (r *notificationResolver) Post(ctx context.Context, obj *domain.Notification) (*domain.Post, error) {
+	post, err := r.PostRepo.GetByID(ctx, obj.PostID)
+	if errors.Is(err, domain.ErrPostNotFound) {
+		return nil, nil
+	}
+	return post, err
+}
+
+// Comment is the resolver for the comment field.
+func (r *notificationResolver) Comment(ctx context.Context, obj *domain.Notification) (*domain.Comment, error) {
+	comment, err := r.CommentRepo.GetByID(ctx, obj.CommentID)
+	if errors.Is(err, domain.ErrCommentNotFound) {
+		return nil, nil
+	}
+	return comment, err
+}
+
+// Read is the resolver for the read field.
+func (r *notificationResolver) Read(ctx context.Context, obj *domain.Notification) (bool, error) {
+	return obj.ReadAt != nil, nil
+}
+
+// ContentHTML is the resolver for the contentHTML field.
+func (r *postResolver) ContentHTML(ctx context.Context, obj *domain.Post) (string, error) {
+	return r.Markdown.Render(obj.Content).HTML, nil
+}
+
+// PlainText is the resolver for the plainText field.
+func (r *postResolver) PlainText(ctx context.Context, obj *domain.Post) (string, error) {
+	return r.Markdown.Render(obj.Content).PlainText, nil
+}
+
+// CommentCount is the resolver for the commentCount field.
+func (r *postResolver) CommentCount(ctx context.Context, obj *domain.Post) (int, error) {
+	stats, err := r.postStats(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return stats.CommentCount, nil
+}
+
+// ParticipantCount is the resolver for the participantCount field.
+func (r *postResolver) ParticipantCount(ctx context.Context, obj *domain.Post) (int, error) {
+	stats, err := r.postStats(ctx, obj.ID)
+	if err != nil {
+		return 0, err
+	}
+	return stats.ParticipantCount, nil
+}
+
+// LastCommentAt is the resolver for the lastCommentAt field.
+func (r *postResolver) LastCommentAt(ctx context.Context, obj *domain.Post) (*time.Time, error) {
+	stats, err := r.postStats(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return stats.LastCommentAt, nil
+}
+
+// PinnedComments is the resolver for the pinnedComments field.
+func (r *postResolver) PinnedComments(ctx context.Context, obj *domain.Post) ([]*domain.Comment, error) {
+	return r.CommentRepo.GetPinned(ctx, obj.ID)
 }
 
 // Comments is the resolver for the comments field.
 func (r *postResolver) Comments(ctx context.Context, obj *domain.Post, limit *int, offset *int) ([]*domain.Comment, error) {
-	lim := 10
+	lim := defaultPageLimit
 	if limit != nil {
 		lim = *limit
 	}
@@ -97,13 +337,165 @@ func (r *postResolver) Comments(ctx context.Context, obj *domain.Post, limit *in
 }
 
 // Posts is the resolver for the posts field.
-func (r *queryResolver) Posts(ctx context.Context) ([]*domain.Post, error) {
-	return r.PostRepo.GetAll(ctx)
+func (r *queryResolver) Posts(ctx context.Context, status *domain.PostStatus, tags []string, tagMatch *domain.TagMatch, sort *model.PostSort) ([]*domain.Post, error) {
+	filter := domain.PostFilter{Status: domain.PostPublished, TagMatch: domain.TagMatchAny}
+	if status != nil {
+		filter.Status = *status
+	}
+	if tagMatch != nil {
+		filter.TagMatch = *tagMatch
+	}
+	if tags != nil {
+		normalized, err := domain.NormalizeTags(tags)
+		if err != nil {
+			return nil, err
+		}
+		filter.Tags = normalized
+	}
+
+	// свои черновики и запланированные посты видит только автор
+	if filter.Status == domain.PostDraft || filter.Status == domain.PostScheduled {
+		author, err := viewer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		filter.Author = author
+	}
+
+	posts, err := r.PostRepo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	order := model.PostSortNew
+	if sort != nil {
+		order = *sort
+	}
+	if err := r.sortPosts(ctx, posts, order); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// Tags is the resolver for the tags field.
+func (r *queryResolver) Tags(ctx context.Context, limit *int) ([]*domain.Tag, error) {
+	lim := defaultPageLimit
+	if limit != nil {
+		lim = *limit
+	}
+	return r.PostRepo.ListTags(ctx, lim)
 }
 
 // Post is the resolver for the post field.
 func (r *queryResolver) Post(ctx context.Context, id string) (*domain.Post, error) {
-	return r.PostRepo.GetByID(ctx, id)
+	post, err := r.PostRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(middleware.ViewerFromContext(ctx)) {
+		return nil, domain.ErrPostNotFound
+	}
+	return post, nil
+}
+
+// Comment is the resolver for the comment field.
+func (r *queryResolver) Comment(ctx context.Context, id string, contextDepth *int) (*model.CommentThread, error) {
+	depth := defaultContextDepth
+	if contextDepth != nil {
+		depth = *contextDepth
+	}
+	if depth < 0 {
+		return nil, &domain.ValidationError{Fields: []domain.FieldError{{Field: "contextDepth", Message: "must not be negative"}}}
+	}
+
+	thread, err := r.CommentRepo.GetWithAncestors(ctx, id, depth)
+	if err != nil {
+		return nil, err
+	}
+	comment := thread[len(thread)-1]
+
+	// ссылка не должна раскрывать скрытые комментарии и посты
+	if comment.Pending {
+		return nil, domain.ErrCommentNotFound
+	}
+	post, err := r.PostRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(middleware.ViewerFromContext(ctx)) {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	ancestors := thread[:len(thread)-1]
+	return &model.CommentThread{
+		Comment:          comment,
+		Ancestors:        ancestors,
+		HasMoreAncestors: comment.Depth > len(ancestors),
+	}, nil
+}
+
+// Notifications is the resolver for the notifications field.
+func (r *queryResolver) Notifications(ctx context.Context, unreadOnly *bool, first *int, after *string) (*model.NotificationConnection, error) {
+	recipient, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := domain.NotificationFilter{Limit: defaultNotificationsPage}
+	if unreadOnly != nil {
+		filter.UnreadOnly = *unreadOnly
+	}
+	if first != nil {
+		filter.Limit = *first
+	}
+	if after != nil {
+		filter.After, err = decodeCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// берем на одно больше, чтобы узнать, есть ли следующая страница
+	page := filter.Limit
+	filter.Limit++
+	notifications, err := r.NotificationRepo.List(ctx, recipient, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.NotificationConnection{
+		Edges:    make([]*model.NotificationEdge, 0, len(notifications)),
+		PageInfo: &model.PageInfo{HasNextPage: len(notifications) > page},
+	}
+	if conn.PageInfo.HasNextPage {
+		notifications = notifications[:page]
+	}
+	for _, n := range notifications {
+		conn.Edges = append(conn.Edges, &model.NotificationEdge{Cursor: encodeCursor(n.Cursor()), Node: n})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn, nil
+}
+
+// UnreadNotificationCount is the resolver for the unreadNotificationCount field.
+func (r *queryResolver) UnreadNotificationCount(ctx context.Context) (int, error) {
+	recipient, err := viewer(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.NotificationRepo.CountUnread(ctx, recipient)
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	owner, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.WebhookRepo.List(ctx, owner)
 }
 
 // CommentAdded is the resolver for the commentAdded field.
@@ -137,12 +529,114 @@ func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string)
 	return gqlChan, nil
 }
 
+// CommentMoved is the resolver for the commentMoved field.
+func (r *subscriptionResolver) CommentMoved(ctx context.Context, postID string) (<-chan *model.CommentMove, error) {
+	_, err := r.PostRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	msgChan, closeFunc := r.PubSub.Subscribe(ctx, commentMovedChannel(postID))
+
+	gqlChan := make(chan *model.CommentMove)
+
+	go func() {
+		defer closeFunc()
+		defer close(gqlChan)
+
+		for payload := range msgChan {
+			var move model.CommentMove
+			if err := json.Unmarshal(payload, &move); err == nil {
+				select {
+				case gqlChan <- &move:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return gqlChan, nil
+}
+
+// NotificationAdded is the resolver for the notificationAdded field.
+func (r *subscriptionResolver) NotificationAdded(ctx context.Context) (<-chan *domain.Notification, error) {
+	recipient, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msgChan, closeFunc := r.PubSub.Subscribe(ctx, notificationsChannel(recipient))
+
+	gqlChan := make(chan *domain.Notification)
+
+	go func() {
+		defer closeFunc()
+		defer close(gqlChan)
+
+		for payload := range msgChan {
+			var notification domain.Notification
+			if err := json.Unmarshal(payload, &notification); err == nil {
+				select {
+				case gqlChan <- &notification:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return gqlChan, nil
+}
+
+// PostPublished is the resolver for the postPublished field.
+func (r *subscriptionResolver) PostPublished(ctx context.Context) (<-chan *domain.Post, error) {
+	msgChan, closeFunc := r.PubSub.Subscribe(ctx, postsPublishedChannel)
+
+	gqlChan := make(chan *domain.Post)
+
+	go func() {
+		defer closeFunc()
+		defer close(gqlChan)
+
+		for payload := range msgChan {
+			var post domain.Post
+			if err := json.Unmarshal(payload, &post); err == nil {
+				select {
+				case gqlChan <- &post:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return gqlChan, nil
+}
+
+// Deliveries is the resolver for the deliveries field.
+func (r *webhookResolver) Deliveries(ctx context.Context, obj *domain.Webhook, status *domain.DeliveryStatus, limit *int) ([]*domain.WebhookDelivery, error) {
+	lim := defaultPageLimit
+	if limit != nil {
+		lim = *limit
+	}
+	return r.WebhookRepo.ListDeliveries(ctx, obj.ID, status, lim)
+}
+
+// Payload is the resolver for the payload field.
+func (r *webhookDeliveryResolver) Payload(ctx context.Context, obj *domain.WebhookDelivery) (string, error) {
+	return string(obj.Payload), nil
+}
+
 // Comment returns generated.CommentResolver implementation.
 func (r *Resolver) Comment() generated.CommentResolver { return &commentResolver{r} }
 
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Notification returns generated.NotificationResolver implementation.
+func (r *Resolver) Notification() generated.NotificationResolver { return &notificationResolver{r} }
+
 // Post returns generated.PostResolver implementation.
 func (r *Resolver) Post() generated.PostResolver { return &postResolver{r} }
 
@@ -152,8 +646,19 @@ func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 // Subscription returns generated.SubscriptionResolver implementation.
 func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
 
+// Webhook returns generated.WebhookResolver implementation.
+func (r *Resolver) Webhook() generated.WebhookResolver { return &webhookResolver{r} }
+
+// WebhookDelivery returns generated.WebhookDeliveryResolver implementation.
+func (r *Resolver) WebhookDelivery() generated.WebhookDeliveryResolver {
+	return &webhookDeliveryResolver{r}
+}
+
 type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type webhookResolver struct{ *Resolver }
+type webhookDeliveryResolver struct{ *Resolver }
@@ -3,13 +3,17 @@ package graph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/tmozzze/SasPosts/graph/model"
 	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/ratelimit"
 	redisMocks "github.com/tmozzze/SasPosts/internal/redis/mocks"
 	"github.com/tmozzze/SasPosts/internal/repository/mocks"
 )
@@ -18,6 +22,7 @@ func TestQuery_Post(t *testing.T) {
 	mockPostRepo := mocks.NewPostRepository(t)
 	expectedPost := &domain.Post{
 		ID: "post-123", Title: "Test post", Content: "Content", Author: "Tester123",
+		Status: domain.PostPublished,
 	}
 
 	mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(expectedPost, nil)
@@ -35,29 +40,56 @@ func TestQuery_Posts(t *testing.T) {
 		{ID: "post-1"},
 		{ID: "post-2"},
 	}
-	mockPostRepo.On("GetAll", mock.Anything).Return(expectedPosts, nil)
+	mockPostRepo.On("GetAll", mock.Anything, domain.PostFilter{Status: domain.PostPublished, TagMatch: domain.TagMatchAny}).Return(expectedPosts, nil)
 
 	resolver := &Resolver{PostRepo: mockPostRepo}
-	result, err := resolver.Query().Posts(context.Background())
+	result, err := resolver.Query().Posts(context.Background(), nil, nil, nil, nil)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedPosts, result)
 	mockPostRepo.AssertExpectations(t)
 }
 
+func testPost() *domain.Post {
+	return &domain.Post{
+		ID: "post-123", Title: "t", Content: "c", Author: "a",
+		AllowComments: true, Status: domain.PostPublished, Settings: domain.DefaultThreadSettings(),
+	}
+}
+
 func TestMutation_CreatePost(t *testing.T) {
 	mockPostRepo := mocks.NewPostRepository(t)
 	input := model.NewPostInput{
 		Title: "Post", Content: "Content", Author: "Author", AllowComments: true,
 	}
+	mockPublisher := redisMocks.NewPubSub(t)
 	mockPostRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil)
-	resolver := &Resolver{PostRepo: mockPostRepo}
+	mockPublisher.On("Publish", mock.Anything, "posts:published", mock.AnythingOfType("*domain.Post")).Return(nil)
+	resolver := &Resolver{PostRepo: mockPostRepo, PubSub: mockPublisher}
 	result, err := resolver.Mutation().CreatePost(context.Background(), input)
 
 	assert.NoError(t, err)
-	assert.Equal(t, input.Title, result.Title)
-	assert.NotEmpty(t, result.ID)
+	assert.Empty(t, result.UserErrors)
+	assert.Equal(t, input.Title, result.Post.Title)
+	assert.Equal(t, domain.PostPublished, result.Post.Status)
+	assert.NotEmpty(t, result.Post.ID)
 	mockPostRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestMutation_CreatePost_Invalid(t *testing.T) {
+	mockPostRepo := mocks.NewPostRepository(t)
+	input := model.NewPostInput{Title: "  ", Content: "Content", Author: ""}
+
+	resolver := &Resolver{PostRepo: mockPostRepo}
+	result, err := resolver.Mutation().CreatePost(context.Background(), input)
+
+	require.NoError(t, err)
+	assert.Nil(t, result.Post)
+	require.Len(t, result.UserErrors, 2)
+	assert.Equal(t, "VALIDATION_FAILED", result.UserErrors[0].Code)
+	assert.Equal(t, "title", *result.UserErrors[0].Field)
+	assert.Equal(t, "author", *result.UserErrors[1].Field)
 }
 
 func TestMutation_CreateComment(t *testing.T) {
@@ -71,6 +103,7 @@ func TestMutation_CreateComment(t *testing.T) {
 		}
 
 		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(testPost(), nil)
 		mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
 
 		channelName := fmt.Sprintf("comments:%s", input.PostID)
@@ -85,7 +118,8 @@ func TestMutation_CreateComment(t *testing.T) {
 		result, err := resolver.Mutation().CreateComment(context.Background(), input)
 
 		assert.NoError(t, err)
-		assert.Equal(t, input.Content, result.Content)
+		assert.Empty(t, result.UserErrors)
+		assert.Equal(t, input.Content, result.Comment.Content)
 		mockPostRepo.AssertExpectations(t)
 		mockCommentRepo.AssertExpectations(t)
 		mockPublisher.AssertExpectations(t)
@@ -97,11 +131,184 @@ func TestMutation_CreateComment(t *testing.T) {
 
 		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-456").Return(false, nil)
 		resolver := &Resolver{PostRepo: mockPostRepo}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		require.NoError(t, err)
+		assert.Nil(t, result.Comment)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "COMMENT_OFF", result.UserErrors[0].Code)
+		mockPostRepo.AssertExpectations(t)
+	})
+
+	t.Run("pending comment is not published", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		mockCommentRepo := mocks.NewCommentRepository(t)
+
+		post := testPost()
+		post.Settings.RequireApproval = true
+		input := model.NewCommentInput{PostID: "post-123", Author: "commenter", Content: "needs review"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(post, nil)
+		mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
+
+		resolver := &Resolver{PostRepo: mockPostRepo, CommentRepo: mockCommentRepo, PubSub: redisMocks.NewPubSub(t)}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Comment.Pending)
+	})
+
+	t.Run("error, if reply is too deep", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		mockCommentRepo := mocks.NewCommentRepository(t)
+
+		post := testPost()
+		post.Settings.MaxReplyDepth = 1
+		parentID := "parent-1"
+		input := model.NewCommentInput{PostID: "post-123", ParentID: &parentID, Author: "commenter", Content: "deep"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(post, nil)
+		mockCommentRepo.On("GetByID", mock.Anything, parentID).Return(&domain.Comment{ID: parentID, Depth: 1}, nil)
+
+		resolver := &Resolver{PostRepo: mockPostRepo, CommentRepo: mockCommentRepo}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "REPLY_TOO_DEEP", result.UserErrors[0].Code)
+	})
+
+	t.Run("error, if slow mode is on", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		mockCommentRepo := mocks.NewCommentRepository(t)
+
+		post := testPost()
+		post.Settings.SlowModeSeconds = 60
+		input := model.NewCommentInput{PostID: "post-123", Author: "commenter", Content: "again"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(post, nil)
+		mockCommentRepo.On("LastCommentTime", mock.Anything, "post-123", "commenter").Return(time.Now(), nil)
+
+		resolver := &Resolver{PostRepo: mockPostRepo, CommentRepo: mockCommentRepo}
+		_, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		var slowModeErr *domain.SlowModeError
+		assert.ErrorAs(t, err, &slowModeErr)
+		assert.Equal(t, "SLOW_MODE", ErrorPresenter(context.Background(), err).Extensions["code"])
+	})
+
+	t.Run("error, if author is rate limited", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		input := model.NewCommentInput{PostID: "post-789", Author: "spammer"}
+
+		policy := ratelimit.NewPolicy(ratelimit.NewMemoryLimiter(), map[string]ratelimit.Limit{
+			"createComment": {Burst: 1, Period: time.Minute},
+		})
+		require.NoError(t, policy.Check(context.Background(), "createComment", "author:spammer"))
+
+		resolver := &Resolver{PostRepo: mockPostRepo, RateLimit: policy}
 		_, err := resolver.Mutation().CreateComment(context.Background(), input)
 
+		var limitErr *ratelimit.LimitError
+		assert.ErrorAs(t, err, &limitErr)
+		mockPostRepo.AssertNotCalled(t, "CheckAllowedComments", mock.Anything, mock.Anything)
+
+		gqlErr := ErrorPresenter(context.Background(), err)
+		assert.Equal(t, "RATE_LIMITED", gqlErr.Extensions["code"])
+		assert.Equal(t, 60, gqlErr.Extensions["retryAfter"])
+	})
+
+	t.Run("comment too long reports max length", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+
+		post := testPost()
+		post.Settings.MaxCommentLength = 5
+		input := model.NewCommentInput{PostID: "post-123", Author: "commenter", Content: "too long"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(post, nil)
+
+		resolver := &Resolver{PostRepo: mockPostRepo}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "COMMENT_TOO_LONG", result.UserErrors[0].Code)
+		assert.Equal(t, "content", *result.UserErrors[0].Field)
+		assert.Equal(t, 5, *result.UserErrors[0].MaxLength)
+	})
+
+	t.Run("mentions known authors and notifies them with post author", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		mockCommentRepo := mocks.NewCommentRepository(t)
+		mockNotificationRepo := mocks.NewNotificationRepository(t)
+		mockPublisher := redisMocks.NewPubSub(t)
+
+		input := model.NewCommentInput{
+			PostID: "post-123", Author: "commenter", Content: "@alice @ghost @commenter look",
+		}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(testPost(), nil)
+		mockPostRepo.On("KnownAuthors", mock.Anything, []string{"alice", "ghost", "commenter"}).Return([]string{"alice"}, nil)
+		mockCommentRepo.On("KnownAuthors", mock.Anything, []string{"ghost", "commenter"}).Return([]string{"commenter"}, nil)
+		mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
+		mockNotificationRepo.On("Create", mock.Anything, mock.MatchedBy(func(n *domain.Notification) bool {
+			return n.Recipient == "alice" && n.Actor == "commenter" && n.Kind == domain.NotificationMention
+		})).Return(nil).Once()
+		mockNotificationRepo.On("Create", mock.Anything, mock.MatchedBy(func(n *domain.Notification) bool {
+			return n.Recipient == "a" && n.Kind == domain.NotificationPostComment
+		})).Return(nil).Once()
+		mockPublisher.On("Publish", mock.Anything, "comments:post-123", mock.Anything).Return(nil)
+		mockPublisher.On("Publish", mock.Anything, "notifications:a", mock.AnythingOfType("*domain.Notification")).Return(nil)
+		mockPublisher.On("Publish", mock.Anything, "notifications:alice", mock.AnythingOfType("*domain.Notification")).Return(nil)
+
+		resolver := &Resolver{
+			PostRepo:         mockPostRepo,
+			CommentRepo:      mockCommentRepo,
+			NotificationRepo: mockNotificationRepo,
+			PubSub:           mockPublisher,
+		}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		require.NoError(t, err)
+		assert.Empty(t, result.UserErrors)
+		assert.Equal(t, []domain.Mention{
+			{Author: "alice", Start: 0, End: 6},
+			{Author: "commenter", Start: 14, End: 24},
+		}, result.Comment.Mentions)
+	})
+
+	t.Run("error, if too many mentions", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		input := model.NewCommentInput{PostID: "post-123", Author: "commenter", Content: "@a @b @c @d @e @f"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(true, nil)
+		mockPostRepo.On("GetByID", mock.Anything, "post-123").Return(testPost(), nil)
+
+		resolver := &Resolver{PostRepo: mockPostRepo}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
+		require.NoError(t, err)
+		require.Len(t, result.UserErrors, 1)
+		assert.Equal(t, "TOO_MANY_MENTIONS", result.UserErrors[0].Code)
+		assert.Equal(t, domain.MaxMentionsPerComment, *result.UserErrors[0].MaxMentions)
+	})
+
+	t.Run("unknown error stays top-level", func(t *testing.T) {
+		mockPostRepo := mocks.NewPostRepository(t)
+		input := model.NewCommentInput{PostID: "post-123", Author: "commenter", Content: "hi"}
+
+		mockPostRepo.On("CheckAllowedComments", mock.Anything, "post-123").Return(false, errors.New("db is down"))
+
+		resolver := &Resolver{PostRepo: mockPostRepo}
+		result, err := resolver.Mutation().CreateComment(context.Background(), input)
+
 		assert.Error(t, err)
-		assert.ErrorIs(t, err, domain.ErrCommentsOff)
-		mockPostRepo.AssertExpectations(t)
+		assert.Nil(t, result)
 	})
 }
 
@@ -117,7 +324,8 @@ func TestMutation_ToggleComments(t *testing.T) {
 	result, err := resolver.Mutation().ToggleComments(context.Background(), postID, false)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedPost, result)
+	assert.Equal(t, expectedPost, result.Post)
+	assert.Empty(t, result.UserErrors)
 	mockPostRepo.AssertExpectations(t)
 }
 
@@ -190,3 +398,16 @@ func TestSubscription_CommentAdded(t *testing.T) {
 	mockPostRepo.AssertExpectations(t)
 	mockPubSub.AssertExpectations(t)
 }
+
+func TestCommentResolver_ContentHTML(t *testing.T) {
+	resolver := &Resolver{}
+	comment := &domain.Comment{Content: "*hi* <script>x</script>"}
+
+	contentHTML, err := resolver.Comment().ContentHTML(context.Background(), comment)
+	require.NoError(t, err)
+	assert.Equal(t, "<p><em>hi</em> x</p>\n", contentHTML)
+
+	plainText, err := resolver.Comment().PlainText(context.Background(), comment)
+	require.NoError(t, err)
+	assert.Equal(t, "hi x", plainText)
+}
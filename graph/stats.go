@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type statsLoaderKey struct{}
+
+// statsCall - один запрос счетчиков, который ждут все поля сущности
+type statsCall[T any] struct {
+	once  sync.Once
+	stats T
+	err   error
+}
+
+// statsLoader запоминает счетчики постов и комментариев на время одного
+// ответа, поэтому поля commentCount, participantCount и lastCommentAt
+// читают их из репозитория один раз на сущность
+type statsLoader struct {
+	mu       sync.Mutex
+	posts    map[string]*statsCall[domain.PostStats]
+	comments map[string]*statsCall[domain.CommentStats]
+}
+
+func newStatsLoader() *statsLoader {
+	return &statsLoader{
+		posts:    make(map[string]*statsCall[domain.PostStats]),
+		comments: make(map[string]*statsCall[domain.CommentStats]),
+	}
+}
+
+func loadStats[T any](mu *sync.Mutex, calls map[string]*statsCall[T], id string, load func() (T, error)) (T, error) {
+	mu.Lock()
+	call, ok := calls[id]
+	if !ok {
+		call = &statsCall[T]{}
+		calls[id] = call
+	}
+	mu.Unlock()
+
+	call.once.Do(func() {
+		call.stats, call.err = load()
+	})
+	return call.stats, call.err
+}
+
+// StatsLoader кладет statsLoader в контекст каждого ответа. Для подписок
+// это каждое событие, поэтому счетчики в них не устаревают. Мутации
+// загрузчик не получают: счетчики меняются между их полями
+type StatsLoader struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = StatsLoader{}
+
+func (StatsLoader) ExtensionName() string {
+	return "StatsLoader"
+}
+
+func (StatsLoader) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (StatsLoader) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	if op := graphql.GetOperationContext(ctx).Operation; op == nil || op.Operation == ast.Mutation {
+		return next(ctx)
+	}
+	return next(context.WithValue(ctx, statsLoaderKey{}, newStatsLoader()))
+}
+
+func (r *Resolver) postStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	loader, ok := ctx.Value(statsLoaderKey{}).(*statsLoader)
+	if !ok {
+		return r.CommentRepo.PostStats(ctx, postID)
+	}
+	return loadStats(&loader.mu, loader.posts, postID, func() (domain.PostStats, error) {
+		return r.CommentRepo.PostStats(ctx, postID)
+	})
+}
+
+func (r *Resolver) commentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	loader, ok := ctx.Value(statsLoaderKey{}).(*statsLoader)
+	if !ok {
+		return r.CommentRepo.CommentStats(ctx, commentID)
+	}
+	return loadStats(&loader.mu, loader.comments, commentID, func() (domain.CommentStats, error) {
+		return r.CommentRepo.CommentStats(ctx, commentID)
+	})
+}
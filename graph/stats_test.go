@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/generated"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func TestCommentCounters(t *testing.T) {
+	ctx := context.Background()
+	commentRepo := inmemory.NewInMemoryCommentRepository()
+	resolver := &Resolver{CommentRepo: commentRepo}
+	post := testPost()
+
+	create := func(author string, parentID *string, pending bool) *domain.Comment {
+		comment, err := domain.NewComment(post.ID, author, parentID, "text")
+		require.NoError(t, err)
+		comment.Pending = pending
+		require.NoError(t, commentRepo.Create(ctx, comment))
+		return comment
+	}
+
+	root := create("a", nil, false)
+	reply := create("b", &root.ID, false)
+	create("a", &reply.ID, false)
+	pending := create("c", &reply.ID, true)
+
+	postCount := func() (int, int) {
+		comments, err := resolver.Post().CommentCount(ctx, post)
+		require.NoError(t, err)
+		participants, err := resolver.Post().ParticipantCount(ctx, post)
+		require.NoError(t, err)
+		return comments, participants
+	}
+
+	t.Run("pending comment is not counted", func(t *testing.T) {
+		comments, participants := postCount()
+		assert.Equal(t, 3, comments)
+		assert.Equal(t, 2, participants)
+
+		replies, err := resolver.Comment().ReplyCount(ctx, root)
+		require.NoError(t, err)
+		assert.Equal(t, 1, replies)
+		descendants, err := resolver.Comment().DescendantCount(ctx, root)
+		require.NoError(t, err)
+		assert.Equal(t, 2, descendants)
+	})
+
+	t.Run("approve counts comment once", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, _, err := commentRepo.Approve(ctx, pending.ID)
+			require.NoError(t, err)
+		}
+
+		comments, participants := postCount()
+		assert.Equal(t, 4, comments)
+		assert.Equal(t, 3, participants)
+
+		replies, err := resolver.Comment().ReplyCount(ctx, reply)
+		require.NoError(t, err)
+		assert.Equal(t, 2, replies)
+		descendants, err := resolver.Comment().DescendantCount(ctx, root)
+		require.NoError(t, err)
+		assert.Equal(t, 3, descendants)
+
+		last, err := resolver.Post().LastCommentAt(ctx, post)
+		require.NoError(t, err)
+		assert.Equal(t, pending.CreatedAt, *last)
+	})
+
+	t.Run("post without comments", func(t *testing.T) {
+		last, err := resolver.Post().LastCommentAt(ctx, &domain.Post{ID: "empty"})
+		require.NoError(t, err)
+		assert.Nil(t, last)
+	})
+}
+
+// countingStats считает обращения к счетчикам
+type countingStats struct {
+	repository.CommentRepository
+	posts, comments atomic.Int32
+}
+
+func (r *countingStats) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	r.posts.Add(1)
+	return r.CommentRepository.PostStats(ctx, postID)
+}
+
+func (r *countingStats) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	r.comments.Add(1)
+	return r.CommentRepository.CommentStats(ctx, commentID)
+}
+
+func TestStatsLoader(t *testing.T) {
+	ctx := context.Background()
+	postRepo, commentRepo := inmemory.NewInMemoryRepositories()
+	post := testPost()
+	require.NoError(t, postRepo.Create(ctx, post))
+	for i := 0; i < 3; i++ {
+		comment, err := domain.NewComment(post.ID, "a", nil, "text")
+		require.NoError(t, err)
+		require.NoError(t, commentRepo.Create(ctx, comment))
+	}
+
+	counting := &countingStats{CommentRepository: commentRepo}
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers: NewResolver(postRepo, counting, nil),
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.Use(StatsLoader{})
+	c := client.New(srv)
+
+	var resp struct {
+		Post struct {
+			CommentCount     int
+			ParticipantCount int
+			LastCommentAt    *string
+			Comments         []struct {
+				ReplyCount      int
+				DescendantCount int
+			}
+		}
+	}
+	err := c.Post(`query($id: ID!) { post(id: $id) {
+		commentCount participantCount lastCommentAt
+		comments { replyCount descendantCount }
+	} }`, &resp, client.Var("id", post.ID))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, resp.Post.CommentCount)
+	assert.Equal(t, 1, resp.Post.ParticipantCount)
+	require.Len(t, resp.Post.Comments, 3)
+	assert.Equal(t, int32(1), counting.posts.Load(), "post stats must be loaded once")
+	assert.Equal(t, int32(3), counting.comments.Load(), "comment stats must be loaded once per comment")
+
+	t.Run("each response loads stats again", func(t *testing.T) {
+		err := c.Post(`query($id: ID!) { post(id: $id) { commentCount } }`, &resp, client.Var("id", post.ID))
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), counting.posts.Load())
+	})
+}
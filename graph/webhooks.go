@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+// ownWebhook возвращает вебхук, если его зарегистрировал автор запроса
+func (r *Resolver) ownWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	owner, err := viewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := r.WebhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook.Owner != owner {
+		return nil, domain.ErrForbidden
+	}
+	return hook, nil
+}
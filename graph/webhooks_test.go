@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/graph/model"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/middleware"
+	redisMocks "github.com/tmozzze/SasPosts/internal/redis/mocks"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+	"github.com/tmozzze/SasPosts/internal/repository/mocks"
+)
+
+func TestWebhooks(t *testing.T) {
+	ctx := middleware.WithViewer(context.Background(), "a")
+	otherCtx := middleware.WithViewer(context.Background(), "b")
+	mockPostRepo := mocks.NewPostRepository(t)
+	mockPublisher := redisMocks.NewPubSub(t)
+	resolver := &Resolver{PostRepo: mockPostRepo, PubSub: mockPublisher}
+	WithWebhooks(inmemory.NewInMemoryWebhookRepository())(resolver)
+
+	hook, err := resolver.Mutation().CreateWebhook(ctx, model.NewWebhookInput{
+		URL:    "https://example.com/hook",
+		Events: []domain.WebhookEvent{domain.EventPostCreated},
+		Secret: "0123456789abcdef",
+	})
+	require.NoError(t, err)
+
+	t.Run("post creation is queued", func(t *testing.T) {
+		mockPostRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Post")).Return(nil)
+		mockPublisher.On("Publish", mock.Anything, "posts:published", mock.AnythingOfType("*domain.Post")).Return(nil)
+
+		_, err := resolver.Mutation().CreatePost(ctx, model.NewPostInput{Title: "t", Content: "c", Author: "a"})
+		require.NoError(t, err)
+
+		deliveries, err := resolver.Webhook().Deliveries(ctx, hook, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, domain.EventPostCreated, deliveries[0].Event)
+		assert.Equal(t, domain.DeliveryPending, deliveries[0].Status)
+	})
+
+	t.Run("posts of other authors are not queued", func(t *testing.T) {
+		_, err := resolver.Mutation().CreatePost(ctx, model.NewPostInput{Title: "t", Content: "c", Author: "b"})
+		require.NoError(t, err)
+
+		deliveries, err := resolver.Webhook().Deliveries(ctx, hook, nil, nil)
+		require.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+	})
+
+	t.Run("webhooks are visible only to owner", func(t *testing.T) {
+		hooks, err := resolver.Query().Webhooks(ctx)
+		require.NoError(t, err)
+		assert.Len(t, hooks, 1)
+
+		hooks, err = resolver.Query().Webhooks(otherCtx)
+		require.NoError(t, err)
+		assert.Empty(t, hooks)
+	})
+
+	t.Run("error, if not owner", func(t *testing.T) {
+		_, err := resolver.Mutation().DeleteWebhook(otherCtx, hook.ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		deliveries, err := resolver.Webhook().Deliveries(ctx, hook, nil, nil)
+		require.NoError(t, err)
+		_, err = resolver.Mutation().RedeliverWebhook(otherCtx, deliveries[0].ID)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("error, if unauthenticated", func(t *testing.T) {
+		_, err := resolver.Mutation().CreateWebhook(context.Background(), model.NewWebhookInput{
+			URL:    "https://example.com/hook",
+			Events: []domain.WebhookEvent{domain.EventPostCreated},
+			Secret: "0123456789abcdef",
+		})
+		assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	})
+
+	t.Run("error, if webhook is invalid", func(t *testing.T) {
+		_, err := resolver.Mutation().CreateWebhook(ctx, model.NewWebhookInput{URL: "ftp://x", Secret: "short"})
+
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+	})
+
+	t.Run("owner can delete webhook", func(t *testing.T) {
+		deleted, err := resolver.Mutation().DeleteWebhook(ctx, hook.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+	})
+}
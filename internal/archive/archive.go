@@ -0,0 +1,210 @@
+// Package archive переносит посты и ветки комментариев между окружениями
+// в формате JSON Lines: строка поста, затем его комментарии, родители раньше ответов
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+// record - одна строка архива, заполнено ровно одно поле
+type record struct {
+	Post    *domain.Post    `json:"post,omitempty"`
+	Comment *domain.Comment `json:"comment,omitempty"`
+}
+
+// Conflict - что делать с постом или комментарием, id которого уже занят
+type Conflict string
+
+const (
+	ConflictSkip Conflict = "skip"
+	// ConflictOverwrite заменяет пост вместе со всей веткой комментариев
+	ConflictOverwrite Conflict = "overwrite"
+)
+
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case ConflictSkip, ConflictOverwrite:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown conflict mode %q", s)
+}
+
+type Stats struct {
+	Posts           int
+	Comments        int
+	SkippedPosts    int
+	SkippedComments int
+}
+
+var statuses = []domain.PostStatus{domain.PostDraft, domain.PostScheduled, domain.PostPublished, domain.PostArchived}
+
+// Export пишет в w все посты во всех статусах и все их комментарии.
+// Голоса по отдельным пользователям не переносятся, только сумма VoteScore
+func Export(ctx context.Context, w io.Writer, posts repository.PostRepository, comments repository.CommentRepository) (Stats, error) {
+	var stats Stats
+	enc := json.NewEncoder(w)
+
+	for _, status := range statuses {
+		list, err := posts.GetAll(ctx, domain.PostFilter{Status: status})
+		if err != nil {
+			return stats, fmt.Errorf("failed list posts %w", err)
+		}
+
+		for _, post := range list {
+			if err := enc.Encode(record{Post: post}); err != nil {
+				return stats, fmt.Errorf("failed write post %w", err)
+			}
+			stats.Posts++
+
+			thread, err := comments.ListByPost(ctx, post.ID)
+			if err != nil {
+				return stats, fmt.Errorf("failed list comments %w", err)
+			}
+			for _, comment := range thread {
+				if err := enc.Encode(record{Comment: comment}); err != nil {
+					return stats, fmt.Errorf("failed write comment %w", err)
+				}
+				stats.Comments++
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// importer держит состояние текущего поста во время импорта
+type importer struct {
+	posts    repository.PostRepository
+	comments repository.CommentRepository
+	conflict Conflict
+
+	stats Stats
+	// skipPost - комментарии текущего поста пропускаются вместе с ним
+	skipPost bool
+	// skipped - пропущенные комментарии, их ответы тоже пропускаются
+	skipped map[string]bool
+	// locked - ветки закрываются после импорта поста, иначе ответы не создать
+	locked []string
+}
+
+// Import читает архив из r. id, время создания, родители, пути и глубина
+// сохраняются, счетчики пересчитываются репозиторием
+func Import(ctx context.Context, r io.Reader, posts repository.PostRepository, comments repository.CommentRepository, conflict Conflict) (Stats, error) {
+	imp := &importer{posts: posts, comments: comments, conflict: conflict, skipped: make(map[string]bool)}
+
+	scanner := bufio.NewScanner(r)
+	// пост может весить больше стандартных 64 КБ
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return imp.stats, fmt.Errorf("failed parse line %d %w", line, err)
+		}
+
+		var err error
+		switch {
+		case rec.Post != nil:
+			err = imp.importPost(ctx, rec.Post)
+		case rec.Comment != nil:
+			err = imp.importComment(ctx, rec.Comment)
+		default:
+			err = errors.New("empty record")
+		}
+		if err != nil {
+			return imp.stats, fmt.Errorf("failed import line %d %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imp.stats, fmt.Errorf("failed read archive %w", err)
+	}
+
+	return imp.stats, imp.lockThreads(ctx)
+}
+
+func (imp *importer) importPost(ctx context.Context, post *domain.Post) error {
+	if err := imp.lockThreads(ctx); err != nil {
+		return err
+	}
+
+	_, err := imp.posts.GetByID(ctx, post.ID)
+	switch {
+	case errors.Is(err, domain.ErrPostNotFound):
+	case err != nil:
+		return err
+	case imp.conflict == ConflictSkip:
+		imp.skipPost = true
+		imp.stats.SkippedPosts++
+		return nil
+	default:
+		if err := imp.comments.DeleteByPost(ctx, post.ID); err != nil {
+			return err
+		}
+		if err := imp.posts.Delete(ctx, post.ID); err != nil {
+			return err
+		}
+	}
+
+	imp.skipPost = false
+	if err := imp.posts.Create(ctx, post); err != nil {
+		return err
+	}
+	imp.stats.Posts++
+	return nil
+}
+
+func (imp *importer) importComment(ctx context.Context, comment *domain.Comment) error {
+	if imp.skipPost || (comment.ParentID != nil && imp.skipped[*comment.ParentID]) {
+		imp.skip(comment)
+		return nil
+	}
+
+	// id занят комментарием другого поста, перезаписывать его нельзя
+	_, err := imp.comments.GetByID(ctx, comment.ID)
+	if err == nil {
+		imp.skip(comment)
+		return nil
+	}
+	if !errors.Is(err, domain.ErrCommentNotFound) {
+		return err
+	}
+
+	if comment.Locked {
+		comment.Locked = false
+		imp.locked = append(imp.locked, comment.ID)
+	}
+	if err := imp.comments.Create(ctx, comment); err != nil {
+		return err
+	}
+	imp.stats.Comments++
+	return nil
+}
+
+func (imp *importer) skip(comment *domain.Comment) {
+	imp.skipped[comment.ID] = true
+	imp.stats.SkippedComments++
+}
+
+func (imp *importer) lockThreads(ctx context.Context) error {
+	for _, id := range imp.locked {
+		if _, err := imp.comments.SetLocked(ctx, id, true); err != nil {
+			return err
+		}
+	}
+	imp.locked = imp.locked[:0]
+	return nil
+}
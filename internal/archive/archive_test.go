@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	srcPosts := inmemory.NewInMemoryPostRepository()
+	srcComments := inmemory.NewInMemoryCommentRepository()
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	post.CreatedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post.Tags = []string{"go"}
+	require.NoError(t, srcPosts.Create(ctx, post))
+
+	draft, err := domain.NewPost("draft", "c", "a", true)
+	require.NoError(t, err)
+	draft.Status, draft.PublishAt = domain.PostDraft, nil
+	require.NoError(t, srcPosts.Create(ctx, draft))
+
+	create := func(parentID *string, pending bool) *domain.Comment {
+		comment, err := domain.NewComment(post.ID, "b", parentID, "text")
+		require.NoError(t, err)
+		comment.Pending = pending
+		require.NoError(t, srcComments.Create(ctx, comment))
+		return comment
+	}
+	root := create(nil, false)
+	reply := create(&root.ID, false)
+	create(&reply.ID, false)
+	create(&root.ID, true)
+	_, err = srcComments.SetLocked(ctx, reply.ID, true)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	stats, err := Export(ctx, &buf, srcPosts, srcComments)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Posts: 2, Comments: 4}, stats)
+	archive := buf.Bytes()
+
+	dstPosts := inmemory.NewInMemoryPostRepository()
+	dstComments := inmemory.NewInMemoryCommentRepository()
+
+	t.Run("import preserves posts and threads", func(t *testing.T) {
+		stats, err := Import(ctx, bytes.NewReader(archive), dstPosts, dstComments, ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{Posts: 2, Comments: 4}, stats)
+
+		imported, err := dstPosts.GetByID(ctx, post.ID)
+		require.NoError(t, err)
+		assert.True(t, post.CreatedAt.Equal(imported.CreatedAt))
+		assert.Equal(t, post.Tags, imported.Tags)
+
+		want, err := srcComments.ListByPost(ctx, post.ID)
+		require.NoError(t, err)
+		got, err := dstComments.ListByPost(ctx, post.ID)
+		require.NoError(t, err)
+		require.Len(t, got, len(want))
+		for i := range want {
+			assert.Equal(t, want[i].ID, got[i].ID)
+			assert.Equal(t, want[i].Path, got[i].Path)
+			assert.Equal(t, want[i].Depth, got[i].Depth)
+			assert.Equal(t, want[i].Locked, got[i].Locked)
+			assert.Equal(t, want[i].Pending, got[i].Pending)
+			assert.True(t, want[i].CreatedAt.Equal(got[i].CreatedAt))
+		}
+
+		postStats, err := dstComments.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, postStats.CommentCount)
+	})
+
+	t.Run("skip conflicting posts", func(t *testing.T) {
+		stats, err := Import(ctx, bytes.NewReader(archive), dstPosts, dstComments, ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{SkippedPosts: 2, SkippedComments: 4}, stats)
+	})
+
+	t.Run("overwrite conflicting posts", func(t *testing.T) {
+		extra, err := domain.NewComment(post.ID, "c", nil, "local")
+		require.NoError(t, err)
+		require.NoError(t, dstComments.Create(ctx, extra))
+
+		stats, err := Import(ctx, bytes.NewReader(archive), dstPosts, dstComments, ConflictOverwrite)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{Posts: 2, Comments: 4}, stats)
+
+		_, err = dstComments.GetByID(ctx, extra.ID)
+		assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+		postStats, err := dstComments.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, postStats.CommentCount)
+	})
+}
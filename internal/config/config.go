@@ -1,10 +1,14 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tmozzze/SasPosts/internal/domain"
 )
 
 type Config struct {
@@ -12,6 +16,53 @@ type Config struct {
 	DBType   string
 	PGURL    string
 	RedisURL string
+
+	// RateLimits - лимиты по мутациям в формате "createComment=20/1m,createPost=5/1m"
+	RateLimits string
+	TrustProxy bool
+
+	// IdempotencyTTL - сколько помнить ключи идемпотентности createPost и createComment
+	IdempotencyTTL time.Duration
+
+	MaxQueryDepth      int
+	MaxQueryComplexity int
+	MaxPageLimit       int
+
+	APQEnabled   bool
+	APQCacheSize int
+	APQTTL       time.Duration
+	// PersistedQueriesManifest - путь к манифесту доверенных операций
+	PersistedQueriesManifest string
+	PersistedQueriesStrict   bool
+
+	CacheEnabled bool
+	CacheTTL     time.Duration
+	// CacheDebug открывает /debug/cache со статистикой кеша
+	CacheDebug bool
+
+	MarkdownCacheSize int
+
+	WebhookWorkerEnabled bool
+	WebhookPollInterval  time.Duration
+	WebhookBatchSize     int
+	WebhookMaxAttempts   int
+	WebhookBackoffBase   time.Duration
+	WebhookBackoffMax    time.Duration
+	WebhookTimeout       time.Duration
+
+	PostSchedulerInterval time.Duration
+
+	MaxPinsPerPost int
+
+	// InMemoryDataDir - каталог журналов для DB_TYPE=inmemory,
+	// пустой - данные живут только в памяти процесса
+	InMemoryDataDir          string
+	InMemoryFsync            string
+	InMemorySyncInterval     time.Duration
+	InMemorySnapshotInterval time.Duration
+
+	// SQLitePath - файл базы для DB_TYPE=sqlite
+	SQLitePath string
 }
 
 func Load() (*Config, error) {
@@ -26,6 +77,51 @@ func Load() (*Config, error) {
 		DBType:   getEnv("DB_TYPE", "inmemory"),
 		PGURL:    getEnv("PG_URL", ""),
 		RedisURL: getEnv("REDIS_URL", "redis://localhost:6379"),
+
+		RateLimits: getEnv("RATE_LIMITS", "createPost=5/1m,createComment=20/1m,toggleComments=30/1m,votePost=60/1m"),
+		TrustProxy: getEnvBool("TRUST_PROXY", false),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		MaxQueryDepth:      getEnvInt("MAX_QUERY_DEPTH", 12),
+		MaxQueryComplexity: getEnvInt("MAX_QUERY_COMPLEXITY", 5000),
+		MaxPageLimit:       getEnvInt("MAX_PAGE_LIMIT", 100),
+
+		APQEnabled:               getEnvBool("APQ_ENABLED", true),
+		APQCacheSize:             getEnvInt("APQ_CACHE_SIZE", 1000),
+		APQTTL:                   getEnvDuration("APQ_TTL", 24*time.Hour),
+		PersistedQueriesManifest: getEnv("PERSISTED_QUERIES_MANIFEST", ""),
+		PersistedQueriesStrict:   getEnvBool("PERSISTED_QUERIES_STRICT", false),
+
+		CacheEnabled: getEnvBool("CACHE_ENABLED", true),
+		CacheTTL:     getEnvDuration("CACHE_TTL", time.Minute),
+		CacheDebug:   getEnvBool("CACHE_DEBUG", false),
+
+		MarkdownCacheSize: getEnvInt("MARKDOWN_CACHE_SIZE", 5000),
+
+		WebhookWorkerEnabled: getEnvBool("WEBHOOK_WORKER_ENABLED", true),
+		WebhookPollInterval:  getEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		WebhookBatchSize:     getEnvInt("WEBHOOK_BATCH_SIZE", 20),
+		WebhookMaxAttempts:   getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase:   getEnvDuration("WEBHOOK_BACKOFF_BASE", 10*time.Second),
+		WebhookBackoffMax:    getEnvDuration("WEBHOOK_BACKOFF_MAX", time.Hour),
+		WebhookTimeout:       getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		PostSchedulerInterval: getEnvDuration("POST_SCHEDULER_INTERVAL", 10*time.Second),
+
+		MaxPinsPerPost: getEnvInt("MAX_PINS_PER_POST", domain.DefaultMaxPins),
+
+		InMemoryDataDir:          getEnv("INMEMORY_DATA_DIR", ""),
+		InMemoryFsync:            getEnv("INMEMORY_FSYNC", "interval"),
+		InMemorySyncInterval:     getEnvDuration("INMEMORY_SYNC_INTERVAL", time.Second),
+		InMemorySnapshotInterval: getEnvDuration("INMEMORY_SNAPSHOT_INTERVAL", 5*time.Minute),
+
+		SQLitePath: getEnv("SQLITE_PATH", "sasposts.db"),
+	}
+
+	// time.NewTicker паникует на неположительном интервале
+	if cfg.InMemoryDataDir != "" && (cfg.InMemorySyncInterval <= 0 || cfg.InMemorySnapshotInterval <= 0) {
+		return nil, errors.New("INMEMORY_SYNC_INTERVAL and INMEMORY_SNAPSHOT_INTERVAL must be positive")
 	}
 
 	return cfg, nil
@@ -39,3 +135,45 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("invalid value %q for %s, default %v will be used", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("invalid value %q for %s, default %d will be used", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("invalid value %q for %s, default %s will be used", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
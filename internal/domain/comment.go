@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -17,6 +18,14 @@ type Comment struct {
 	CreatedAt time.Time `json:"createdAt"`
 	Path      string    `json:"path"`
 	Depth     int       `json:"depth"`
+	// Pending - комментарий ждет одобрения и не виден в ветке
+	Pending  bool      `json:"pending"`
+	Mentions []Mention `json:"mentions,omitempty"`
+	// Locked - ветка закрыта на этом комментарии. Блокировка
+	// наследуется всеми ответами ниже по пути
+	Locked bool `json:"locked"`
+	// PinnedAt - когда автор поста закрепил комментарий, nil если не закреплен
+	PinnedAt *time.Time `json:"pinnedAt,omitempty"`
 }
 
 const MaxCommentLength = 2000
@@ -25,8 +34,15 @@ func NewComment(postID, author string, parentID *string, content string) (*Comme
 	if utf8.RuneCountInString(content) > MaxCommentLength {
 		return nil, ErrCommentTooLong
 	}
-	if postID == "" || author == "" {
-		return nil, errors.New("postID and author cannot be empty")
+
+	var v validator
+	v.required("postID", postID)
+	if v.required("author", author) {
+		v.maxLength("author", author, MaxAuthorLength)
+	}
+	v.required("content", content)
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
 	comment := &Comment{
@@ -42,3 +58,15 @@ func NewComment(postID, author string, parentID *string, content string) (*Comme
 }
 
 var ErrCommentTooLong = errors.New("comment is too long")
+
+// MoveUnder возвращает путь и глубину комментария после переноса под parent
+// или в корень поста, если parent nil. Ветку нельзя перенести под саму себя
+func (c *Comment) MoveUnder(parent *Comment) (string, int, error) {
+	if parent == nil {
+		return c.ID, 0, nil
+	}
+	if parent.ID == c.ID || strings.HasPrefix(parent.Path, c.Path+".") {
+		return "", 0, ErrCommentCycle
+	}
+	return parent.Path + "." + c.ID, parent.Depth + 1, nil
+}
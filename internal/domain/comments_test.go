@@ -33,4 +33,33 @@ func TestNewComment(t *testing.T) {
 		_, err = NewComment("post1", "", nil, "content")
 		assert.Error(t, err)
 	})
+
+	t.Run("error, if content is blank", func(t *testing.T) {
+		_, err := NewComment("post1", "author1", nil, " \n ")
+
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []FieldError{{Field: "content", Message: "is required"}}, validationErr.Fields)
+	})
+}
+
+func TestComment_MoveUnder(t *testing.T) {
+	root := &Comment{ID: "r", Path: "r"}
+	child := &Comment{ID: "c", Path: "r.c", Depth: 1}
+	other := &Comment{ID: "o", Path: "o"}
+
+	path, depth, err := child.MoveUnder(other)
+	require.NoError(t, err)
+	assert.Equal(t, "o.c", path)
+	assert.Equal(t, 1, depth)
+
+	path, depth, err = child.MoveUnder(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c", path)
+	assert.Equal(t, 0, depth)
+
+	_, _, err = root.MoveUnder(child)
+	assert.ErrorIs(t, err, ErrCommentCycle)
+	_, _, err = root.MoveUnder(root)
+	assert.ErrorIs(t, err, ErrCommentCycle)
 }
@@ -5,6 +5,12 @@ import "errors"
 var (
 	ErrCommentNotFound       = errors.New("comment not found")
 	ErrParentCommentNotFound = errors.New("parent comment not found")
+	ErrCommentCycle          = errors.New("comment cannot be moved under itself or its replies")
 	ErrPostNotFound          = errors.New("post not found")
 	ErrCommentsOff           = errors.New("comments off for this post")
+	ErrThreadLocked          = errors.New("thread is locked")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrForbidden             = errors.New("only the author can do this")
+	ErrUnauthenticated       = errors.New("author is not specified")
 )
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const MaxMentionsPerComment = 5
+
+// Mention - упоминание автора в тексте. Start и End - смещения в рунах,
+// End не включается, диапазон покрывает "@" вместе с именем
+type Mention struct {
+	Author string `json:"author"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// перед "@" не должно быть буквы, цифры или "@", чтобы не ловить email
+var mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_][\p{L}\p{N}_.-]*)`)
+
+type TooManyMentionsError struct {
+	MaxMentions int
+}
+
+func (e *TooManyMentionsError) Error() string {
+	return fmt.Sprintf("too many mentions, max %d per comment", e.MaxMentions)
+}
+
+// ParseMentions находит упоминания в тексте. Разных авторов может быть
+// не больше MaxMentionsPerComment
+func ParseMentions(content string) ([]Mention, error) {
+	var mentions []Mention
+	authors := make(map[string]struct{})
+
+	for _, m := range mentionRe.FindAllStringSubmatchIndex(content, -1) {
+		// точка или дефис в конце - это пунктуация, а не часть имени
+		name := strings.TrimRight(content[m[2]:m[3]], ".-")
+		if utf8.RuneCountInString(name) > MaxAuthorLength {
+			continue
+		}
+
+		start := utf8.RuneCountInString(content[:m[2]-1])
+		mentions = append(mentions, Mention{
+			Author: name,
+			Start:  start,
+			End:    start + 1 + utf8.RuneCountInString(name),
+		})
+		authors[name] = struct{}{}
+	}
+
+	if len(authors) > MaxMentionsPerComment {
+		return nil, &TooManyMentionsError{MaxMentions: MaxMentionsPerComment}
+	}
+
+	return mentions, nil
+}
+
+// MentionedAuthors возвращает авторов без повторов в порядке упоминания
+func MentionedAuthors(mentions []Mention) []string {
+	seen := make(map[string]struct{}, len(mentions))
+	authors := make([]string, 0, len(mentions))
+
+	for _, m := range mentions {
+		if _, ok := seen[m.Author]; ok {
+			continue
+		}
+		seen[m.Author] = struct{}{}
+		authors = append(authors, m.Author)
+	}
+
+	return authors
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	t.Run("finds mentions with rune offsets", func(t *testing.T) {
+		mentions, err := ParseMentions("привет @alice и @bob.")
+
+		require.NoError(t, err)
+		assert.Equal(t, []Mention{
+			{Author: "alice", Start: 7, End: 13},
+			{Author: "bob", Start: 16, End: 20},
+		}, mentions)
+	})
+
+	t.Run("ignores emails", func(t *testing.T) {
+		mentions, err := ParseMentions("write to alice@example.com")
+
+		require.NoError(t, err)
+		assert.Empty(t, mentions)
+	})
+
+	t.Run("repeated author counts once", func(t *testing.T) {
+		mentions, err := ParseMentions("@a @a @a @a @a @a @b")
+
+		require.NoError(t, err)
+		assert.Len(t, mentions, 7)
+		assert.Equal(t, []string{"a", "b"}, MentionedAuthors(mentions))
+	})
+
+	t.Run("error, if too many authors", func(t *testing.T) {
+		_, err := ParseMentions("@a @b @c @d @e @f")
+
+		var tooManyErr *TooManyMentionsError
+		require.ErrorAs(t, err, &tooManyErr)
+		assert.Equal(t, MaxMentionsPerComment, tooManyErr.MaxMentions)
+	})
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+type NotificationKind string
+
+const (
+	NotificationMention NotificationKind = "MENTION"
+	// NotificationReply - ответ на комментарий получателя
+	NotificationReply NotificationKind = "REPLY"
+	// NotificationPostComment - комментарий верхнего уровня к посту получателя
+	NotificationPostComment NotificationKind = "POST_COMMENT"
+)
+
+type Notification struct {
+	ID        string           `json:"id"`
+	Recipient string           `json:"recipient"`
+	Kind      NotificationKind `json:"kind"`
+	// Actor - автор комментария, вызвавшего уведомление
+	Actor     string     `json:"actor"`
+	PostID    string     `json:"postId"`
+	CommentID string     `json:"commentId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+}
+
+func NewNotification(kind NotificationKind, comment *Comment, recipient string) *Notification {
+	return &Notification{
+		ID:        utils.GenerateID(),
+		Recipient: recipient,
+		Kind:      kind,
+		Actor:     comment.Author,
+		PostID:    comment.PostID,
+		CommentID: comment.ID,
+		CreatedAt: time.Now(),
+	}
+}
+
+// NotificationCursor - позиция в ленте уведомлений, отсортированной
+// по убыванию CreatedAt и ID
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (n *Notification) Cursor() NotificationCursor {
+	return NotificationCursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+// Before сообщает, идет ли уведомление в ленте после курсора
+func (c NotificationCursor) Before(n *Notification) bool {
+	if n.CreatedAt.Equal(c.CreatedAt) {
+		return n.ID < c.ID
+	}
+	return n.CreatedAt.Before(c.CreatedAt)
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool
+	After      *NotificationCursor
+	Limit      int
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "fmt"
+
+// DefaultMaxPins - сколько комментариев можно закрепить в посте,
+// если лимит не задан в конфиге
+const DefaultMaxPins = 3
+
+type TooManyPinsError struct {
+	MaxPins int
+}
+
+func (e *TooManyPinsError) Error() string {
+	return fmt.Sprintf("too many pinned comments, max %d per post", e.MaxPins)
+}
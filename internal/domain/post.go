@@ -7,21 +7,56 @@ import (
 )
 
 type Post struct {
-	ID            string    `json:"id"`
-	Title         string    `json:"title"`
-	Content       string    `json:"content"`
-	Author        string    `json:"author"`
-	CreatedAt     time.Time `json:"createdAt"`
-	AllowComments bool      `json:"allowComments"`
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	Author        string     `json:"author"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	AllowComments bool       `json:"allowComments"`
+	Status        PostStatus `json:"status"`
+	// PublishAt - время плановой публикации, а после нее - фактической
+	PublishAt *time.Time `json:"publishAt,omitempty"`
+	Tags      []string   `json:"tags"`
+	// VoteScore - сумма голосов за пост
+	VoteScore int `json:"voteScore"`
+
+	Settings ThreadSettings `json:"settings"`
 }
 
-func NewPost(title, content, author string, allowComments bool) *Post {
-	return &Post{
+func NewPost(title, content, author string, allowComments bool) (*Post, error) {
+	now := time.Now()
+	post := &Post{
 		ID:            utils.GenerateID(),
 		Title:         title,
 		Content:       content,
 		Author:        author,
-		CreatedAt:     time.Now(),
+		CreatedAt:     now,
 		AllowComments: allowComments,
+		Status:        PostPublished,
+		Tags:          []string{},
+		PublishAt:     &now,
+		Settings:      DefaultThreadSettings(),
+	}
+
+	if err := post.Validate(); err != nil {
+		return nil, err
 	}
+
+	return post, nil
+}
+
+func (p *Post) Validate() error {
+	var v validator
+
+	if v.required("title", p.Title) {
+		v.maxLength("title", p.Title, MaxTitleLength)
+	}
+	if v.required("content", p.Content) {
+		v.maxLength("content", p.Content, MaxPostContentLength)
+	}
+	if v.required("author", p.Author) {
+		v.maxLength("author", p.Author, MaxAuthorLength)
+	}
+
+	return v.err()
 }
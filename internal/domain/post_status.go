@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+type PostStatus string
+
+const (
+	PostDraft     PostStatus = "DRAFT"
+	PostScheduled PostStatus = "SCHEDULED"
+	PostPublished PostStatus = "PUBLISHED"
+	// PostArchived - пост убран из ленты, но доступен по ссылке
+	PostArchived PostStatus = "ARCHIVED"
+)
+
+// allowedTransitions - опубликованный пост нельзя вернуть в черновик
+var allowedTransitions = map[PostStatus][]PostStatus{
+	PostDraft:     {PostDraft, PostScheduled, PostPublished, PostArchived},
+	PostScheduled: {PostDraft, PostScheduled, PostPublished, PostArchived},
+	PostPublished: {PostPublished, PostArchived},
+	PostArchived:  {PostArchived, PostPublished},
+}
+
+// SetStatus переводит пост в новый статус. Для SCHEDULED нужно время
+// публикации в будущем. Возвращает true, если пост только что опубликован
+func (p *Post) SetStatus(status PostStatus, publishAt *time.Time, now time.Time) (bool, error) {
+	var v validator
+
+	allowed := false
+	for _, s := range allowedTransitions[p.Status] {
+		if s == status {
+			allowed = true
+		}
+	}
+	if !allowed {
+		v.add("status", "cannot change status from "+string(p.Status)+" to "+string(status))
+	}
+	if status == PostScheduled && (publishAt == nil || !publishAt.After(now)) {
+		v.add("publishAt", "must be in the future for a scheduled post")
+	}
+	if status != PostScheduled && publishAt != nil {
+		v.add("publishAt", "can be set only for a scheduled post")
+	}
+	if err := v.err(); err != nil {
+		return false, err
+	}
+
+	published := status == PostPublished && p.Status != PostPublished
+	p.Status = status
+
+	switch {
+	case status == PostScheduled:
+		p.PublishAt = publishAt
+	case published:
+		p.PublishAt = &now
+	case status == PostDraft:
+		p.PublishAt = nil
+	}
+
+	return published, nil
+}
+
+// VisibleTo сообщает, может ли viewer видеть пост. Черновики и
+// запланированные посты видит только автор
+func (p *Post) VisibleTo(viewer string) bool {
+	if p.Status == PostPublished || p.Status == PostArchived {
+		return true
+	}
+	return viewer != "" && viewer == p.Author
+}
+
+// PostFilter - условия выборки постов. Пустое поле не ограничивает выборку
+type PostFilter struct {
+	Status PostStatus
+	Author string
+	// Tags - нормализованные теги, TagMatch - как их сочетать
+	Tags     []string
+	TagMatch TagMatch
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPost_SetStatus(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+
+	t.Run("schedule and publish draft", func(t *testing.T) {
+		post := &Post{Status: PostDraft}
+
+		published, err := post.SetStatus(PostScheduled, &future, now)
+		require.NoError(t, err)
+		assert.False(t, published)
+		assert.Equal(t, &future, post.PublishAt)
+
+		published, err = post.SetStatus(PostPublished, nil, now)
+		require.NoError(t, err)
+		assert.True(t, published)
+		assert.Equal(t, now, *post.PublishAt)
+	})
+
+	t.Run("error, if scheduled without future time", func(t *testing.T) {
+		post := &Post{Status: PostDraft}
+		past := now.Add(-time.Hour)
+
+		_, err := post.SetStatus(PostScheduled, &past, now)
+		assert.ErrorIs(t, err, ErrValidation)
+		_, err = post.SetStatus(PostScheduled, nil, now)
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Equal(t, PostDraft, post.Status)
+	})
+
+	t.Run("error, if published post goes back to draft", func(t *testing.T) {
+		post := &Post{Status: PostPublished}
+
+		_, err := post.SetStatus(PostDraft, nil, now)
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestPost_VisibleTo(t *testing.T) {
+	draft := &Post{Author: "a", Status: PostDraft}
+	assert.True(t, draft.VisibleTo("a"))
+	assert.False(t, draft.VisibleTo("b"))
+	assert.False(t, draft.VisibleTo(""))
+
+	archived := &Post{Author: "a", Status: PostArchived}
+	assert.True(t, archived.VisibleTo(""))
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPost(t *testing.T) {
+	t.Run("valid post", func(t *testing.T) {
+		post, err := NewPost("title", "content", "author", true)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, post.ID)
+		assert.Equal(t, DefaultThreadSettings(), post.Settings)
+	})
+
+	t.Run("error, if fields are invalid", func(t *testing.T) {
+		_, err := NewPost(strings.Repeat("t", MaxTitleLength+1), "", "author", true)
+
+		assert.ErrorIs(t, err, ErrValidation)
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []FieldError{
+			{Field: "title", Message: "must be at most 200 characters"},
+			{Field: "content", Message: "is required"},
+		}, validationErr.Fields)
+	})
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+type ThreadSettings struct {
+	MaxReplyDepth    int  `json:"maxReplyDepth"` // 0 - без ограничений
+	MaxCommentLength int  `json:"maxCommentLength"`
+	SlowModeSeconds  int  `json:"slowModeSeconds"` // 0 - медленный режим выключен
+	RequireApproval  bool `json:"requireApproval"`
+}
+
+func DefaultThreadSettings() ThreadSettings {
+	return ThreadSettings{MaxCommentLength: MaxCommentLength}
+}
+
+func (s ThreadSettings) Validate() error {
+	var v validator
+
+	if s.MaxReplyDepth < 0 {
+		v.add("maxReplyDepth", "must not be negative")
+	}
+	if s.MaxCommentLength < 1 || s.MaxCommentLength > MaxCommentLength {
+		v.add("maxCommentLength", fmt.Sprintf("must be between 1 and %d", MaxCommentLength))
+	}
+	if s.SlowModeSeconds < 0 {
+		v.add("slowModeSeconds", "must not be negative")
+	}
+
+	return v.err()
+}
+
+// CheckComment проверяет комментарий глубины depth на соответствие настройкам
+// ветки. lastCommentAt - время предыдущего комментария автора в посте
+func (s ThreadSettings) CheckComment(content string, depth int, lastCommentAt, now time.Time) error {
+	maxLength := s.MaxCommentLength
+	if maxLength <= 0 || maxLength > MaxCommentLength {
+		maxLength = MaxCommentLength
+	}
+	if utf8.RuneCountInString(content) > maxLength {
+		return &CommentTooLongError{MaxLength: maxLength}
+	}
+
+	if s.MaxReplyDepth > 0 && depth > s.MaxReplyDepth {
+		return &ReplyTooDeepError{MaxDepth: s.MaxReplyDepth}
+	}
+
+	if s.SlowModeSeconds > 0 && !lastCommentAt.IsZero() {
+		next := lastCommentAt.Add(time.Duration(s.SlowModeSeconds) * time.Second)
+		if now.Before(next) {
+			return &SlowModeError{RetryAfter: next.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+type CommentTooLongError struct {
+	MaxLength int
+}
+
+func (e *CommentTooLongError) Error() string {
+	return fmt.Sprintf("comment is too long, max length is %d", e.MaxLength)
+}
+
+func (e *CommentTooLongError) Is(target error) bool {
+	return target == ErrCommentTooLong
+}
+
+type ReplyTooDeepError struct {
+	MaxDepth int
+}
+
+func (e *ReplyTooDeepError) Error() string {
+	return fmt.Sprintf("reply is nested too deep, max depth is %d", e.MaxDepth)
+}
+
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode is on, next comment allowed in %s", e.RetryAfter.Round(time.Second))
+}
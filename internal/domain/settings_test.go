@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadSettings_CheckComment(t *testing.T) {
+	now := time.Now()
+
+	t.Run("defaults allow any comment", func(t *testing.T) {
+		settings := DefaultThreadSettings()
+		assert.NoError(t, settings.CheckComment("hello", 42, now, now))
+	})
+
+	t.Run("error, if comment is longer than post limit", func(t *testing.T) {
+		settings := ThreadSettings{MaxCommentLength: 10}
+		err := settings.CheckComment(strings.Repeat("a", 11), 0, time.Time{}, now)
+
+		var tooLongErr *CommentTooLongError
+		require.ErrorAs(t, err, &tooLongErr)
+		assert.Equal(t, 10, tooLongErr.MaxLength)
+		assert.ErrorIs(t, err, ErrCommentTooLong)
+	})
+
+	t.Run("error, if reply is too deep", func(t *testing.T) {
+		settings := ThreadSettings{MaxCommentLength: MaxCommentLength, MaxReplyDepth: 2}
+		assert.NoError(t, settings.CheckComment("ok", 2, time.Time{}, now))
+
+		var tooDeepErr *ReplyTooDeepError
+		assert.ErrorAs(t, settings.CheckComment("too deep", 3, time.Time{}, now), &tooDeepErr)
+	})
+
+	t.Run("error, if author comments during slow mode", func(t *testing.T) {
+		settings := ThreadSettings{MaxCommentLength: MaxCommentLength, SlowModeSeconds: 30}
+
+		var slowModeErr *SlowModeError
+		require.ErrorAs(t, settings.CheckComment("fast", 0, now.Add(-10*time.Second), now), &slowModeErr)
+		assert.Equal(t, 20*time.Second, slowModeErr.RetryAfter)
+
+		assert.NoError(t, settings.CheckComment("later", 0, now.Add(-31*time.Second), now))
+	})
+}
+
+func TestThreadSettings_Validate(t *testing.T) {
+	assert.NoError(t, DefaultThreadSettings().Validate())
+
+	err := ThreadSettings{MaxCommentLength: MaxCommentLength + 1, SlowModeSeconds: -1}.Validate()
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "maxCommentLength", validationErr.Fields[0].Field)
+	assert.Equal(t, "slowModeSeconds", validationErr.Fields[1].Field)
+}
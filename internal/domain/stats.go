@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// PostStats - денормализованные счетчики видимых комментариев поста.
+// Обновляются при появлении комментария, поэтому читаются за O(1)
+type PostStats struct {
+	CommentCount     int        `json:"commentCount"`
+	ParticipantCount int        `json:"participantCount"`
+	LastCommentAt    *time.Time `json:"lastCommentAt,omitempty"`
+}
+
+// CommentStats - счетчики видимых ответов: прямых и во всей ветке
+type CommentStats struct {
+	ReplyCount      int `json:"replyCount"`
+	DescendantCount int `json:"descendantCount"`
+}
+
+// AncestorIDs возвращает id всех предков комментария от корня ветки
+func (c *Comment) AncestorIDs() []string {
+	ids := strings.Split(c.Path, ".")
+	return ids[:len(ids)-1]
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTagLength   = 32
+	MaxTagsPerPost = 10
+)
+
+// Tag - тег с числом опубликованных постов
+type Tag struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"postCount"`
+}
+
+type TagMatch string
+
+const (
+	// TagMatchAny - пост подходит, если у него есть хотя бы один из тегов
+	TagMatchAny TagMatch = "ANY"
+	// TagMatchAll - пост подходит, если у него есть все теги
+	TagMatchAll TagMatch = "ALL"
+)
+
+// NormalizeTags приводит теги к нижнему регистру, убирает повторы
+// и сортирует. Допустимы буквы, цифры, '-' и '_'
+func NormalizeTags(tags []string) ([]string, error) {
+	var v validator
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimSpace(tag))
+		if !validTagName(name) {
+			v.add("tags", fmt.Sprintf("invalid tag %q", tag))
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	if len(normalized) > MaxTagsPerPost {
+		v.add("tags", fmt.Sprintf("must be at most %d tags", MaxTagsPerPost))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func validTagName(name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > MaxTagLength {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// HasTags проверяет теги поста по правилу match
+func (p *Post) HasTags(tags []string, match TagMatch) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	own := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		own[tag] = true
+	}
+
+	found := 0
+	for _, tag := range tags {
+		if own[tag] {
+			found++
+		}
+	}
+	if match == TagMatchAll {
+		return found == len(tags)
+	}
+	return found > 0
+}
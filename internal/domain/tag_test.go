@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	t.Run("normalize and deduplicate", func(t *testing.T) {
+		tags, err := NormalizeTags([]string{" Go ", "graphql", "go", "новости"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"go", "graphql", "новости"}, tags)
+	})
+
+	t.Run("error, if tag is invalid", func(t *testing.T) {
+		_, err := NormalizeTags([]string{"", "c++", strings.Repeat("a", MaxTagLength+1)})
+
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+	})
+
+	t.Run("error, if too many tags", func(t *testing.T) {
+		tags := make([]string, MaxTagsPerPost+1)
+		for i := range tags {
+			tags[i] = strings.Repeat("t", i+1)
+		}
+
+		_, err := NormalizeTags(tags)
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestPost_HasTags(t *testing.T) {
+	post := &Post{Tags: []string{"go", "graphql"}}
+
+	assert.True(t, post.HasTags(nil, TagMatchAll))
+	assert.True(t, post.HasTags([]string{"go", "rust"}, TagMatchAny))
+	assert.False(t, post.HasTags([]string{"go", "rust"}, TagMatchAll))
+	assert.True(t, post.HasTags([]string{"go", "graphql"}, TagMatchAll))
+}
@@ -0,0 +1,10 @@
+package domain
+
+// ValidateVote проверяет голос за пост: 1 - за, -1 - против, 0 - отозвать голос
+func ValidateVote(value int) error {
+	var v validator
+	if value < -1 || value > 1 {
+		v.add("value", "must be -1, 0 or 1")
+	}
+	return v.err()
+}
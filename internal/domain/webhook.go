@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"encoding/json"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+type WebhookEvent string
+
+const (
+	EventPostCreated     WebhookEvent = "post.created"
+	EventCommentCreated  WebhookEvent = "comment.created"
+	EventCommentsToggled WebhookEvent = "comments.toggled"
+)
+
+const MinWebhookSecretLength = 16
+
+type Webhook struct {
+	ID string `json:"id"`
+	// Owner - автор, который зарегистрировал вебхук и получает события своих постов
+	Owner  string         `json:"owner"`
+	URL    string         `json:"url"`
+	Events []WebhookEvent `json:"events"`
+	// Secret - ключ подписи HMAC, наружу не отдается
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewWebhook(owner, rawURL string, events []WebhookEvent, secret string) (*Webhook, error) {
+	var v validator
+
+	if v.required("url", rawURL) {
+		u, err := url.Parse(rawURL)
+		switch {
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			v.add("url", "must be an absolute http or https URL")
+		case internalHost(u.Hostname()):
+			v.add("url", "must not point to a loopback, private or link-local address")
+		}
+	}
+	if len(events) == 0 {
+		v.add("events", "at least one event is required")
+	}
+	if utf8.RuneCountInString(secret) < MinWebhookSecretLength {
+		v.add("secret", "must be at least 16 characters")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return &Webhook{
+		ID:        utils.GenerateID(),
+		Owner:     owner,
+		URL:       rawURL,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// internalHost отсекает адреса внутренней сети, заданные в URL явно.
+// Имена, которые резолвятся во внутренние адреса, отсекаются при доставке
+func internalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(host)
+	return err == nil && InternalAddr(ip)
+}
+
+// InternalAddr - адрес, на который вебхуки не отправляются
+func InternalAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliverySucceeded DeliveryStatus = "SUCCEEDED"
+	// DeliveryFailed - попытка не удалась, будет повтор в NextAttemptAt
+	DeliveryFailed DeliveryStatus = "FAILED"
+	// DeliveryDead - попытки исчерпаны, доставка больше не повторяется
+	DeliveryDead DeliveryStatus = "DEAD"
+)
+
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	Event          WebhookEvent    `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError"`
+	ResponseStatus int             `json:"responseStatus"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+func NewWebhookDelivery(webhookID string, event WebhookEvent, payload json.RawMessage) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:            utils.GenerateID(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhook(t *testing.T) {
+	events := []WebhookEvent{EventPostCreated}
+	secret := "0123456789abcdef"
+
+	hook, err := NewWebhook("alice", "https://example.com/hook", events, secret)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", hook.Owner)
+
+	t.Run("error, if url points to internal network", func(t *testing.T) {
+		for _, url := range []string{
+			"http://localhost:8080/hook",
+			"http://127.0.0.1/hook",
+			"http://[::1]/hook",
+			"http://10.0.0.5/hook",
+			"http://192.168.1.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::ffff:127.0.0.1]/hook",
+			"http://0.0.0.0/hook",
+		} {
+			_, err := NewWebhook("alice", url, events, secret)
+			assert.ErrorIs(t, err, ErrValidation, url)
+		}
+	})
+}
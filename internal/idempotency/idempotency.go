@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const MaxKeyLength = 255
+
+// pendingTTL - сколько ключ считается занятым, пока запрос выполняется.
+// Если реплика упала посреди запроса, ключ освободится сам
+const pendingTTL = 30 * time.Second
+
+var (
+	ErrKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// Record - сохраненный ключ. EntityID пуст, пока запрос не завершен
+type Record struct {
+	Hash     string
+	EntityID string
+}
+
+type Store interface {
+	// Reserve занимает ключ за запросом с хешем hash. Если ключ уже занят,
+	// возвращает его запись и false
+	Reserve(ctx context.Context, key, hash string, ttl time.Duration) (Record, bool, error)
+	// Complete сохраняет id созданной сущности на ttl
+	Complete(ctx context.Context, key, entityID string, ttl time.Duration) error
+	// Release освобождает ключ запроса, который завершился ошибкой
+	Release(ctx context.Context, key string) error
+}
+
+// Keys помнит результат мутаций по ключу идемпотентности в течение ttl
+type Keys struct {
+	store Store
+	ttl   time.Duration
+}
+
+func NewKeys(store Store, ttl time.Duration) *Keys {
+	return &Keys{store: store, ttl: ttl}
+}
+
+// Do выполняет create один раз на ключ caller. Повтор с тем же request возвращает
+// id из первого вызова и replayed, с другим request - ErrKeyReused.
+// Ключи разных caller не пересекаются. Без ключа create выполняется как обычно
+func (k *Keys) Do(ctx context.Context, action, caller, key string, request any, create func() (string, error)) (id string, replayed bool, err error) {
+	if k == nil || key == "" {
+		id, err = create()
+		return id, false, err
+	}
+
+	if utf8.RuneCountInString(key) > MaxKeyLength {
+		return "", false, &domain.ValidationError{Fields: []domain.FieldError{{
+			Field:   "idempotencyKey",
+			Message: fmt.Sprintf("must be at most %d characters", MaxKeyLength),
+		}}}
+	}
+
+	hash, err := requestHash(request)
+	if err != nil {
+		return "", false, err
+	}
+
+	// caller экранируется, чтобы двоеточие в нем не давало чужой ключ
+	storeKey := action + ":" + url.QueryEscape(caller) + ":" + key
+	rec, reserved, err := k.store.Reserve(ctx, storeKey, hash, pendingTTL)
+	if err != nil {
+		// недоступность хранилища ключей не должна блокировать запись
+		log.Printf("idempotency reserve failed for %s: %v", action, err)
+		id, err = create()
+		return id, false, err
+	}
+
+	if !reserved {
+		switch {
+		case rec.Hash != hash:
+			return "", false, ErrKeyReused
+		case rec.EntityID == "":
+			return "", false, ErrInProgress
+		}
+		return rec.EntityID, true, nil
+	}
+
+	id, err = create()
+	if err != nil {
+		// запрос не выполнен, клиент может повторить его с тем же ключом
+		if releaseErr := k.store.Release(ctx, storeKey); releaseErr != nil {
+			log.Printf("idempotency release failed for %s: %v", action, releaseErr)
+		}
+		return "", false, err
+	}
+
+	if err := k.store.Complete(ctx, storeKey, id, k.ttl); err != nil {
+		log.Printf("idempotency complete failed for %s: %v", action, err)
+	}
+
+	return id, false, nil
+}
+
+func requestHash(request any) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed marshal idempotent request %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,149 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+func testStore(t *testing.T, store Store, advance func(time.Duration)) {
+	ctx := context.Background()
+
+	rec, reserved, err := store.Reserve(ctx, "createComment:k1", "h1", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, "h1", rec.Hash)
+
+	rec, reserved, err = store.Reserve(ctx, "createComment:k1", "h2", time.Second)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, Record{Hash: "h1"}, rec, "pending key has no entity yet")
+
+	require.NoError(t, store.Complete(ctx, "createComment:k1", "c1", time.Minute))
+
+	rec, reserved, err = store.Reserve(ctx, "createComment:k1", "h1", time.Second)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, Record{Hash: "h1", EntityID: "c1"}, rec)
+
+	_, reserved, err = store.Reserve(ctx, "createComment:k2", "h1", time.Second)
+	require.NoError(t, err)
+	require.True(t, reserved)
+	require.NoError(t, store.Release(ctx, "createComment:k2"))
+
+	_, reserved, err = store.Reserve(ctx, "createComment:k2", "h3", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reserved, "released key must be free")
+
+	advance(2 * time.Second)
+
+	_, reserved, err = store.Reserve(ctx, "createComment:k2", "h4", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reserved, "pending key must expire")
+
+	advance(time.Minute)
+
+	_, reserved, err = store.Reserve(ctx, "createComment:k1", "h5", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reserved, "completed key must expire after ttl")
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	testStore(t, store, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	testStore(t, NewRedisStore(client), mr.FastForward)
+}
+
+func TestKeys_Do(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeys(NewMemoryStore(), time.Hour)
+
+	calls := 0
+	create := func() (string, error) {
+		calls++
+		return "c1", nil
+	}
+
+	id, replayed, err := keys.Do(ctx, "createComment", "a", "k1", map[string]string{"content": "hi"}, create)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+	assert.False(t, replayed)
+
+	id, replayed, err = keys.Do(ctx, "createComment", "a", "k1", map[string]string{"content": "hi"}, create)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+	assert.True(t, replayed)
+	assert.Equal(t, 1, calls)
+
+	t.Run("error, if key is reused with another payload", func(t *testing.T) {
+		_, _, err := keys.Do(ctx, "createComment", "a", "k1", map[string]string{"content": "bye"}, create)
+		assert.ErrorIs(t, err, ErrKeyReused)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("keys are separate per action", func(t *testing.T) {
+		_, replayed, err := keys.Do(ctx, "createPost", "a", "k1", map[string]string{"content": "bye"}, create)
+		require.NoError(t, err)
+		assert.False(t, replayed)
+	})
+
+	t.Run("keys are separate per caller", func(t *testing.T) {
+		id, replayed, err := keys.Do(ctx, "createComment", "b", "k1", map[string]string{"content": "bye"}, func() (string, error) {
+			return "c2", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "c2", id)
+		assert.False(t, replayed)
+
+		_, _, err = keys.Do(ctx, "createComment", "a:k1", "x", map[string]string{"content": "hi"}, create)
+		require.NoError(t, err)
+		_, replayed, err = keys.Do(ctx, "createComment", "a", "k1:x", map[string]string{"content": "bye"}, create)
+		require.NoError(t, err)
+		assert.False(t, replayed, "colon in caller must not reach another caller's key")
+	})
+
+	t.Run("failed request releases key", func(t *testing.T) {
+		failed := errors.New("boom")
+		_, _, err := keys.Do(ctx, "createComment", "a", "k2", 1, func() (string, error) { return "", failed })
+		assert.ErrorIs(t, err, failed)
+
+		id, replayed, err := keys.Do(ctx, "createComment", "a", "k2", 2, create)
+		require.NoError(t, err)
+		assert.Equal(t, "c1", id)
+		assert.False(t, replayed)
+	})
+
+	t.Run("error, if request is in progress", func(t *testing.T) {
+		_, _, err := keys.Do(ctx, "createComment", "a", "k3", 1, func() (string, error) {
+			_, _, err := keys.Do(ctx, "createComment", "a", "k3", 1, create)
+			assert.ErrorIs(t, err, ErrInProgress)
+			return "c3", nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("error, if key is too long", func(t *testing.T) {
+		long := make([]byte, MaxKeyLength+1)
+		for i := range long {
+			long[i] = 'k'
+		}
+		_, _, err := keys.Do(ctx, "createComment", "a", string(long), 1, create)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+}
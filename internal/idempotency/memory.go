@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore хранит ключи в памяти процесса, используется в inmemory режиме
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+	calls   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+const sweepEvery = 1024
+
+func (s *MemoryStore) Reserve(ctx context.Context, key, hash string, ttl time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	if e, exists := s.entries[key]; exists && now.Before(e.expiresAt) {
+		return e.Record, false, nil
+	}
+
+	s.entries[key] = &entry{Record: Record{Hash: hash}, expiresAt: now.Add(ttl)}
+	return Record{Hash: hash}, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key, entityID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, exists := s.entries[key]; exists {
+		e.EntityID = entityID
+		e.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep удаляет истекшие ключи
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveScript занимает ключ, если его нет. Возвращает {1, hash, ""} для
+// нового ключа, иначе {0, <сохраненный hash>, <id или "">}
+var reserveScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], 'hash', ARGV[1]) == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return {1, ARGV[1], ''}
+end
+
+local state = redis.call('HMGET', KEYS[1], 'hash', 'id')
+return {0, state[1] or '', state[2] or ''}
+`)
+
+// completeScript записывает id, только если ключ еще не истек
+var completeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'id', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// RedisStore хранит ключи в Redis, поэтому повтор может прийти на любую реплику
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: "idempotency:",
+	}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key, hash string, ttl time.Duration) (Record, bool, error) {
+	res, err := reserveScript.Run(ctx, s.client, []string{s.prefix + key}, hash, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed run idempotency reserve script %w", err)
+	}
+
+	reserved, _ := res[0].(int64)
+	storedHash, _ := res[1].(string)
+	entityID, _ := res[2].(string)
+	return Record{Hash: storedHash, EntityID: entityID}, reserved == 1, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key, entityID string, ttl time.Duration) error {
+	err := completeScript.Run(ctx, s.client, []string{s.prefix + key}, entityID, ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed run idempotency complete script %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed delete idempotency key %w", err)
+	}
+	return nil
+}
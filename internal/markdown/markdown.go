@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Rendered - результат рендера Markdown
+type Rendered struct {
+	HTML      string
+	PlainText string
+}
+
+var (
+	// сырой HTML goldmark не пропускает, санитайзер - вторая линия защиты
+	md = goldmark.New(goldmark.WithExtensions(extension.Strikethrough, extension.Linkify))
+
+	htmlPolicy  = newPolicy()
+	textPolicy  = bluemonday.StrictPolicy()
+	blockTagsRe = regexp.MustCompile(`</(p|li|h[1-6]|blockquote|pre)>|<br\s*/?>`)
+	spacesRe    = regexp.MustCompile(`[ \t]+`)
+	newlinesRe  = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// newPolicy разрешает только форматирование текста, списки, код и ссылки
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "strong", "em", "del", "code", "pre",
+		"blockquote", "ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	return p
+}
+
+// Renderer рендерит Markdown в безопасный HTML и кеширует результат по хешу текста
+type Renderer struct {
+	cache *lru.Cache[[sha256.Size]byte, Rendered]
+}
+
+func NewRenderer(cacheSize int) (*Renderer, error) {
+	cache, err := lru.New[[sha256.Size]byte, Rendered](cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{cache: cache}, nil
+}
+
+// Render безопасен для nil-рендерера - тогда результат не кешируется
+func (r *Renderer) Render(source string) Rendered {
+	if r == nil {
+		return render(source)
+	}
+
+	key := sha256.Sum256([]byte(source))
+	if rendered, ok := r.cache.Get(key); ok {
+		return rendered
+	}
+
+	rendered := render(source)
+	r.cache.Add(key, rendered)
+	return rendered
+}
+
+func render(source string) Rendered {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		log.Printf("failed render markdown %v", err)
+		escaped := html.EscapeString(source)
+		return Rendered{HTML: "<p>" + escaped + "</p>", PlainText: source}
+	}
+
+	safeHTML := htmlPolicy.Sanitize(buf.String())
+	return Rendered{HTML: safeHTML, PlainText: plainText(safeHTML)}
+}
+
+// plainText убирает разметку, сохраняя переносы между блоками
+func plainText(safeHTML string) string {
+	text := blockTagsRe.ReplaceAllString(safeHTML, "$0\n")
+	text = html.UnescapeString(textPolicy.Sanitize(text))
+	text = spacesRe.ReplaceAllString(text, " ")
+	text = newlinesRe.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,39 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	r, err := NewRenderer(10)
+	require.NoError(t, err)
+
+	t.Run("formats markdown", func(t *testing.T) {
+		out := r.Render("**bold** and [link](https://example.com)\n\n- item")
+
+		assert.Contains(t, out.HTML, "<strong>bold</strong>")
+		assert.Contains(t, out.HTML, `<a href="https://example.com" rel="nofollow noreferrer">link</a>`)
+		assert.Contains(t, out.HTML, "<li>item</li>")
+		assert.Equal(t, "bold and link\nitem", out.PlainText)
+	})
+
+	t.Run("strips unsafe content", func(t *testing.T) {
+		out := r.Render("<script>alert(1)</script> [x](javascript:alert(1)) <img src=x onerror=alert(1)>")
+
+		assert.NotContains(t, out.HTML, "script")
+		assert.NotContains(t, out.HTML, "javascript")
+		assert.NotContains(t, out.HTML, "onerror")
+	})
+
+	t.Run("plain text unescapes entities", func(t *testing.T) {
+		assert.Equal(t, "a < b & c", r.Render("a < b & c").PlainText)
+	})
+
+	t.Run("nil renderer works without cache", func(t *testing.T) {
+		var nilRenderer *Renderer
+		assert.Equal(t, r.Render("*hi*"), nilRenderer.Render("*hi*"))
+	})
+}
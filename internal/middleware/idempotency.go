@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type idempotencyKey struct{}
+
+// IdempotencyKey кладет в контекст ключ из заголовка Idempotency-Key.
+// Ключ относится ко всему запросу, поле idempotencyKey в input его переопределяет
+func IdempotencyKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+			ctx = WithIdempotencyKey(ctx, key)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
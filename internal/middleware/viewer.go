@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type viewerKey struct{}
+
+// Viewer кладет в контекст автора из заголовка X-Author. Аутентификации
+// в сервисе пока нет, поэтому заголовку доверяем как и полю author в мутациях
+func Viewer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if author := strings.TrimSpace(r.Header.Get("X-Author")); author != "" {
+			ctx = WithViewer(ctx, author)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func WithViewer(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, viewerKey{}, author)
+}
+
+func ViewerFromContext(ctx context.Context) string {
+	author, _ := ctx.Value(viewerKey{}).(string)
+	return author
+}
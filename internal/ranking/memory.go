@@ -0,0 +1,53 @@
+package ranking
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+type postScore struct {
+	activity float64
+	votes    int
+}
+
+// MemoryRanker хранит счета в памяти процесса
+type MemoryRanker struct {
+	mu     sync.RWMutex
+	scores map[string]*postScore
+}
+
+func NewMemoryRanker() *MemoryRanker {
+	return &MemoryRanker{scores: make(map[string]*postScore)}
+}
+
+func (r *MemoryRanker) Record(ctx context.Context, post *domain.Post, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, exists := r.scores[post.ID]
+	if !exists {
+		s = &postScore{activity: publicationTerm(post)}
+		r.scores[post.ID] = s
+	}
+
+	if event.Activity > 0 {
+		s.activity = logAddExp(s.activity, activityTerm(event.At, event.Activity))
+	}
+	s.votes += event.Votes
+	return nil
+}
+
+func (r *MemoryRanker) Scores(ctx context.Context, postIDs []string) (map[string]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := make(map[string]float64, len(postIDs))
+	for _, id := range postIDs {
+		if s, exists := r.scores[id]; exists {
+			scores[id] = s.activity + voteTerm(s.votes)
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,97 @@
+package ranking
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const (
+	// DecayPeriod - за это время вклад активности в счет падает в e раз
+	DecayPeriod = 12 * time.Hour
+	// CommentWeight - вклад комментария относительно публикации поста
+	CommentWeight = 1.0
+	// VoteWeight - множитель логарифма суммы голосов
+	VoteWeight = 1.0
+)
+
+// epoch - точка отсчета времени для счетов. Счет хранится в логарифмах,
+// поэтому затухание по возрасту не требует пересчета старых постов
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Event - событие поста, меняющее его счет
+type Event struct {
+	At time.Time
+	// Activity - вес активности, например CommentWeight. 0 - без активности
+	Activity float64
+	// Votes - изменение суммы голосов
+	Votes int
+}
+
+// Ranker хранит hot-счета постов и обновляет их инкрементально на каждое
+// событие. Первое событие добавляет к счету публикацию поста
+type Ranker interface {
+	Record(ctx context.Context, post *domain.Post, event Event) error
+	// Scores возвращает счета известных постов, остальных в ответе нет
+	Scores(ctx context.Context, postIDs []string) (map[string]float64, error)
+}
+
+// activityTerm - логарифм вклада активности с весом weight в момент at.
+// Сумма exp(activityTerm) пропорциональна сумме весов, затухающих со временем
+func activityTerm(at time.Time, weight float64) float64 {
+	return math.Log(weight) + at.Sub(epoch).Seconds()/DecayPeriod.Seconds()
+}
+
+// publicationTerm - вклад публикации поста
+func publicationTerm(post *domain.Post) float64 {
+	publishedAt := post.CreatedAt
+	if post.PublishAt != nil {
+		publishedAt = *post.PublishAt
+	}
+	return activityTerm(publishedAt, 1)
+}
+
+// logAddExp считает log(exp(a) + exp(b)) без переполнения
+func logAddExp(a, b float64) float64 {
+	m := math.Max(a, b)
+	return m + math.Log(math.Exp(a-m)+math.Exp(b-m))
+}
+
+func voteTerm(votes int) float64 {
+	if votes == 0 {
+		return 0
+	}
+	term := VoteWeight * math.Log1p(math.Abs(float64(votes)))
+	if votes < 0 {
+		return -term
+	}
+	return term
+}
+
+// SortHot сортирует посты по убыванию hot-счета. Посты без событий
+// ранжируются только по времени публикации
+func SortHot(ctx context.Context, r Ranker, posts []*domain.Post) error {
+	ids := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	scores, err := r.Scores(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	score := func(post *domain.Post) float64 {
+		if s, ok := scores[post.ID]; ok {
+			return s
+		}
+		return publicationTerm(post)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return score(posts[i]) > score(posts[j])
+	})
+	return nil
+}
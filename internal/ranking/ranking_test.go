@@ -0,0 +1,65 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+func testPost(id string, publishedAt time.Time) *domain.Post {
+	return &domain.Post{ID: id, Status: domain.PostPublished, CreatedAt: publishedAt, PublishAt: &publishedAt}
+}
+
+func testRankers(t *testing.T) map[string]Ranker {
+	mr := miniredis.RunT(t)
+	return map[string]Ranker{
+		"memory": NewMemoryRanker(),
+		"redis":  NewRedisRanker(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+}
+
+func TestRanker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	for name, r := range testRankers(t) {
+		t.Run(name, func(t *testing.T) {
+			fresh := testPost("fresh", now)
+			old := testPost("old", now.Add(-2*DecayPeriod))
+			busy := testPost("busy", now.Add(-2*DecayPeriod))
+			quiet := testPost("quiet", now.Add(-time.Minute))
+
+			// свежие комментарии поднимают старый пост выше нового
+			for i := 0; i < 10; i++ {
+				require.NoError(t, r.Record(ctx, busy, Event{At: now, Activity: CommentWeight}))
+			}
+			require.NoError(t, r.Record(ctx, fresh, Event{At: now}))
+			require.NoError(t, r.Record(ctx, quiet, Event{At: now, Votes: -3}))
+
+			posts := []*domain.Post{old, quiet, fresh, busy}
+			require.NoError(t, SortHot(ctx, r, posts))
+
+			ids := make([]string, len(posts))
+			for i, post := range posts {
+				ids[i] = post.ID
+			}
+			assert.Equal(t, []string{"busy", "fresh", "quiet", "old"}, ids)
+
+			scores, err := r.Scores(ctx, []string{"busy", "old"})
+			require.NoError(t, err)
+			assert.Len(t, scores, 1)
+			assert.InDelta(t, logAddExp(publicationTerm(busy), activityTerm(now, 10*CommentWeight)), scores["busy"], 1e-9)
+		})
+	}
+}
+
+func TestLogAddExp(t *testing.T) {
+	assert.InDelta(t, 1e6+0.6931471805599453, logAddExp(1e6, 1e6), 1e-9)
+	assert.InDelta(t, 5.0, logAddExp(5, -1e6), 1e-9)
+}
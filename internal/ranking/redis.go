@@ -0,0 +1,107 @@
+package ranking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+// recordScript атомарно добавляет событие к состоянию поста в хеше
+// и пишет итоговый счет в sorted set.
+// ARGV: id поста, вклад публикации, вклад активности (пустая строка - без активности),
+// изменение голосов, VoteWeight
+var recordScript = redis.NewScript(`
+local id = ARGV[1]
+local activity = tonumber(redis.call('HGET', KEYS[2], id .. ':activity'))
+if activity == nil then
+	activity = tonumber(ARGV[2])
+end
+
+if ARGV[3] ~= '' then
+	local add = tonumber(ARGV[3])
+	local m = math.max(activity, add)
+	activity = m + math.log(math.exp(activity - m) + math.exp(add - m))
+end
+
+local votes = (tonumber(redis.call('HGET', KEYS[2], id .. ':votes')) or 0) + tonumber(ARGV[4])
+redis.call('HSET', KEYS[2], id .. ':activity', string.format('%.17g', activity), id .. ':votes', votes)
+
+local score = activity
+if votes ~= 0 then
+	local term = tonumber(ARGV[5]) * math.log(1 + math.abs(votes))
+	if votes < 0 then
+		term = -term
+	end
+	score = score + term
+end
+
+redis.call('ZADD', KEYS[1], string.format('%.17g', score), id)
+return string.format('%.17g', score)
+`)
+
+// RedisRanker хранит счета в sorted set, поэтому они общие для всех реплик
+type RedisRanker struct {
+	client   *redis.Client
+	scoreKey string
+	stateKey string
+}
+
+func NewRedisRanker(client *redis.Client) *RedisRanker {
+	return &RedisRanker{
+		client:   client,
+		scoreKey: "posts:hot",
+		stateKey: "posts:hot:state",
+	}
+}
+
+func (r *RedisRanker) Record(ctx context.Context, post *domain.Post, event Event) error {
+	activity := ""
+	if event.Activity > 0 {
+		activity = strconv.FormatFloat(activityTerm(event.At, event.Activity), 'g', -1, 64)
+	}
+
+	err := recordScript.Run(ctx, r.client, []string{r.scoreKey, r.stateKey},
+		post.ID,
+		strconv.FormatFloat(publicationTerm(post), 'g', -1, 64),
+		activity,
+		event.Votes,
+		VoteWeight,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed record post score %w", err)
+	}
+	return nil
+}
+
+func (r *RedisRanker) Scores(ctx context.Context, postIDs []string) (map[string]float64, error) {
+	scores := make(map[string]float64, len(postIDs))
+	if len(postIDs) == 0 {
+		return scores, nil
+	}
+
+	// ZSCORE в отличие от ZMSCORE отличает отсутствующий пост от нулевого счета
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.ZScore(ctx, r.scoreKey, id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed get post scores %w", err)
+	}
+
+	for i, cmd := range cmds {
+		score, err := cmd.Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed get post score %w", err)
+		}
+		scores[postIDs[i]] = score
+	}
+	return scores, nil
+}
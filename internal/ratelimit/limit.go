@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit описывает token bucket: в ведре помещается Burst токенов,
+// и за Period оно полностью восстанавливается.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// perMillisecond возвращает скорость пополнения ведра в токенах за миллисекунду
+func (l Limit) perMillisecond() float64 {
+	return float64(l.Burst) / float64(l.Period.Milliseconds())
+}
+
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit разбирает строку вида "10/1m" - 10 запросов за минуту
+func ParseLimit(s string) (Limit, error) {
+	burst, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(burst)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit count %q", burst)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d < time.Millisecond {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q", period)
+	}
+
+	return Limit{Burst: n, Period: d}, nil
+}
+
+// ParseLimits разбирает список лимитов по мутациям:
+// "createComment=20/1m,createPost=5/1m"
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		action, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q, expected <mutation>=<count>/<period>", part)
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", action, err)
+		}
+		limits[strings.TrimSpace(action)] = limit
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+type LimitError struct {
+	Action     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry after %s", e.Action, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Policy связывает мутации с их лимитами
+type Policy struct {
+	limiter Limiter
+	limits  map[string]Limit
+}
+
+func NewPolicy(limiter Limiter, limits map[string]Limit) *Policy {
+	return &Policy{limiter: limiter, limits: limits}
+}
+
+// Check расходует по токену из ведра каждого субъекта (автор, ip) для действия.
+// Пустые субъекты пропускаются, действия без лимита всегда разрешены
+func (p *Policy) Check(ctx context.Context, action string, subjects ...string) error {
+	if p == nil {
+		return nil
+	}
+
+	limit, exists := p.limits[action]
+	if !exists {
+		return nil
+	}
+
+	for _, subject := range subjects {
+		if subject == "" {
+			continue
+		}
+
+		res, err := p.limiter.Allow(ctx, action+":"+subject, limit)
+		if err != nil {
+			// недоступность хранилища лимитов не должна блокировать запись
+			log.Printf("rate limit check failed for %s: %v", action, err)
+			continue
+		}
+		if !res.Allowed {
+			return &LimitError{Action: action, RetryAfter: res.RetryAfter}
+		}
+	}
+
+	return nil
+}
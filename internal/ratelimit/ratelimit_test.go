@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	t.Run("valid limits", func(t *testing.T) {
+		limits, err := ParseLimits("createComment=20/1m, createPost=5/30s")
+
+		require.NoError(t, err)
+		assert.Equal(t, Limit{Burst: 20, Period: time.Minute}, limits["createComment"])
+		assert.Equal(t, Limit{Burst: 5, Period: 30 * time.Second}, limits["createPost"])
+	})
+
+	t.Run("error, if entry is malformed", func(t *testing.T) {
+		_, err := ParseLimits("createComment=20")
+		assert.Error(t, err)
+
+		_, err = ParseLimits("createComment=0/1m")
+		assert.Error(t, err)
+	})
+}
+
+func testLimiter(t *testing.T, limiter Limiter, advance func(time.Duration)) {
+	ctx := context.Background()
+	limit := Limit{Burst: 2, Period: 2 * time.Second}
+
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(ctx, "bob", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	res, err := limiter.Allow(ctx, "bob", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	res, err = limiter.Allow(ctx, "alice", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "buckets must be separate per key")
+
+	advance(time.Second)
+
+	res, err = limiter.Allow(ctx, "bob", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "bucket must refill over time")
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	testLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	now := time.Unix(1700000000, 0)
+	limiter := NewRedisLimiter(client)
+	limiter.now = func() time.Time { return now }
+
+	testLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestPolicy_Check(t *testing.T) {
+	ctx := context.Background()
+	policy := NewPolicy(NewMemoryLimiter(), map[string]Limit{
+		"createComment": {Burst: 1, Period: time.Minute},
+	})
+
+	require.NoError(t, policy.Check(ctx, "createComment", "author:bob", "ip:127.0.0.1"))
+
+	err := policy.Check(ctx, "createComment", "author:bob", "ip:127.0.0.1")
+	var limitErr *LimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "createComment", limitErr.Action)
+	assert.Positive(t, limitErr.RetryAfter)
+
+	assert.NoError(t, policy.Check(ctx, "toggleComments", "ip:127.0.0.1"), "actions without limit are allowed")
+
+	var nilPolicy *Policy
+	assert.NoError(t, nilPolicy.Check(ctx, "createComment", "author:bob"))
+}
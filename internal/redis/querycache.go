@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/redis/go-redis/v9"
+)
+
+// QueryCache хранит тексты automatic persisted queries в Redis,
+// чтобы зарегистрированный на одной реплике запрос был виден остальным
+type QueryCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+var _ graphql.Cache[string] = (*QueryCache)(nil)
+
+func NewQueryCache(client *redis.Client, ttl time.Duration) *QueryCache {
+	return &QueryCache{client: client, ttl: ttl}
+}
+
+const apqPrefix = "apq:"
+
+func (c *QueryCache) Get(ctx context.Context, key string) (string, bool) {
+	query, err := c.client.Get(ctx, apqPrefix+key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			fmt.Printf("failed to get persisted query from redis: %v\n", err)
+		}
+		return "", false
+	}
+	return query, true
+}
+
+func (c *QueryCache) Add(ctx context.Context, key string, query string) {
+	if err := c.client.Set(ctx, apqPrefix+key, query, c.ttl).Err(); err != nil {
+		fmt.Printf("failed to save persisted query to redis: %v\n", err)
+	}
+}
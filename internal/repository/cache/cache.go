@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"`
+}
+
+// Cache хранит результаты репозиториев в Redis. Каждый ключ привязан к тегу
+// (посту или родительскому комментарию), по тегу ключи инвалидируются разом
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+func NewCache(client *redis.Client, ttl time.Duration) *Cache {
+	return &Cache{client: client, ttl: ttl}
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+// ServeHTTP отдает статистику попаданий в кеш
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Stats())
+}
+
+func (c *Cache) get(ctx context.Context, key string, dst interface{}) bool {
+	// транзакция должна видеть свои изменения, а не закешированную страницу
+	if repository.InUnitOfWork(ctx) {
+		return false
+	}
+
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.fail("get", err)
+		}
+		c.misses.Add(1)
+		return false
+	}
+
+	if err := json.Unmarshal(data, dst); err != nil {
+		c.fail("decode", err)
+		c.misses.Add(1)
+		return false
+	}
+
+	c.hits.Add(1)
+	return true
+}
+
+func (c *Cache) set(ctx context.Context, key string, tags []string, value interface{}) {
+	// внутри транзакции прочитаны незафиксированные данные
+	if repository.InUnitOfWork(ctx) {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.fail("encode", err)
+		return
+	}
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, c.ttl)
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tag, key)
+			pipe.Expire(ctx, tag, c.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		c.fail("set", err)
+	}
+}
+
+// invalidate удаляет ключи и все ключи, привязанные к тегам. Внутри единицы
+// работы сброс откладывается до фиксации, иначе параллельный запрос успеет
+// закешировать старые данные до коммита
+func (c *Cache) invalidate(ctx context.Context, keys []string, tags []string) {
+	repository.AfterCommit(ctx, func() {
+		c.invalidateNow(ctx, keys, tags)
+	})
+}
+
+func (c *Cache) invalidateNow(ctx context.Context, keys []string, tags []string) {
+	for _, tag := range tags {
+		members, err := c.client.SMembers(ctx, tag).Result()
+		if err != nil {
+			c.fail("invalidate", err)
+			continue
+		}
+		keys = append(keys, members...)
+		keys = append(keys, tag)
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		c.fail("invalidate", err)
+	}
+}
+
+func (c *Cache) fail(op string, err error) {
+	c.errors.Add(1)
+	log.Printf("cache %s failed: %v", op, err)
+}
+
+func postKey(postID string) string {
+	return "cache:post:" + postID
+}
+
+func postCommentsKey(postID string, limit, offset int) string {
+	return fmt.Sprintf("cache:comments:post:%s:%d:%d", postID, limit, offset)
+}
+
+func pinnedKey(postID string) string {
+	return "cache:comments:pinned:" + postID
+}
+
+func childrenKey(parentID string, limit, offset int) string {
+	return fmt.Sprintf("cache:comments:children:%s:%d:%d", parentID, limit, offset)
+}
+
+func postTag(postID string) string {
+	return "cache:tag:post:" + postID
+}
+
+func parentTag(parentID string) string {
+	return "cache:tag:parent:" + parentID
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	mr := miniredis.RunT(t)
+	return NewCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+}
+
+func TestCachedPostRepository(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t)
+	repo := NewCachedPostRepository(inmemory.NewInMemoryPostRepository(), c)
+
+	post, err := domain.NewPost("title", "content", "author", true)
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, post))
+
+	_, err = repo.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	cached, err := repo.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, post.Title, cached.Title)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1}, c.Stats())
+
+	t.Run("toggle invalidates post", func(t *testing.T) {
+		require.NoError(t, repo.ToggleComments(ctx, post.ID, false))
+
+		found, err := repo.GetByID(ctx, post.ID)
+		require.NoError(t, err)
+		assert.False(t, found.AllowComments)
+	})
+
+	t.Run("missing post is not cached", func(t *testing.T) {
+		_, err := repo.GetByID(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrPostNotFound)
+	})
+}
+
+func TestCachedCommentRepository(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t)
+	repo := NewCachedCommentRepository(inmemory.NewInMemoryCommentRepository(), c)
+
+	first, _ := domain.NewComment("post-1", "author", nil, "first")
+	require.NoError(t, repo.Create(ctx, first))
+	other, _ := domain.NewComment("post-2", "author", nil, "other post")
+	require.NoError(t, repo.Create(ctx, other))
+
+	page, err := repo.GetByPost(ctx, "post-1", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	_, err = repo.GetByPost(ctx, "post-2", 10, 0)
+	require.NoError(t, err)
+	_, err = repo.GetChildren(ctx, first.ID, 10, 0)
+	require.NoError(t, err)
+
+	t.Run("reply invalidates only parent pages", func(t *testing.T) {
+		reply, _ := domain.NewComment("post-1", "author", &first.ID, "reply")
+		require.NoError(t, repo.Create(ctx, reply))
+
+		before := c.Stats()
+		children, err := repo.GetChildren(ctx, first.ID, 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, children, 1)
+		_, err = repo.GetByPost(ctx, "post-1", 10, 0)
+		require.NoError(t, err)
+
+		after := c.Stats()
+		assert.Equal(t, before.Misses+1, after.Misses, "children page must be reloaded")
+		assert.Equal(t, before.Hits+1, after.Hits, "post page must stay cached")
+	})
+
+	t.Run("top-level comment invalidates only its post", func(t *testing.T) {
+		second, _ := domain.NewComment("post-1", "author", nil, "second")
+		require.NoError(t, repo.Create(ctx, second))
+
+		before := c.Stats()
+		page, err := repo.GetByPost(ctx, "post-1", 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, page, 2)
+		_, err = repo.GetByPost(ctx, "post-2", 10, 0)
+		require.NoError(t, err)
+
+		after := c.Stats()
+		assert.Equal(t, before.Misses+1, after.Misses)
+		assert.Equal(t, before.Hits+1, after.Hits)
+	})
+}
+
+func TestCachedCommentRepository_UnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t)
+	posts, comments := inmemory.NewInMemoryRepositories()
+	units := inmemory.NewUnitOfWork(posts)
+	repo := NewCachedCommentRepository(comments, c)
+
+	post, err := domain.NewPost("title", "content", "author", true)
+	require.NoError(t, err)
+	require.NoError(t, posts.Create(ctx, post))
+
+	key := postCommentsKey(post.ID, 10, 0)
+	cached := func() bool {
+		n, err := c.client.Exists(ctx, key).Result()
+		require.NoError(t, err)
+		return n == 1
+	}
+
+	_, err = repo.GetByPost(ctx, post.ID, 10, 0)
+	require.NoError(t, err)
+	require.True(t, cached())
+
+	t.Run("rolled back unit keeps pages", func(t *testing.T) {
+		failed := errors.New("boom")
+		err := units.Do(ctx, func(ctx context.Context) error {
+			comment, _ := domain.NewComment(post.ID, "author", nil, "rolled back")
+			require.NoError(t, repo.Create(ctx, comment))
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+		assert.True(t, cached())
+	})
+
+	t.Run("pages are invalidated after commit", func(t *testing.T) {
+		err := units.Do(ctx, func(ctx context.Context) error {
+			comment, _ := domain.NewComment(post.ID, "author", nil, "committed")
+			require.NoError(t, repo.Create(ctx, comment))
+			assert.True(t, cached(), "page must not be invalidated before commit")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, cached())
+	})
+}
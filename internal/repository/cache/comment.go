@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+// CachedCommentRepository кеширует страницы комментариев поста и ответов.
+// Новый комментарий сбрасывает только страницы своего поста или родителя
+type CachedCommentRepository struct {
+	next  repository.CommentRepository
+	cache *Cache
+}
+
+var _ repository.CommentRepository = (*CachedCommentRepository)(nil)
+
+func NewCachedCommentRepository(next repository.CommentRepository, cache *Cache) *CachedCommentRepository {
+	return &CachedCommentRepository{next: next, cache: cache}
+}
+
+func (r *CachedCommentRepository) Create(ctx context.Context, comment *domain.Comment) error {
+	if err := r.next.Create(ctx, comment); err != nil {
+		return err
+	}
+
+	r.invalidatePage(ctx, comment)
+	return nil
+}
+
+// invalidatePage сбрасывает страницы, на которых виден комментарий
+func (r *CachedCommentRepository) invalidatePage(ctx context.Context, comment *domain.Comment) {
+	if comment.ParentID == nil {
+		r.cache.invalidate(ctx, nil, []string{postTag(comment.PostID)})
+	} else {
+		r.cache.invalidate(ctx, nil, []string{parentTag(*comment.ParentID)})
+	}
+}
+
+func (r *CachedCommentRepository) ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	return r.next.ListByPost(ctx, postID)
+}
+
+func (r *CachedCommentRepository) DeleteByPost(ctx context.Context, postID string) error {
+	if err := r.next.DeleteByPost(ctx, postID); err != nil {
+		return err
+	}
+
+	r.cache.invalidate(ctx, nil, []string{postTag(postID)})
+	return nil
+}
+
+func (r *CachedCommentRepository) SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error) {
+	comment, err := r.next.SetLocked(ctx, id, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	// закрытый комментарий может лежать на любой странице поста
+	r.cache.invalidate(ctx, nil, []string{postTag(comment.PostID)})
+	return comment, nil
+}
+
+func (r *CachedCommentRepository) ThreadLocked(ctx context.Context, id string) (bool, error) {
+	return r.next.ThreadLocked(ctx, id)
+}
+
+func (r *CachedCommentRepository) Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error) {
+	comment, err := r.next.Pin(ctx, id, maxPins)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.invalidate(ctx, nil, []string{postTag(comment.PostID)})
+	return comment, nil
+}
+
+func (r *CachedCommentRepository) Unpin(ctx context.Context, id string) (*domain.Comment, error) {
+	comment, err := r.next.Unpin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.invalidate(ctx, nil, []string{postTag(comment.PostID)})
+	return comment, nil
+}
+
+func (r *CachedCommentRepository) GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	key := pinnedKey(postID)
+
+	var comments []*domain.Comment
+	if r.cache.get(ctx, key, &comments) {
+		return comments, nil
+	}
+
+	comments, err := r.next.GetPinned(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.set(ctx, key, []string{postTag(postID)}, comments)
+	return comments, nil
+}
+
+func (r *CachedCommentRepository) Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error) {
+	old, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldPostID := old.PostID
+
+	moved, err := r.next.Move(ctx, id, parentID, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// страницы ответов помечены тегом поста, поэтому сбрасываются вместе с ним
+	tags := []string{postTag(oldPostID), postTag(moved.PostID)}
+	if moved.ParentID != nil {
+		tags = append(tags, parentTag(*moved.ParentID))
+	}
+	r.cache.invalidate(ctx, nil, tags)
+	return moved, nil
+}
+
+func (r *CachedCommentRepository) GetByID(ctx context.Context, id string) (*domain.Comment, error) {
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *CachedCommentRepository) GetByPost(ctx context.Context, postID string, limit int, offset int) ([]*domain.Comment, error) {
+	key := postCommentsKey(postID, limit, offset)
+
+	var comments []*domain.Comment
+	if r.cache.get(ctx, key, &comments) {
+		return comments, nil
+	}
+
+	comments, err := r.next.GetByPost(ctx, postID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.set(ctx, key, []string{postTag(postID)}, comments)
+	return comments, nil
+}
+
+func (r *CachedCommentRepository) GetChildren(ctx context.Context, parentID string, limit int, offset int) ([]*domain.Comment, error) {
+	key := childrenKey(parentID, limit, offset)
+
+	var comments []*domain.Comment
+	if r.cache.get(ctx, key, &comments) {
+		return comments, nil
+	}
+
+	comments, err := r.next.GetChildren(ctx, parentID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{parentTag(parentID)}
+	if len(comments) > 0 {
+		// чтобы удаление поста сбрасывало и ветки ответов
+		tags = append(tags, postTag(comments[0].PostID))
+	}
+	r.cache.set(ctx, key, tags, comments)
+	return comments, nil
+}
+
+func (r *CachedCommentRepository) GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error) {
+	return r.next.GetWithAncestors(ctx, id, depth)
+}
+
+func (r *CachedCommentRepository) LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error) {
+	return r.next.LastCommentTime(ctx, postID, author)
+}
+
+// счетчики меняются с каждым комментарием, поэтому не кешируются
+
+func (r *CachedCommentRepository) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	return r.next.PostStats(ctx, postID)
+}
+
+func (r *CachedCommentRepository) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	return r.next.CommentStats(ctx, commentID)
+}
+
+func (r *CachedCommentRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	return r.next.KnownAuthors(ctx, authors)
+}
+
+func (r *CachedCommentRepository) Approve(ctx context.Context, id string) (*domain.Comment, bool, error) {
+	comment, approved, err := r.next.Approve(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if approved {
+		r.invalidatePage(ctx, comment)
+	}
+	return comment, approved, nil
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+// CachedPostRepository кеширует посты по id. Проверка AllowComments всегда
+// идет в хранилище, чтобы выключение комментариев срабатывало сразу
+type CachedPostRepository struct {
+	next  repository.PostRepository
+	cache *Cache
+}
+
+var _ repository.PostRepository = (*CachedPostRepository)(nil)
+
+func NewCachedPostRepository(next repository.PostRepository, cache *Cache) *CachedPostRepository {
+	return &CachedPostRepository{next: next, cache: cache}
+}
+
+func (r *CachedPostRepository) Create(ctx context.Context, post *domain.Post) error {
+	return r.next.Create(ctx, post)
+}
+
+func (r *CachedPostRepository) GetByID(ctx context.Context, id string) (*domain.Post, error) {
+	var post domain.Post
+	if r.cache.get(ctx, postKey(id), &post) {
+		return &post, nil
+	}
+
+	found, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.set(ctx, postKey(id), []string{postTag(id)}, found)
+	return found, nil
+}
+
+func (r *CachedPostRepository) GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error) {
+	return r.next.GetAll(ctx, filter)
+}
+
+func (r *CachedPostRepository) PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error) {
+	posts, err := r.next.PublishDue(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(posts))
+	for _, post := range posts {
+		keys = append(keys, postKey(post.ID))
+	}
+	if len(keys) > 0 {
+		r.cache.invalidate(ctx, keys, nil)
+	}
+	return posts, nil
+}
+
+func (r *CachedPostRepository) Update(ctx context.Context, post *domain.Post) error {
+	if err := r.next.Update(ctx, post); err != nil {
+		return err
+	}
+
+	r.cache.invalidate(ctx, []string{postKey(post.ID)}, nil)
+	return nil
+}
+
+func (r *CachedPostRepository) Delete(ctx context.Context, postID string) error {
+	if err := r.next.Delete(ctx, postID); err != nil {
+		return err
+	}
+
+	r.cache.invalidate(ctx, []string{postKey(postID)}, []string{postTag(postID)})
+	return nil
+}
+
+func (r *CachedPostRepository) CheckAllowedComments(ctx context.Context, postID string) (bool, error) {
+	return r.next.CheckAllowedComments(ctx, postID)
+}
+
+func (r *CachedPostRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	return r.next.KnownAuthors(ctx, authors)
+}
+
+func (r *CachedPostRepository) Vote(ctx context.Context, postID, voter string, value int) (int, error) {
+	delta, err := r.next.Vote(ctx, postID, voter, value)
+	if err != nil {
+		return 0, err
+	}
+
+	if delta != 0 {
+		r.cache.invalidate(ctx, []string{postKey(postID)}, nil)
+	}
+	return delta, nil
+}
+
+func (r *CachedPostRepository) ListTags(ctx context.Context, limit int) ([]*domain.Tag, error) {
+	return r.next.ListTags(ctx, limit)
+}
+
+func (r *CachedPostRepository) ToggleComments(ctx context.Context, postID string, allow bool) error {
+	if err := r.next.ToggleComments(ctx, postID, allow); err != nil {
+		return err
+	}
+
+	r.cache.invalidate(ctx, []string{postKey(postID)}, nil)
+	return nil
+}
+
+func (r *CachedPostRepository) UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error {
+	if err := r.next.UpdateSettings(ctx, postID, settings); err != nil {
+		return err
+	}
+
+	r.cache.invalidate(ctx, []string{postKey(postID)}, nil)
+	return nil
+}
@@ -2,8 +2,12 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/tmozzze/SasPosts/internal/domain"
@@ -12,15 +16,125 @@ import (
 type InMemoryCommentRepository struct {
 	mu       sync.RWMutex
 	comments map[string]*domain.Comment
+
+	postStats    map[string]*domain.PostStats
+	commentStats map[string]*domain.CommentStats
+	participants map[string]map[string]bool
+
+	// journal - nil, если хранилище живет только в памяти процесса
+	journal *journal
+	// units - блокировка для UnitOfWork, общая со связанными постами
+	units *units
+	// posts проверяются при создании и переносе, см. NewInMemoryRepositories
+	posts *InMemoryPostRepository
+}
+
+// commentEntry - запись журнала: новое состояние комментария или удаление
+// всех комментариев поста. Счетчики не пишутся, они пересчитываются при загрузке
+type commentEntry struct {
+	Comment     *domain.Comment `json:"comment,omitempty"`
+	DeletedPost string          `json:"deletedPost,omitempty"`
+}
+
+// record пишет изменение в журнал. Вызывается под блокировкой до того,
+// как изменение применено в памяти: если запись не удалась, состояние не меняется
+func (r *InMemoryCommentRepository) record(comments ...*domain.Comment) error {
+	if r.journal == nil {
+		return nil
+	}
+	entries := make([]any, 0, len(comments))
+	for _, comment := range comments {
+		entries = append(entries, commentEntry{Comment: comment})
+	}
+	if err := r.journal.append(entries...); err != nil {
+		return fmt.Errorf("failed write journal %w", err)
+	}
+	return nil
+}
+
+// clone копирует комментарий. Комментарии в map не меняются после записи:
+// изменение кладет новую копию, а наружу отдаются копии, поэтому вызывающий
+// код читает их без блокировки репозитория
+func clone(comment *domain.Comment) *domain.Comment {
+	c := *comment
+	return &c
+}
+
+func cloneAll(comments []*domain.Comment) []*domain.Comment {
+	for i, comment := range comments {
+		comments[i] = clone(comment)
+	}
+	return comments
+}
+
+func (r *InMemoryCommentRepository) apply(data []byte) error {
+	var entry commentEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+
+	if entry.Comment != nil {
+		r.comments[entry.Comment.ID] = entry.Comment
+	}
+	if entry.DeletedPost != "" {
+		for id, comment := range r.comments {
+			if comment.PostID == entry.DeletedPost {
+				delete(r.comments, id)
+			}
+		}
+	}
+	return nil
+}
+
+// rebuildStats пересчитывает счетчики постов и веток после загрузки журнала
+func (r *InMemoryCommentRepository) rebuildStats() {
+	r.postStats = make(map[string]*domain.PostStats)
+	r.commentStats = make(map[string]*domain.CommentStats)
+	r.participants = make(map[string]map[string]bool)
+
+	for _, comment := range r.comments {
+		if !comment.Pending {
+			r.recordVisible(comment)
+		}
+	}
+}
+
+// dropOrphans удаляет комментарии постов, которых больше нет
+func (r *InMemoryCommentRepository) dropOrphans() {
+	for id, comment := range r.comments {
+		if !r.postExists(comment.PostID) {
+			delete(r.comments, id)
+		}
+	}
+}
+
+func (r *InMemoryCommentRepository) compact() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.journal.compact(func(emit func(entry any) error) error {
+		for _, comment := range r.comments {
+			if err := emit(commentEntry{Comment: comment}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func NewInMemoryCommentRepository() *InMemoryCommentRepository {
 	return &InMemoryCommentRepository{
-		comments: make(map[string]*domain.Comment),
+		comments:     make(map[string]*domain.Comment),
+		postStats:    make(map[string]*domain.PostStats),
+		commentStats: make(map[string]*domain.CommentStats),
+		participants: make(map[string]map[string]bool),
+		units:        &units{},
 	}
 }
 
 func (r *InMemoryCommentRepository) Create(ctx context.Context, comment *domain.Comment) error {
+	defer r.units.hold(ctx)()
+
 	if utf8.RuneCountInString(comment.Content) > domain.MaxCommentLength {
 		return domain.ErrCommentTooLong
 	}
@@ -28,6 +142,11 @@ func (r *InMemoryCommentRepository) Create(ctx context.Context, comment *domain.
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// пост, удаленный после проверки, заберет комментарий в DeleteByPost
+	if !r.postExists(comment.PostID) {
+		return domain.ErrPostNotFound
+	}
+
 	if comment.ParentID == nil {
 		comment.Path = comment.ID
 		comment.Depth = 0
@@ -36,14 +155,304 @@ func (r *InMemoryCommentRepository) Create(ctx context.Context, comment *domain.
 		if !exists {
 			return domain.ErrParentCommentNotFound
 		}
+		if r.lockedPath(parent.Path) {
+			return domain.ErrThreadLocked
+		}
 		comment.Path = parent.Path + "." + comment.ID
 		comment.Depth = parent.Depth + 1
 	}
 
-	r.comments[comment.ID] = comment
+	if err := r.record(comment); err != nil {
+		return err
+	}
+	r.comments[comment.ID] = clone(comment)
+	if !comment.Pending {
+		r.recordVisible(comment)
+	}
 	return nil
 }
 
+// postExists проверяет пост, если репозиторий связан с постами
+func (r *InMemoryCommentRepository) postExists(postID string) bool {
+	return r.posts == nil || r.posts.exists(postID)
+}
+
+// recordVisible обновляет счетчики поста и предков комментария,
+// который стал виден в ветке. Вызывается под блокировкой
+func (r *InMemoryCommentRepository) recordVisible(comment *domain.Comment) {
+	if comment.ParentID != nil {
+		r.statsOf(*comment.ParentID).ReplyCount++
+		for _, id := range comment.AncestorIDs() {
+			r.statsOf(id).DescendantCount++
+		}
+	}
+
+	r.addToPostStats(comment)
+}
+
+func (r *InMemoryCommentRepository) addToPostStats(comment *domain.Comment) {
+	stats, exists := r.postStats[comment.PostID]
+	if !exists {
+		stats = &domain.PostStats{}
+		r.postStats[comment.PostID] = stats
+	}
+	stats.CommentCount++
+	if stats.LastCommentAt == nil || comment.CreatedAt.After(*stats.LastCommentAt) {
+		createdAt := comment.CreatedAt
+		stats.LastCommentAt = &createdAt
+	}
+
+	authors, exists := r.participants[comment.PostID]
+	if !exists {
+		authors = make(map[string]bool)
+		r.participants[comment.PostID] = authors
+	}
+	if !authors[comment.Author] {
+		authors[comment.Author] = true
+		stats.ParticipantCount++
+	}
+}
+
+// lockedPath проверяет блокировку у всех комментариев пути. Вызывается под блокировкой
+func (r *InMemoryCommentRepository) lockedPath(path string) bool {
+	for _, id := range strings.Split(path, ".") {
+		if comment, exists := r.comments[id]; exists && comment.Locked {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryCommentRepository) SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	next := *comment
+	next.Locked = locked
+	if err := r.record(&next); err != nil {
+		return nil, err
+	}
+	r.comments[id] = &next
+	return clone(&next), nil
+}
+
+func (r *InMemoryCommentRepository) ThreadLocked(ctx context.Context, id string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return false, domain.ErrCommentNotFound
+	}
+	return r.lockedPath(comment.Path), nil
+}
+
+func (r *InMemoryCommentRepository) Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, domain.ErrCommentNotFound
+	}
+	if comment.PinnedAt != nil {
+		return clone(comment), nil
+	}
+
+	pinned := 0
+	for _, c := range r.comments {
+		if c.PostID == comment.PostID && c.PinnedAt != nil {
+			pinned++
+		}
+	}
+	if pinned >= maxPins {
+		return nil, &domain.TooManyPinsError{MaxPins: maxPins}
+	}
+
+	now := time.Now()
+	next := *comment
+	next.PinnedAt = &now
+	if err := r.record(&next); err != nil {
+		return nil, err
+	}
+	r.comments[id] = &next
+	return clone(&next), nil
+}
+
+func (r *InMemoryCommentRepository) Unpin(ctx context.Context, id string) (*domain.Comment, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	next := *comment
+	next.PinnedAt = nil
+	if err := r.record(&next); err != nil {
+		return nil, err
+	}
+	r.comments[id] = &next
+	return clone(&next), nil
+}
+
+func (r *InMemoryCommentRepository) GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := []*domain.Comment{}
+	for _, comment := range r.comments {
+		if comment.PostID == postID && comment.PinnedAt != nil {
+			results = append(results, comment)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].PinnedAt.Before(*results[j].PinnedAt)
+	})
+	return cloneAll(results), nil
+}
+
+func (r *InMemoryCommentRepository) Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	var parent *domain.Comment
+	if parentID != nil {
+		parent, exists = r.comments[*parentID]
+		if !exists {
+			return nil, domain.ErrParentCommentNotFound
+		}
+		postID = parent.PostID
+	} else if !r.postExists(postID) {
+		return nil, domain.ErrPostNotFound
+	}
+
+	newPath, newDepth, err := comment.MoveUnder(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath, oldDepth, oldPostID := comment.Path, comment.Depth, comment.PostID
+	var moved []*domain.Comment
+	visible := 0
+	for _, c := range r.comments {
+		if c.Path == oldPath || strings.HasPrefix(c.Path, oldPath+".") {
+			next := *c
+			next.Path = newPath + strings.TrimPrefix(c.Path, oldPath)
+			next.Depth += newDepth - oldDepth
+			// закрепление не переезжает в другой пост, чтобы не превысить его лимит
+			if c.PostID != postID {
+				next.PinnedAt = nil
+			}
+			next.PostID = postID
+			if c.ID == comment.ID {
+				next.ParentID = parentID
+			}
+
+			moved = append(moved, &next)
+			if !c.Pending {
+				visible++
+			}
+		}
+	}
+	if err := r.record(moved...); err != nil {
+		return nil, err
+	}
+
+	r.shiftCounters(comment, visible, -1)
+	for _, c := range moved {
+		r.comments[c.ID] = c
+	}
+	comment = r.comments[id]
+	r.shiftCounters(comment, visible, 1)
+
+	if oldPostID != postID {
+		r.recountPost(oldPostID)
+		r.recountPost(postID)
+	}
+	return clone(comment), nil
+}
+
+// shiftCounters добавляет ветку из visible видимых комментариев к счетчикам
+// предков комментария (sign = 1) или убирает ее (sign = -1)
+func (r *InMemoryCommentRepository) shiftCounters(comment *domain.Comment, visible int, sign int) {
+	if comment.ParentID == nil {
+		return
+	}
+	if !comment.Pending {
+		r.statsOf(*comment.ParentID).ReplyCount += sign
+	}
+	for _, id := range comment.AncestorIDs() {
+		r.statsOf(id).DescendantCount += sign * visible
+	}
+}
+
+// recountPost пересчитывает счетчики поста по его комментариям
+func (r *InMemoryCommentRepository) recountPost(postID string) {
+	delete(r.postStats, postID)
+	delete(r.participants, postID)
+
+	for _, comment := range r.comments {
+		if comment.PostID == postID && !comment.Pending {
+			r.addToPostStats(comment)
+		}
+	}
+}
+
+func (r *InMemoryCommentRepository) statsOf(commentID string) *domain.CommentStats {
+	stats, exists := r.commentStats[commentID]
+	if !exists {
+		stats = &domain.CommentStats{}
+		r.commentStats[commentID] = stats
+	}
+	return stats
+}
+
+func (r *InMemoryCommentRepository) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats, exists := r.postStats[postID]
+	if !exists {
+		return domain.PostStats{}, nil
+	}
+	return *stats, nil
+}
+
+func (r *InMemoryCommentRepository) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.comments[commentID]; !exists {
+		return domain.CommentStats{}, domain.ErrCommentNotFound
+	}
+	stats, exists := r.commentStats[commentID]
+	if !exists {
+		return domain.CommentStats{}, nil
+	}
+	return *stats, nil
+}
+
 func (r *InMemoryCommentRepository) GetByID(ctx context.Context, id string) (*domain.Comment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -52,7 +461,7 @@ func (r *InMemoryCommentRepository) GetByID(ctx context.Context, id string) (*do
 	if !exists {
 		return nil, domain.ErrCommentNotFound
 	}
-	return comment, nil
+	return clone(comment), nil
 }
 
 func (r *InMemoryCommentRepository) GetByPost(ctx context.Context, postID string, limit, offset int) ([]*domain.Comment, error) {
@@ -61,7 +470,7 @@ func (r *InMemoryCommentRepository) GetByPost(ctx context.Context, postID string
 
 	var results []*domain.Comment
 	for _, comment := range r.comments {
-		if comment.PostID == postID && comment.ParentID == nil {
+		if comment.PostID == postID && comment.ParentID == nil && !comment.Pending {
 			results = append(results, comment)
 		}
 	}
@@ -77,7 +486,7 @@ func (r *InMemoryCommentRepository) GetByPost(ctx context.Context, postID string
 		end = len(results)
 	}
 
-	return results[start:end], nil
+	return cloneAll(results[start:end]), nil
 }
 
 func (r *InMemoryCommentRepository) GetChildren(ctx context.Context, parentID string, limit, offset int) ([]*domain.Comment, error) {
@@ -87,7 +496,7 @@ func (r *InMemoryCommentRepository) GetChildren(ctx context.Context, parentID st
 	var results []*domain.Comment
 
 	for _, comment := range r.comments {
-		if comment.ParentID != nil && *comment.ParentID == parentID {
+		if comment.ParentID != nil && *comment.ParentID == parentID && !comment.Pending {
 			results = append(results, comment)
 		}
 	}
@@ -102,7 +511,128 @@ func (r *InMemoryCommentRepository) GetChildren(ctx context.Context, parentID st
 		end = len(results)
 	}
 
-	return results[start:end], nil
+	return cloneAll(results[start:end]), nil
+}
+
+func (r *InMemoryCommentRepository) ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := []*domain.Comment{}
+	for _, comment := range r.comments {
+		if comment.PostID == postID {
+			results = append(results, comment)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Depth != results[j].Depth {
+			return results[i].Depth < results[j].Depth
+		}
+		return results[i].CreatedAt.Before(results[j].CreatedAt)
+	})
+	return cloneAll(results), nil
+}
+
+func (r *InMemoryCommentRepository) DeleteByPost(ctx context.Context, postID string) error {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.journal != nil {
+		if err := r.journal.append(commentEntry{DeletedPost: postID}); err != nil {
+			return fmt.Errorf("failed write journal %w", err)
+		}
+	}
+
+	for id, comment := range r.comments {
+		if comment.PostID == postID {
+			delete(r.comments, id)
+			delete(r.commentStats, id)
+		}
+	}
+	delete(r.postStats, postID)
+	delete(r.participants, postID)
+	return nil
+}
+
+func (r *InMemoryCommentRepository) GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	ids := comment.AncestorIDs()
+	if len(ids) > depth {
+		ids = ids[len(ids)-depth:]
+	}
+
+	results := make([]*domain.Comment, 0, len(ids)+1)
+	for _, ancestorID := range ids {
+		if ancestor, exists := r.comments[ancestorID]; exists {
+			results = append(results, ancestor)
+		}
+	}
+	return cloneAll(append(results, comment)), nil
+}
+
+func (r *InMemoryCommentRepository) LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var last time.Time
+	for _, comment := range r.comments {
+		if comment.PostID == postID && comment.Author == author && comment.CreatedAt.After(last) {
+			last = comment.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+func (r *InMemoryCommentRepository) Approve(ctx context.Context, id string) (*domain.Comment, bool, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, false, domain.ErrCommentNotFound
+	}
+	if !comment.Pending {
+		return clone(comment), false, nil
+	}
+
+	next := *comment
+	next.Pending = false
+	if err := r.record(&next); err != nil {
+		return nil, false, err
+	}
+	r.comments[id] = &next
+	r.recordVisible(&next)
+	return clone(&next), true, nil
+}
+
+func (r *InMemoryCommentRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]bool, len(authors))
+	for _, comment := range r.comments {
+		found[comment.Author] = true
+	}
+
+	known := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if found[author] {
+			known = append(known, author)
+		}
+	}
+	return known, nil
 }
 
 func sortCommentsByCreatedAt(comments []*domain.Comment) {
@@ -0,0 +1,25 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (repository.PostRepository, repository.CommentRepository) {
+		return NewInMemoryRepositories()
+	})
+}
+
+func TestConformanceStore(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (repository.PostRepository, repository.CommentRepository) {
+		store, err := OpenStore(t.TempDir(), SyncNever)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store.Posts, store.Comments
+	})
+}
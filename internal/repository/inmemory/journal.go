@@ -0,0 +1,222 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SyncPolicy - когда записи журнала сбрасываются на диск
+type SyncPolicy string
+
+const (
+	// SyncAlways - fsync после каждой записи, изменения не теряются
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval - fsync фоном раз в интервал, см. Store.Run
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever - сброс на диск остается на усмотрение ОС
+	SyncNever SyncPolicy = "never"
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown fsync policy %q", s)
+}
+
+// journal - снимок состояния и лог записей, сделанных после него.
+// Каждая запись - строка JSON с новым состоянием измененных объектов,
+// поэтому снимок пишется в том же формате
+type journal struct {
+	mu           sync.Mutex
+	logPath      string
+	snapshotPath string
+	file         *os.File
+	policy       SyncPolicy
+	dirty        bool
+}
+
+func openJournal(dir, name string, policy SyncPolicy) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed create data dir %w", err)
+	}
+
+	j := &journal{
+		logPath:      filepath.Join(dir, name+".log"),
+		snapshotPath: filepath.Join(dir, name+".snapshot"),
+		policy:       policy,
+	}
+
+	file, err := os.OpenFile(j.logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed open journal %w", err)
+	}
+	j.file = file
+	return j, nil
+}
+
+// replay применяет записи снимка, затем лога. Последняя строка лога без
+// перевода строки - след падения посреди записи, она отрезается
+func (j *journal) replay(apply func(data []byte) error) error {
+	snapshot, err := os.Open(j.snapshotPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed open snapshot %w", err)
+	default:
+		_, err = readLines(snapshot, apply)
+		snapshot.Close()
+		if err != nil {
+			return fmt.Errorf("failed read snapshot %w", err)
+		}
+	}
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	valid, err := readLines(j.file, apply)
+	if err != nil {
+		return fmt.Errorf("failed read journal %w", err)
+	}
+	return j.file.Truncate(valid)
+}
+
+// readLines возвращает длину прочитанной части, которая заканчивается целой строкой
+func readLines(r io.Reader, apply func(data []byte) error) (int64, error) {
+	reader := bufio.NewReader(r)
+
+	var valid int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return valid, nil
+		}
+		if err != nil {
+			return valid, err
+		}
+
+		valid += int64(len(line))
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if err := apply(line); err != nil {
+			return valid, err
+		}
+	}
+}
+
+// append пишет записи одним блоком. Если запись не удалась, лог
+// обрезается до прежней длины, чтобы в нем не осталось части блока
+func (j *journal) append(entries ...any) error {
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info, err := j.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(data); err != nil {
+		j.file.Truncate(info.Size())
+		return err
+	}
+	if j.policy == SyncAlways {
+		if err := j.file.Sync(); err != nil {
+			j.file.Truncate(info.Size())
+			return err
+		}
+		return nil
+	}
+	j.dirty = true
+	return nil
+}
+
+func (j *journal) sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.dirty || j.policy == SyncNever {
+		return nil
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// compact записывает новый снимок через временный файл и очищает лог.
+// Вызывающий держит блокировку репозитория, чтобы между снимком
+// и очисткой лога не появилось новых записей
+func (j *journal) compact(write func(emit func(entry any) error) error) error {
+	tmpPath := j.snapshotPath + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed create snapshot %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	err = write(func(entry any) error {
+		return enc.Encode(entry)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed write snapshot %w", err)
+	}
+
+	if err := os.Rename(tmpPath, j.snapshotPath); err != nil {
+		return fmt.Errorf("failed replace snapshot %w", err)
+	}
+	if err := syncDir(filepath.Dir(j.snapshotPath)); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed truncate journal %w", err)
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	if err := j.sync(); err != nil {
+		return err
+	}
+	return j.file.Close()
+}
+
+// syncDir фиксирует переименование снимка в каталоге
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
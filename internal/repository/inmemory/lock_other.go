@@ -0,0 +1,23 @@
+//go:build !unix
+
+package inmemory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockDir без flock только создает файл блокировки: второй процесс
+// на этих платформах не обнаруживается
+func lockDir(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed create data dir %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, "LOCK"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed open lock file %w", err)
+	}
+	return file, nil
+}
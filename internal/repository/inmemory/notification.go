@@ -0,0 +1,88 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+type InMemoryNotificationRepository struct {
+	mu            sync.RWMutex
+	notifications map[string][]*domain.Notification // по получателю
+}
+
+func NewInMemoryNotificationRepository() *InMemoryNotificationRepository {
+	return &InMemoryNotificationRepository{
+		notifications: make(map[string][]*domain.Notification),
+	}
+}
+
+func (r *InMemoryNotificationRepository) Create(ctx context.Context, notification *domain.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications[notification.Recipient] = append(r.notifications[notification.Recipient], notification)
+	return nil
+}
+
+func (r *InMemoryNotificationRepository) List(ctx context.Context, recipient string, filter domain.NotificationFilter) ([]*domain.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := make([]*domain.Notification, 0, filter.Limit)
+	for _, n := range r.notifications[recipient] {
+		if filter.UnreadOnly && n.ReadAt != nil {
+			continue
+		}
+		if filter.After != nil && !filter.After.Before(n) {
+			continue
+		}
+		results = append(results, n)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Cursor().Before(results[j])
+	})
+
+	if len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+	return results, nil
+}
+
+func (r *InMemoryNotificationRepository) CountUnread(ctx context.Context, recipient string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, n := range r.notifications[recipient] {
+		if n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryNotificationRepository) MarkRead(ctx context.Context, recipient string, ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	now := time.Now()
+	marked := 0
+	for _, n := range r.notifications[recipient] {
+		if n.ReadAt != nil || (ids != nil && !wanted[n.ID]) {
+			continue
+		}
+		n.ReadAt = &now
+		marked++
+	}
+	return marked, nil
+}
@@ -2,6 +2,9 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,22 +15,110 @@ import (
 type InMemoryPostRepository struct {
 	mu    sync.RWMutex
 	posts map[string]*domain.Post
+	// votes - голоса по посту и автору
+	votes map[string]map[string]int
+	// journal - nil, если хранилище живет только в памяти процесса
+	journal *journal
+	// units - блокировка для UnitOfWork, общая со связанными комментариями
+	units *units
+	// comments удаляются вместе с постом, см. NewInMemoryRepositories
+	comments *InMemoryCommentRepository
 }
 
 func NewInMemoryPostRepository() *InMemoryPostRepository {
 	return &InMemoryPostRepository{
 		posts: make(map[string]*domain.Post),
+		votes: make(map[string]map[string]int),
+		units: &units{},
 	}
 }
 
+// postEntry - запись журнала: новое состояние поста, его удаление или голос
+type postEntry struct {
+	Post    *domain.Post `json:"post,omitempty"`
+	Deleted string       `json:"deleted,omitempty"`
+	Vote    *voteEntry   `json:"vote,omitempty"`
+}
+
+type voteEntry struct {
+	PostID string `json:"postId"`
+	Voter  string `json:"voter"`
+	Value  int    `json:"value"`
+}
+
+// record пишет изменение в журнал. Вызывается под блокировкой до того,
+// как изменение применено в памяти: если запись не удалась, состояние не меняется
+func (r *InMemoryPostRepository) record(entry postEntry) error {
+	if r.journal == nil {
+		return nil
+	}
+	if err := r.journal.append(entry); err != nil {
+		return fmt.Errorf("failed write journal %w", err)
+	}
+	return nil
+}
+
+func (r *InMemoryPostRepository) apply(data []byte) error {
+	var entry postEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+
+	if entry.Post != nil {
+		r.posts[entry.Post.ID] = entry.Post
+	}
+	if entry.Deleted != "" {
+		delete(r.posts, entry.Deleted)
+		delete(r.votes, entry.Deleted)
+	}
+	if vote := entry.Vote; vote != nil {
+		votes, exists := r.votes[vote.PostID]
+		if !exists {
+			votes = make(map[string]int)
+			r.votes[vote.PostID] = votes
+		}
+		votes[vote.Voter] = vote.Value
+	}
+	return nil
+}
+
+func (r *InMemoryPostRepository) compact() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.journal.compact(func(emit func(entry any) error) error {
+		for _, post := range r.posts {
+			if err := emit(postEntry{Post: post}); err != nil {
+				return err
+			}
+		}
+		for postID, votes := range r.votes {
+			for voter, value := range votes {
+				if err := emit(postEntry{Vote: &voteEntry{PostID: postID, Voter: voter, Value: value}}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
 func (r *InMemoryPostRepository) Create(ctx context.Context, post *domain.Post) error {
+	defer r.units.hold(ctx)()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if post.ID == "" {
 		post.ID = utils.GenerateID()
 	}
-	post.CreatedAt = time.Now()
+	// время уже задано у постов, восстановленных из архива
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+	if err := r.record(postEntry{Post: post}); err != nil {
+		return err
+	}
 	r.posts[post.ID] = post
 	return nil
 }
@@ -43,18 +134,50 @@ func (r *InMemoryPostRepository) GetByID(ctx context.Context, id string) (*domai
 	return post, nil
 }
 
-func (r *InMemoryPostRepository) GetAll(ctx context.Context) ([]*domain.Post, error) {
+func (r *InMemoryPostRepository) GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	posts := make([]*domain.Post, 0, len(r.posts))
 	for _, post := range r.posts {
+		if filter.Status != "" && post.Status != filter.Status {
+			continue
+		}
+		if filter.Author != "" && post.Author != filter.Author {
+			continue
+		}
+		if !post.HasTags(filter.Tags, filter.TagMatch) {
+			continue
+		}
 		posts = append(posts, post)
 	}
 	return posts, nil
 }
 
+func (r *InMemoryPostRepository) PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var published []*domain.Post
+	for _, post := range r.posts {
+		if post.Status == domain.PostScheduled && post.PublishAt != nil && !post.PublishAt.After(now) {
+			next := *post
+			next.Status = domain.PostPublished
+			if err := r.record(postEntry{Post: &next}); err != nil {
+				return published, err
+			}
+			*post = next
+			published = append(published, post)
+		}
+	}
+	return published, nil
+}
+
 func (r *InMemoryPostRepository) ToggleComments(ctx context.Context, postID string, allow bool) error {
+	defer r.units.hold(ctx)()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -63,23 +186,53 @@ func (r *InMemoryPostRepository) ToggleComments(ctx context.Context, postID stri
 		return domain.ErrPostNotFound
 	}
 
-	post.AllowComments = allow
+	next := *post
+	next.AllowComments = allow
+	if err := r.record(postEntry{Post: &next}); err != nil {
+		return err
+	}
+	*post = next
 	return nil
 }
 
 func (r *InMemoryPostRepository) Update(ctx context.Context, post *domain.Post) error {
+	defer r.units.hold(ctx)()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	_, exists := r.posts[post.ID]
+	existing, exists := r.posts[post.ID]
 	if !exists {
 		return domain.ErrPostNotFound
 	}
+	// голоса меняются только через Vote, настройки - через UpdateSettings
+	post.VoteScore = existing.VoteScore
+	post.Settings = existing.Settings
+	post.CreatedAt = existing.CreatedAt
+	if err := r.record(postEntry{Post: post}); err != nil {
+		return err
+	}
 	r.posts[post.ID] = post
 	return nil
 }
 
 func (r *InMemoryPostRepository) Delete(ctx context.Context, postID string) error {
+	ctx, done := r.units.enter(ctx)
+	defer done()
+
+	if err := r.delete(postID); err != nil {
+		return err
+	}
+
+	// комментарии удаляются после снятия блокировки постов: создание
+	// комментария берет блокировки в обратном порядке
+	if r.comments == nil {
+		return nil
+	}
+	return r.comments.DeleteByPost(ctx, postID)
+}
+
+func (r *InMemoryPostRepository) delete(postID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -87,10 +240,22 @@ func (r *InMemoryPostRepository) Delete(ctx context.Context, postID string) erro
 	if !exists {
 		return domain.ErrPostNotFound
 	}
+	if err := r.record(postEntry{Deleted: postID}); err != nil {
+		return err
+	}
 	delete(r.posts, postID)
+	delete(r.votes, postID)
 	return nil
 }
 
+func (r *InMemoryPostRepository) exists(postID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.posts[postID]
+	return exists
+}
+
 func (r *InMemoryPostRepository) CheckAllowedComments(ctx context.Context, postID string) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -101,3 +266,100 @@ func (r *InMemoryPostRepository) CheckAllowedComments(ctx context.Context, postI
 	}
 	return post.AllowComments, nil
 }
+
+func (r *InMemoryPostRepository) UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, exists := r.posts[postID]
+	if !exists {
+		return domain.ErrPostNotFound
+	}
+
+	next := *post
+	next.Settings = settings
+	if err := r.record(postEntry{Post: &next}); err != nil {
+		return err
+	}
+	*post = next
+	return nil
+}
+
+func (r *InMemoryPostRepository) Vote(ctx context.Context, postID, voter string, value int) (int, error) {
+	defer r.units.hold(ctx)()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, exists := r.posts[postID]
+	if !exists {
+		return 0, domain.ErrPostNotFound
+	}
+
+	votes, exists := r.votes[postID]
+	if !exists {
+		votes = make(map[string]int)
+		r.votes[postID] = votes
+	}
+
+	delta := value - votes[voter]
+	next := *post
+	next.VoteScore += delta
+	if err := r.record(postEntry{Post: &next, Vote: &voteEntry{PostID: postID, Voter: voter, Value: value}}); err != nil {
+		return 0, err
+	}
+	votes[voter] = value
+	*post = next
+	return delta, nil
+}
+
+func (r *InMemoryPostRepository) ListTags(ctx context.Context, limit int) ([]*domain.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, post := range r.posts {
+		if post.Status != domain.PostPublished {
+			continue
+		}
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]*domain.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &domain.Tag{Name: name, PostCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func (r *InMemoryPostRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]bool, len(authors))
+	for _, post := range r.posts {
+		found[post.Author] = true
+	}
+
+	known := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if found[author] {
+			known = append(known, author)
+		}
+	}
+	return known, nil
+}
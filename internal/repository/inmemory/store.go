@@ -0,0 +1,128 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Store - постоянный режим хранилища в памяти для одного узла: изменения
+// постов и комментариев пишутся в журналы в каталоге, периодически
+// сжимаются в снимки и воспроизводятся при старте
+type Store struct {
+	Posts    *InMemoryPostRepository
+	Comments *InMemoryCommentRepository
+
+	// lock не дает второму процессу открыть тот же каталог
+	lock *os.File
+}
+
+var ErrStoreLocked = errors.New("data directory is used by another process")
+
+// NewInMemoryRepositories возвращает связанные репозитории, которые ведут
+// себя как таблицы с внешними ключами: комментарий нельзя создать
+// к несуществующему посту, удаление поста удаляет его комментарии
+func NewInMemoryRepositories() (*InMemoryPostRepository, *InMemoryCommentRepository) {
+	posts := NewInMemoryPostRepository()
+	comments := NewInMemoryCommentRepository()
+	posts.comments = comments
+	comments.posts = posts
+	comments.units = posts.units
+	return posts, comments
+}
+
+// OpenStore открывает каталог данных. Если его уже держит другой процесс,
+// возвращает ErrStoreLocked: иначе сжатие журналов одним процессом
+// стерло бы записи другого
+func OpenStore(dir string, policy SyncPolicy) (*Store, error) {
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openStore(dir, policy)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	store.lock = lock
+	return store, nil
+}
+
+func openStore(dir string, policy SyncPolicy) (*Store, error) {
+	posts, comments := NewInMemoryRepositories()
+
+	postJournal, err := openJournal(dir, "posts", policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := postJournal.replay(posts.apply); err != nil {
+		postJournal.close()
+		return nil, fmt.Errorf("failed load posts %w", err)
+	}
+
+	commentJournal, err := openJournal(dir, "comments", policy)
+	if err != nil {
+		postJournal.close()
+		return nil, err
+	}
+	if err := commentJournal.replay(comments.apply); err != nil {
+		postJournal.close()
+		commentJournal.close()
+		return nil, fmt.Errorf("failed load comments %w", err)
+	}
+	// падение между удалением поста и его комментариев оставляет сирот
+	comments.dropOrphans()
+	comments.rebuildStats()
+
+	posts.journal = postJournal
+	comments.journal = commentJournal
+	return &Store{Posts: posts, Comments: comments}, nil
+}
+
+// Run сбрасывает журналы на диск раз в syncInterval (для SyncInterval)
+// и сжимает их в снимки раз в snapshotInterval, пока не отменен ctx
+func (s *Store) Run(ctx context.Context, syncInterval, snapshotInterval time.Duration) {
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	snapshotTicker := time.NewTicker(snapshotInterval)
+	defer snapshotTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncTicker.C:
+			if err := s.Sync(); err != nil {
+				log.Printf("failed sync journal %v", err)
+			}
+		case <-snapshotTicker.C:
+			if err := s.Compact(); err != nil {
+				log.Printf("failed compact journal %v", err)
+			}
+		}
+	}
+}
+
+func (s *Store) Sync() error {
+	return errors.Join(s.Posts.journal.sync(), s.Comments.journal.sync())
+}
+
+// Compact записывает снимки текущего состояния и очищает журналы
+func (s *Store) Compact() error {
+	return errors.Join(s.Posts.compact(), s.Comments.compact())
+}
+
+// Close сжимает журналы, чтобы следующий старт не воспроизводил весь лог,
+// и освобождает каталог
+func (s *Store) Close() error {
+	err := s.Compact()
+	err = errors.Join(err, s.Posts.journal.close(), s.Comments.journal.close())
+	if s.lock != nil {
+		err = errors.Join(err, s.lock.Close())
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package inmemory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := OpenStore(dir, SyncAlways)
+	require.NoError(t, err)
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	require.NoError(t, store.Posts.Create(ctx, post))
+	_, err = store.Posts.Vote(ctx, post.ID, "b", 1)
+	require.NoError(t, err)
+
+	root, err := domain.NewComment(post.ID, "b", nil, "text")
+	require.NoError(t, err)
+	require.NoError(t, store.Comments.Create(ctx, root))
+	reply, err := domain.NewComment(post.ID, "c", &root.ID, "text")
+	require.NoError(t, err)
+	require.NoError(t, store.Comments.Create(ctx, reply))
+	_, err = store.Comments.SetLocked(ctx, root.ID, true)
+	require.NoError(t, err)
+
+	check := func(t *testing.T, store *Store) {
+		restored, err := store.Posts.GetByID(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, restored.VoteScore)
+
+		// повторный голос того же автора не меняет сумму
+		delta, err := store.Posts.Vote(ctx, post.ID, "b", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, delta)
+
+		comment, err := store.Comments.GetByID(ctx, reply.ID)
+		require.NoError(t, err)
+		assert.Equal(t, root.ID+"."+reply.ID, comment.Path)
+		assert.Equal(t, 1, comment.Depth)
+
+		locked, err := store.Comments.ThreadLocked(ctx, reply.ID)
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		postStats, err := store.Comments.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, postStats.CommentCount)
+		assert.Equal(t, 2, postStats.ParticipantCount)
+		commentStats, err := store.Comments.CommentStats(ctx, root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 1}, commentStats)
+	}
+
+	t.Run("replay log", func(t *testing.T) {
+		// закрытие без Close имитирует падение процесса
+		require.NoError(t, store.Posts.journal.file.Close())
+		require.NoError(t, store.Comments.journal.file.Close())
+		require.NoError(t, store.lock.Close())
+
+		store, err = OpenStore(dir, SyncAlways)
+		require.NoError(t, err)
+		check(t, store)
+	})
+
+	t.Run("replay snapshot", func(t *testing.T) {
+		require.NoError(t, store.Close())
+
+		info, err := os.Stat(filepath.Join(dir, "comments.log"))
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+
+		store, err = OpenStore(dir, SyncAlways)
+		require.NoError(t, err)
+		check(t, store)
+	})
+
+	t.Run("torn last record is dropped", func(t *testing.T) {
+		deleted, err := domain.NewPost("deleted", "c", "a", true)
+		require.NoError(t, err)
+		require.NoError(t, store.Posts.Create(ctx, deleted))
+		require.NoError(t, store.Posts.journal.file.Close())
+		require.NoError(t, store.Comments.journal.file.Close())
+		require.NoError(t, store.lock.Close())
+
+		logPath := filepath.Join(dir, "posts.log")
+		file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"deleted":"` + deleted.ID)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		store, err = OpenStore(dir, SyncAlways)
+		require.NoError(t, err)
+		defer store.Close()
+
+		_, err = store.Posts.GetByID(ctx, deleted.ID)
+		assert.NoError(t, err)
+		check(t, store)
+	})
+}
+
+func TestStore_JournalFailure(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := OpenStore(t.TempDir(), SyncAlways)
+	require.NoError(t, err)
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	require.NoError(t, store.Posts.Create(ctx, post))
+	root, err := domain.NewComment(post.ID, "b", nil, "text")
+	require.NoError(t, err)
+	require.NoError(t, store.Comments.Create(ctx, root))
+
+	// запись в закрытый журнал не проходит, и изменение не должно появиться в памяти
+	require.NoError(t, store.Posts.journal.file.Close())
+	require.NoError(t, store.Comments.journal.file.Close())
+
+	other, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	assert.Error(t, store.Posts.Create(ctx, other))
+	_, err = store.Posts.GetByID(ctx, other.ID)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+
+	assert.Error(t, store.Posts.ToggleComments(ctx, post.ID, false))
+	_, err = store.Posts.Vote(ctx, post.ID, "b", 1)
+	assert.Error(t, err)
+	assert.Error(t, store.Posts.Delete(ctx, post.ID))
+	got, err := store.Posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.True(t, got.AllowComments)
+	assert.Equal(t, 0, got.VoteScore)
+
+	reply, err := domain.NewComment(post.ID, "c", &root.ID, "text")
+	require.NoError(t, err)
+	assert.Error(t, store.Comments.Create(ctx, reply))
+	_, err = store.Comments.GetByID(ctx, reply.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+	_, err = store.Comments.SetLocked(ctx, root.ID, true)
+	assert.Error(t, err)
+	assert.False(t, root.Locked)
+	stats, err := store.Comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.CommentCount)
+}
+
+func TestStore_Locked(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := OpenStore(dir, SyncNever)
+	require.NoError(t, err)
+
+	_, err = OpenStore(dir, SyncNever)
+	assert.ErrorIs(t, err, ErrStoreLocked)
+
+	require.NoError(t, store.Close())
+	reopened, err := OpenStore(dir, SyncNever)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+}
@@ -0,0 +1,65 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+// units - общая блокировка связанных репозиториев. Единица работы берет ее
+// целиком, отдельные изменения - на чтение, поэтому они не попадают
+// внутрь единицы работы, но не мешают друг другу
+type units struct {
+	mu sync.RWMutex
+}
+
+type unitKey struct{}
+
+// enter ждет конца чужой единицы работы. Вызовы с ctx, который уже
+// держит блокировку, выполняются сразу
+func (u *units) enter(ctx context.Context) (context.Context, func()) {
+	if ctx.Value(unitKey{}) == u {
+		return ctx, func() {}
+	}
+
+	u.mu.RLock()
+	return context.WithValue(ctx, unitKey{}, u), u.mu.RUnlock
+}
+
+func (u *units) hold(ctx context.Context) func() {
+	_, done := u.enter(ctx)
+	return done
+}
+
+// UnitOfWork для хранилища в памяти. Изменения не откатываются,
+// поэтому запись в единице работы должна идти после всех проверок
+type UnitOfWork struct {
+	units *units
+}
+
+// NewUnitOfWork возвращает единицу работы над posts и связанными с ними
+// комментариями, см. NewInMemoryRepositories
+func NewUnitOfWork(posts *InMemoryPostRepository) *UnitOfWork {
+	return &UnitOfWork{units: posts.units}
+}
+
+func (w *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(unitKey{}) == w.units {
+		return fn(ctx)
+	}
+
+	ctx, committed := repository.WithAfterCommit(ctx)
+	err := func() error {
+		w.units.mu.Lock()
+		defer w.units.mu.Unlock()
+
+		return fn(context.WithValue(ctx, unitKey{}, w.units))
+	}()
+	if err != nil {
+		return err
+	}
+
+	committed()
+	return nil
+}
@@ -0,0 +1,162 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+// InMemoryWebhookRepository отдает копии доставок, чтобы воркер
+// не менял их в обход UpdateDelivery
+type InMemoryWebhookRepository struct {
+	mu         sync.RWMutex
+	webhooks   map[string]*domain.Webhook
+	deliveries map[string]domain.WebhookDelivery
+}
+
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		webhooks:   make(map[string]*domain.Webhook),
+		deliveries: make(map[string]domain.WebhookDelivery),
+	}
+}
+
+func (r *InMemoryWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, exists := r.webhooks[id]
+	if !exists {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (r *InMemoryWebhookRepository) List(ctx context.Context, owner string) ([]*domain.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]*domain.Webhook, 0)
+	for _, webhook := range r.webhooks {
+		if webhook.Owner == owner {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+func (r *InMemoryWebhookRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[id]; !exists {
+		return domain.ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		r.deliveries[delivery.ID] = *delivery
+	}
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status != domain.DeliveryPending && delivery.Status != domain.DeliveryFailed {
+			continue
+		}
+		if delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		d := delivery
+		due = append(due, &d)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, d := range due {
+		stored := r.deliveries[d.ID]
+		stored.NextAttemptAt = now.Add(lease)
+		r.deliveries[d.ID] = stored
+	}
+	return due, nil
+}
+
+func (r *InMemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[delivery.ID]; !exists {
+		return domain.ErrDeliveryNotFound
+	}
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+func (r *InMemoryWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, status *domain.DeliveryStatus, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []*domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.WebhookID != webhookID || (status != nil && delivery.Status != *status) {
+			continue
+		}
+		d := delivery
+		deliveries = append(deliveries, &d)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 	domain "github.com/tmozzze/SasPosts/internal/domain"
@@ -14,55 +15,64 @@ type CommentRepository struct {
 	mock.Mock
 }
 
-// CountByPost provides a mock function with given fields: ctx, postID
-func (_m *CommentRepository) CountByPost(ctx context.Context, postID string) (int, error) {
-	ret := _m.Called(ctx, postID)
+// Approve provides a mock function with given fields: ctx, id
+func (_m *CommentRepository) Approve(ctx context.Context, id string) (*domain.Comment, bool, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CountByPost")
+		panic("no return value specified for Approve")
 	}
 
-	var r0 int
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
-		return rf(ctx, postID)
+	var r0 *domain.Comment
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.Comment, bool, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
-		r0 = rf(ctx, postID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.Comment); ok {
+		r0 = rf(ctx, id)
 	} else {
-		r0 = ret.Get(0).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, postID)
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, id)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(bool)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, id)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// CountChildren provides a mock function with given fields: ctx, parentID
-func (_m *CommentRepository) CountChildren(ctx context.Context, parentID string) (int, error) {
-	ret := _m.Called(ctx, parentID)
+// CommentStats provides a mock function with given fields: ctx, commentID
+func (_m *CommentRepository) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	ret := _m.Called(ctx, commentID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CountChildren")
+		panic("no return value specified for CommentStats")
 	}
 
-	var r0 int
+	var r0 domain.CommentStats
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
-		return rf(ctx, parentID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.CommentStats, error)); ok {
+		return rf(ctx, commentID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
-		r0 = rf(ctx, parentID)
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.CommentStats); ok {
+		r0 = rf(ctx, commentID)
 	} else {
-		r0 = ret.Get(0).(int)
+		r0 = ret.Get(0).(domain.CommentStats)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, parentID)
+		r1 = rf(ctx, commentID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -88,6 +98,24 @@ func (_m *CommentRepository) Create(ctx context.Context, comment *domain.Comment
 	return r0
 }
 
+// DeleteByPost provides a mock function with given fields: ctx, postID
+func (_m *CommentRepository) DeleteByPost(ctx context.Context, postID string) error {
+	ret := _m.Called(ctx, postID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByPost")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, postID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetByID provides a mock function with given fields: ctx, id
 func (_m *CommentRepository) GetByID(ctx context.Context, id string) (*domain.Comment, error) {
 	ret := _m.Called(ctx, id)
@@ -178,6 +206,330 @@ func (_m *CommentRepository) GetChildren(ctx context.Context, parentID string, l
 	return r0, r1
 }
 
+// GetPinned provides a mock function with given fields: ctx, postID
+func (_m *CommentRepository) GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	ret := _m.Called(ctx, postID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPinned")
+	}
+
+	var r0 []*domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*domain.Comment, error)); ok {
+		return rf(ctx, postID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*domain.Comment); ok {
+		r0 = rf(ctx, postID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, postID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWithAncestors provides a mock function with given fields: ctx, id, depth
+func (_m *CommentRepository) GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error) {
+	ret := _m.Called(ctx, id, depth)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWithAncestors")
+	}
+
+	var r0 []*domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*domain.Comment, error)); ok {
+		return rf(ctx, id, depth)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*domain.Comment); ok {
+		r0 = rf(ctx, id, depth)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, id, depth)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// KnownAuthors provides a mock function with given fields: ctx, authors
+func (_m *CommentRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	ret := _m.Called(ctx, authors)
+
+	if len(ret) == 0 {
+		panic("no return value specified for KnownAuthors")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, authors)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, authors)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, authors)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastCommentTime provides a mock function with given fields: ctx, postID, author
+func (_m *CommentRepository) LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error) {
+	ret := _m.Called(ctx, postID, author)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LastCommentTime")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (time.Time, error)); ok {
+		return rf(ctx, postID, author)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) time.Time); ok {
+		r0 = rf(ctx, postID, author)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, postID, author)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByPost provides a mock function with given fields: ctx, postID
+func (_m *CommentRepository) ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	ret := _m.Called(ctx, postID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByPost")
+	}
+
+	var r0 []*domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*domain.Comment, error)); ok {
+		return rf(ctx, postID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*domain.Comment); ok {
+		r0 = rf(ctx, postID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, postID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Move provides a mock function with given fields: ctx, id, parentID, postID
+func (_m *CommentRepository) Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error) {
+	ret := _m.Called(ctx, id, parentID, postID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Move")
+	}
+
+	var r0 *domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *string, string) (*domain.Comment, error)); ok {
+		return rf(ctx, id, parentID, postID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *string, string) *domain.Comment); ok {
+		r0 = rf(ctx, id, parentID, postID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *string, string) error); ok {
+		r1 = rf(ctx, id, parentID, postID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Pin provides a mock function with given fields: ctx, id, maxPins
+func (_m *CommentRepository) Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error) {
+	ret := _m.Called(ctx, id, maxPins)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Pin")
+	}
+
+	var r0 *domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*domain.Comment, error)); ok {
+		return rf(ctx, id, maxPins)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *domain.Comment); ok {
+		r0 = rf(ctx, id, maxPins)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, id, maxPins)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PostStats provides a mock function with given fields: ctx, postID
+func (_m *CommentRepository) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	ret := _m.Called(ctx, postID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PostStats")
+	}
+
+	var r0 domain.PostStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.PostStats, error)); ok {
+		return rf(ctx, postID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.PostStats); ok {
+		r0 = rf(ctx, postID)
+	} else {
+		r0 = ret.Get(0).(domain.PostStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, postID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetLocked provides a mock function with given fields: ctx, id, locked
+func (_m *CommentRepository) SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error) {
+	ret := _m.Called(ctx, id, locked)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetLocked")
+	}
+
+	var r0 *domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) (*domain.Comment, error)); ok {
+		return rf(ctx, id, locked)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) *domain.Comment); ok {
+		r0 = rf(ctx, id, locked)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, id, locked)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ThreadLocked provides a mock function with given fields: ctx, id
+func (_m *CommentRepository) ThreadLocked(ctx context.Context, id string) (bool, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ThreadLocked")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Unpin provides a mock function with given fields: ctx, id
+func (_m *CommentRepository) Unpin(ctx context.Context, id string) (*domain.Comment, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unpin")
+	}
+
+	var r0 *domain.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*domain.Comment, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *domain.Comment); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewCommentRepository creates a new instance of CommentRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCommentRepository(t interface {
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 	domain "github.com/tmozzze/SasPosts/internal/domain"
@@ -78,9 +79,9 @@ func (_m *PostRepository) Delete(ctx context.Context, postID string) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: ctx
-func (_m *PostRepository) GetAll(ctx context.Context) ([]*domain.Post, error) {
-	ret := _m.Called(ctx)
+// GetAll provides a mock function with given fields: ctx, filter
+func (_m *PostRepository) GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error) {
+	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
@@ -88,19 +89,19 @@ func (_m *PostRepository) GetAll(ctx context.Context) ([]*domain.Post, error) {
 
 	var r0 []*domain.Post
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]*domain.Post, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostFilter) ([]*domain.Post, error)); ok {
+		return rf(ctx, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []*domain.Post); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.PostFilter) []*domain.Post); ok {
+		r0 = rf(ctx, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*domain.Post)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.PostFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -138,6 +139,96 @@ func (_m *PostRepository) GetByID(ctx context.Context, id string) (*domain.Post,
 	return r0, r1
 }
 
+// KnownAuthors provides a mock function with given fields: ctx, authors
+func (_m *PostRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	ret := _m.Called(ctx, authors)
+
+	if len(ret) == 0 {
+		panic("no return value specified for KnownAuthors")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, authors)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, authors)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, authors)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTags provides a mock function with given fields: ctx, limit
+func (_m *PostRepository) ListTags(ctx context.Context, limit int) ([]*domain.Tag, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTags")
+	}
+
+	var r0 []*domain.Tag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*domain.Tag, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*domain.Tag); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Tag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublishDue provides a mock function with given fields: ctx, now
+func (_m *PostRepository) PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error) {
+	ret := _m.Called(ctx, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishDue")
+	}
+
+	var r0 []*domain.Post
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]*domain.Post, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []*domain.Post); ok {
+		r0 = rf(ctx, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ToggleComments provides a mock function with given fields: ctx, postID, allow
 func (_m *PostRepository) ToggleComments(ctx context.Context, postID string, allow bool) error {
 	ret := _m.Called(ctx, postID, allow)
@@ -174,6 +265,52 @@ func (_m *PostRepository) Update(ctx context.Context, post *domain.Post) error {
 	return r0
 }
 
+// UpdateSettings provides a mock function with given fields: ctx, postID, settings
+func (_m *PostRepository) UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error {
+	ret := _m.Called(ctx, postID, settings)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSettings")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.ThreadSettings) error); ok {
+		r0 = rf(ctx, postID, settings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Vote provides a mock function with given fields: ctx, postID, voter, value
+func (_m *PostRepository) Vote(ctx context.Context, postID string, voter string, value int) (int, error) {
+	ret := _m.Called(ctx, postID, voter, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Vote")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) (int, error)); ok {
+		return rf(ctx, postID, voter, value)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) int); ok {
+		r0 = rf(ctx, postID, voter, value)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, postID, voter, value)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewPostRepository creates a new instance of PostRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewPostRepository(t interface {
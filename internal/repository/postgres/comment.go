@@ -3,14 +3,71 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tmozzze/SasPosts/internal/domain"
 )
 
+// foreignKeyViolation - код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+const commentColumns = `id, post_id, parent_id, author, content, path, depth, created_at, pending, mentions, locked, pinned_at`
+
+func scanComment(row rowScanner) (*domain.Comment, error) {
+	var comment domain.Comment
+	var scannedParentID sql.NullString
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&scannedParentID,
+		&comment.Author,
+		&comment.Content,
+		&comment.Path,
+		&comment.Depth,
+		&comment.CreatedAt,
+		&comment.Pending,
+		&comment.Mentions,
+		&comment.Locked,
+		&comment.PinnedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if scannedParentID.Valid {
+		comment.ParentID = &scannedParentID.String
+	}
+
+	return &comment, nil
+}
+
+func scanComments(rows pgx.Rows) ([]*domain.Comment, error) {
+	defer rows.Close()
+
+	var comments []*domain.Comment
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan comment %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return comments, nil
+}
+
 type PostgresCommentRepository struct {
 	db *pgxpool.Pool
 }
@@ -24,41 +81,88 @@ func (r *PostgresCommentRepository) Create(ctx context.Context, comment *domain.
 		return domain.ErrCommentTooLong
 	}
 
-	if comment.ParentID == nil {
-		comment.Depth = 0
-		comment.Path = comment.ID
-	} else {
-		var parentPath string
-		var parentDepth int
-
-		query := `SELECT path, depth FROM comments WHERE id = $1`
-		err := r.db.QueryRow(ctx, query, *comment.ParentID).Scan(&parentPath, &parentDepth)
-		if err != nil {
+	insertQuery := `INSERT INTO comments (` + commentColumns + `)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	// nil сохранился бы как JSON null
+	mentions := comment.Mentions
+	if mentions == nil {
+		mentions = []domain.Mention{}
+	}
+
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		if comment.ParentID == nil {
+			comment.Depth = 0
+			comment.Path = comment.ID
+		} else {
+			var parentPath string
+			var parentDepth int
+			var locked bool
+
+			// перенос ветки берет пост FOR UPDATE: ответ ждет его конца,
+			// иначе UPDATE по префиксу пути не увидит новую строку
+			if err := lockPosts(ctx, tx, "FOR SHARE", comment.PostID); err != nil {
+				return err
+			}
+
+			// родитель читается в транзакции вставки и блокируется,
+			// чтобы его не перенесли и не удалили до коммита.
+			// Блокировка ветки ищется по id из пути, то есть по первичному ключу
+			query := `SELECT p.path, p.depth, EXISTS (
+						  SELECT 1 FROM comments a WHERE a.id = ANY(string_to_array(p.path, '.')) AND a.locked
+					  )
+					  FROM comments p WHERE p.id = $1
+					  FOR SHARE OF p`
+			err := tx.QueryRow(ctx, query, *comment.ParentID).Scan(&parentPath, &parentDepth, &locked)
 			if err == pgx.ErrNoRows {
 				return domain.ErrParentCommentNotFound
 			}
-			return fmt.Errorf("failed get parent comment %w", err)
-		}
+			if err != nil {
+				return err
+			}
+			if locked {
+				return domain.ErrThreadLocked
+			}
 
-		comment.Depth = parentDepth + 1
-		comment.Path = parentPath + "." + comment.ID
-	}
+			comment.Depth = parentDepth + 1
+			comment.Path = parentPath + "." + comment.ID
+		}
 
-	insertQuery := `INSERT INTO comments (id, post_id, parent_id, author, content, path, depth, created_at)
-					VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		_, err := tx.Exec(ctx, insertQuery,
+			comment.ID,
+			comment.PostID,
+			comment.ParentID,
+			comment.Author,
+			comment.Content,
+			comment.Path,
+			comment.Depth,
+			comment.CreatedAt,
+			comment.Pending,
+			mentions,
+			comment.Locked,
+			comment.PinnedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	_, err := r.db.Exec(ctx, insertQuery,
-		comment.ID,
-		comment.PostID,
-		comment.ParentID,
-		comment.Author,
-		comment.Content,
-		comment.Path,
-		comment.Depth,
-		comment.CreatedAt,
-	)
+		if comment.Pending {
+			return nil
+		}
+		return recordVisible(ctx, tx, comment)
+	})
 
 	if err != nil {
+		if errors.Is(err, domain.ErrParentCommentNotFound) ||
+			errors.Is(err, domain.ErrThreadLocked) ||
+			errors.Is(err, domain.ErrPostNotFound) {
+			return err
+		}
+		// пост могли удалить между проверкой и вставкой
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return domain.ErrPostNotFound
+		}
 		return fmt.Errorf("failed create comment %w", err)
 	}
 
@@ -66,23 +170,9 @@ func (r *PostgresCommentRepository) Create(ctx context.Context, comment *domain.
 }
 
 func (r *PostgresCommentRepository) GetByID(ctx context.Context, id string) (*domain.Comment, error) {
-	query := `SELECT id, post_id, parent_id, author, content, path, depth, created_at
-			  FROM comments WHERE id = $1`
-
-	var comment domain.Comment
-	var scannedParentID sql.NullString
-
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&comment.ID,
-		&comment.PostID,
-		&scannedParentID,
-		&comment.Author,
-		&comment.Content,
-		&comment.Path,
-		&comment.Depth,
-		&comment.CreatedAt,
-	)
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1`
 
+	comment, err := scanComment(conn(ctx, r.db).QueryRow(ctx, query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, domain.ErrCommentNotFound
@@ -90,92 +180,58 @@ func (r *PostgresCommentRepository) GetByID(ctx context.Context, id string) (*do
 		return nil, fmt.Errorf("failed get comment by id %w", err)
 	}
 
-	if scannedParentID.Valid {
-		comment.ParentID = &scannedParentID.String
-	}
-
-	return &comment, nil
+	return comment, nil
 }
 
 func (r *PostgresCommentRepository) GetByPost(ctx context.Context, postID string, limit int, offset int) ([]*domain.Comment, error) {
-	query := `SELECT id, post_id, parent_id, author, content, path, depth, created_at
-			  FROM comments WHERE post_id = $1 AND parent_id IS NULL
+	query := `SELECT ` + commentColumns + `
+			  FROM comments WHERE post_id = $1 AND parent_id IS NULL AND NOT pending
 			  ORDER BY created_at ASC
 			  LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(ctx, query, postID, limit, offset)
+	rows, err := conn(ctx, r.db).Query(ctx, query, postID, limit, offset)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed get comments by post %w", err)
 	}
-	defer rows.Close()
-
-	var comments []*domain.Comment
-
-	for rows.Next() {
-		var comment domain.Comment
-		var scannedParentID sql.NullString
-
-		if err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&scannedParentID,
-			&comment.Author,
-			&comment.Content,
-			&comment.Path,
-			&comment.Depth,
-			&comment.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed scan comment %w", err)
-		}
-
-		if scannedParentID.Valid {
-			comment.ParentID = &scannedParentID.String
-		}
 
-		comments = append(comments, &comment)
-	}
-
-	return comments, nil
+	return scanComments(rows)
 }
 
 func (r *PostgresCommentRepository) GetChildren(ctx context.Context, parentID string, limit int, offset int) ([]*domain.Comment, error) {
-	query := `SELECT id, post_id, parent_id, author, content, path, depth, created_at
-			  FROM comments WHERE parent_id = $1
+	query := `SELECT ` + commentColumns + `
+			  FROM comments WHERE parent_id = $1 AND NOT pending
 			  ORDER BY created_at ASC
 			  LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(ctx, query, parentID, limit, offset)
+	rows, err := conn(ctx, r.db).Query(ctx, query, parentID, limit, offset)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed get comments by post %w", err)
+		return nil, fmt.Errorf("failed get children comments %w", err)
 	}
-	defer rows.Close()
 
-	var comments []*domain.Comment
+	return scanComments(rows)
+}
 
-	for rows.Next() {
-		var comment domain.Comment
-		var scannedParentID sql.NullString
-
-		if err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&scannedParentID,
-			&comment.Author,
-			&comment.Content,
-			&comment.Path,
-			&comment.Depth,
-			&comment.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed scan comment %w", err)
-		}
+func (r *PostgresCommentRepository) GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error) {
+	// предки берутся из материализованного пути комментария
+	query := `WITH target AS (SELECT path AS target_path, depth AS target_depth FROM comments WHERE id = $1)
+			  SELECT ` + commentColumns + `
+			  FROM comments, target
+			  WHERE id = ANY(string_to_array(target_path, '.')) AND depth >= target_depth - $2
+			  ORDER BY depth ASC`
 
-		if scannedParentID.Valid {
-			comment.ParentID = &scannedParentID.String
-		}
+	rows, err := conn(ctx, r.db).Query(ctx, query, id, depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed get comment ancestors %w", err)
+	}
 
-		comments = append(comments, &comment)
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, domain.ErrCommentNotFound
 	}
 
 	return comments, nil
@@ -186,7 +242,7 @@ func (r *PostgresCommentRepository) CountByPost(ctx context.Context, postID stri
 
 	var count int
 
-	err := r.db.QueryRow(ctx, query, postID).Scan(&count)
+	err := conn(ctx, r.db).QueryRow(ctx, query, postID).Scan(&count)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed count comments by post %w", err)
@@ -200,10 +256,484 @@ func (r *PostgresCommentRepository) CountChildren(ctx context.Context, parentID
 
 	var count int
 
-	err := r.db.QueryRow(ctx, query, parentID).Scan(&count)
+	err := conn(ctx, r.db).QueryRow(ctx, query, parentID).Scan(&count)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed count comments by children %w", err)
 	}
 	return count, nil
 }
+
+// slowModeLockClass - пространство advisory-блокировок медленного режима,
+// ключи из двух int4 не пересекаются с ключом планировщика
+const slowModeLockClass int32 = 1
+
+func (r *PostgresCommentRepository) LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error) {
+	query := `SELECT MAX(created_at) FROM comments WHERE post_id = $1 AND author = $2`
+
+	db := conn(ctx, r.db)
+	// в единице работы второй комментарий автора ждет коммита первого
+	// и видит его время, иначе оба пройдут проверку медленного режима
+	if _, ok := db.(pgx.Tx); ok {
+		_, err := db.Exec(ctx, `SELECT pg_advisory_xact_lock($1::int4, hashtext($2::text || ':' || $3::text))`,
+			slowModeLockClass, postID, author)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed lock author in post %w", err)
+		}
+	}
+
+	var last sql.NullTime
+
+	err := db.QueryRow(ctx, query, postID, author).Scan(&last)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed get last comment time %w", err)
+	}
+
+	return last.Time, nil
+}
+
+func (r *PostgresCommentRepository) Approve(ctx context.Context, id string) (*domain.Comment, bool, error) {
+	query := `UPDATE comments SET pending = FALSE WHERE id = $1 AND pending
+			  RETURNING ` + commentColumns
+
+	var comment *domain.Comment
+	approved := false
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		var err error
+		comment, err = scanComment(tx.QueryRow(ctx, query, id))
+		if err == pgx.ErrNoRows {
+			// уже одобрен - счетчики не меняются
+			comment, err = scanComment(tx.QueryRow(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = $1`, id))
+			if err == pgx.ErrNoRows {
+				return domain.ErrCommentNotFound
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		approved = true
+		return recordVisible(ctx, tx, comment)
+	})
+	if errors.Is(err, domain.ErrCommentNotFound) {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed approve comment %w", err)
+	}
+
+	return comment, approved, nil
+}
+
+func (r *PostgresCommentRepository) ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments
+			  WHERE post_id = $1
+			  ORDER BY depth, created_at`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed list comments %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *PostgresCommentRepository) DeleteByPost(ctx context.Context, postID string) error {
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM comments WHERE post_id = $1`, postID); err != nil {
+			return err
+		}
+		return recountPost(ctx, tx, postID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed delete comments %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresCommentRepository) SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error) {
+	query := `UPDATE comments SET locked = $2 WHERE id = $1
+			  RETURNING ` + commentColumns
+
+	comment, err := scanComment(conn(ctx, r.db).QueryRow(ctx, query, id, locked))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed lock thread %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) ThreadLocked(ctx context.Context, id string) (bool, error) {
+	query := `SELECT EXISTS (
+				  SELECT 1 FROM comments a WHERE a.id = ANY(string_to_array(c.path, '.')) AND a.locked
+			  )
+			  FROM comments c WHERE c.id = $1`
+
+	var locked bool
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&locked)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, domain.ErrCommentNotFound
+		}
+		return false, fmt.Errorf("failed check thread lock %w", err)
+	}
+
+	return locked, nil
+}
+
+func (r *PostgresCommentRepository) Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error) {
+	var comment *domain.Comment
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		var err error
+		comment, err = scanComment(tx.QueryRow(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = $1`, id))
+		if err == pgx.ErrNoRows {
+			return domain.ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if comment.PinnedAt != nil {
+			return nil
+		}
+
+		// блокировка поста не дает двум параллельным закреплениям обойти лимит
+		if _, err := tx.Exec(ctx, `SELECT 1 FROM posts WHERE id = $1 FOR UPDATE`, comment.PostID); err != nil {
+			return err
+		}
+
+		var pinned int
+		query := `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND pinned_at IS NOT NULL`
+		if err := tx.QueryRow(ctx, query, comment.PostID).Scan(&pinned); err != nil {
+			return err
+		}
+		if pinned >= maxPins {
+			return &domain.TooManyPinsError{MaxPins: maxPins}
+		}
+
+		query = `UPDATE comments SET pinned_at = NOW() WHERE id = $1 RETURNING ` + commentColumns
+		comment, err = scanComment(tx.QueryRow(ctx, query, id))
+		return err
+	})
+
+	if err != nil {
+		var pinsErr *domain.TooManyPinsError
+		if errors.Is(err, domain.ErrCommentNotFound) || errors.As(err, &pinsErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed pin comment %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) Unpin(ctx context.Context, id string) (*domain.Comment, error) {
+	query := `UPDATE comments SET pinned_at = NULL WHERE id = $1
+			  RETURNING ` + commentColumns
+
+	comment, err := scanComment(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed unpin comment %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments
+			  WHERE post_id = $1 AND pinned_at IS NOT NULL
+			  ORDER BY pinned_at`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get pinned comments %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *PostgresCommentRepository) Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error) {
+	selectQuery := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1`
+
+	var comment *domain.Comment
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		var parent *domain.Comment
+		var err error
+		// переносы в посте идут по очереди, иначе два встречных переноса
+		// пройдут проверку цикла по еще не измененным путям
+		for attempt := 0; ; attempt++ {
+			fromPostID, toPostID, err := r.movePosts(ctx, tx, id, parentID, postID)
+			if err != nil {
+				return err
+			}
+			if err := lockPosts(ctx, tx, "FOR UPDATE", fromPostID, toPostID); err != nil {
+				return err
+			}
+
+			comment, err = scanComment(tx.QueryRow(ctx, selectQuery+` FOR UPDATE`, id))
+			if err == pgx.ErrNoRows {
+				return domain.ErrCommentNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			postID = toPostID
+			if parentID != nil {
+				parent, err = scanComment(tx.QueryRow(ctx, selectQuery+` FOR UPDATE`, *parentID))
+				if err == pgx.ErrNoRows {
+					return domain.ErrParentCommentNotFound
+				}
+				if err != nil {
+					return err
+				}
+				postID = parent.PostID
+			}
+
+			// ветку или родителя успели перенести в другой пост до блокировки
+			if comment.PostID == fromPostID && postID == toPostID {
+				break
+			}
+			if attempt == maxMoveAttempts {
+				return errors.New("comment was moved concurrently")
+			}
+		}
+
+		newPath, newDepth, err := comment.MoveUnder(parent)
+		if err != nil {
+			return err
+		}
+
+		var visible int
+		query := `SELECT COUNT(*) FROM comments WHERE (path = $1 OR path LIKE $1 || '.%') AND NOT pending`
+		if err := tx.QueryRow(ctx, query, comment.Path).Scan(&visible); err != nil {
+			return err
+		}
+		if err := shiftCounters(ctx, tx, comment, visible, -1); err != nil {
+			return err
+		}
+
+		// вся ветка переносится одним UPDATE по префиксу пути
+		query = `UPDATE comments SET
+					path = $2 || substr(path, length($1) + 1),
+					depth = depth + $3,
+					pinned_at = CASE WHEN post_id = $4 THEN pinned_at END,
+					post_id = $4,
+					parent_id = CASE WHEN id = $5 THEN $6 ELSE parent_id END
+				 WHERE path = $1 OR path LIKE $1 || '.%'`
+		_, err = tx.Exec(ctx, query, comment.Path, newPath, newDepth-comment.Depth, postID, comment.ID, parentID)
+		if err != nil {
+			return err
+		}
+
+		oldPostID := comment.PostID
+		comment.Path, comment.Depth, comment.PostID, comment.ParentID = newPath, newDepth, postID, parentID
+		if err := shiftCounters(ctx, tx, comment, visible, 1); err != nil {
+			return err
+		}
+
+		if oldPostID == postID {
+			return nil
+		}
+		comment.PinnedAt = nil
+		if err := recountPost(ctx, tx, oldPostID); err != nil {
+			return err
+		}
+		return recountPost(ctx, tx, postID)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrCommentNotFound),
+			errors.Is(err, domain.ErrParentCommentNotFound),
+			errors.Is(err, domain.ErrCommentCycle),
+			errors.Is(err, domain.ErrPostNotFound):
+			return nil, err
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return nil, domain.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed move comment %w", err)
+	}
+
+	return comment, nil
+}
+
+const maxMoveAttempts = 3
+
+// movePosts возвращает пост, где сейчас ветка id, и пост, куда она переносится
+func (r *PostgresCommentRepository) movePosts(ctx context.Context, tx pgx.Tx, id string, parentID *string, postID string) (string, string, error) {
+	var fromPostID string
+	err := tx.QueryRow(ctx, `SELECT post_id FROM comments WHERE id = $1`, id).Scan(&fromPostID)
+	if err == pgx.ErrNoRows {
+		return "", "", domain.ErrCommentNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if parentID == nil {
+		return fromPostID, postID, nil
+	}
+
+	var toPostID string
+	err = tx.QueryRow(ctx, `SELECT post_id FROM comments WHERE id = $1`, *parentID).Scan(&toPostID)
+	if err == pgx.ErrNoRows {
+		return "", "", domain.ErrParentCommentNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return fromPostID, toPostID, nil
+}
+
+// lockPosts блокирует посты в порядке id, чтобы встречные переносы
+// не взаимоблокировались. Отсутствующий пост - ErrPostNotFound
+func lockPosts(ctx context.Context, tx pgx.Tx, mode string, ids ...string) error {
+	if len(ids) == 2 && ids[0] == ids[1] {
+		ids = ids[:1]
+	}
+
+	query := `SELECT id FROM posts WHERE id = ANY($1) ORDER BY id ` + mode
+	rows, err := tx.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed lock posts %w", err)
+	}
+	defer rows.Close()
+
+	locked := 0
+	for rows.Next() {
+		locked++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed lock posts %w", err)
+	}
+	if locked != len(ids) {
+		return domain.ErrPostNotFound
+	}
+	return nil
+}
+
+// shiftCounters добавляет ветку из visible видимых комментариев к счетчикам
+// предков комментария (sign = 1) или убирает ее (sign = -1)
+func shiftCounters(ctx context.Context, tx pgx.Tx, comment *domain.Comment, visible int, sign int) error {
+	if comment.ParentID == nil {
+		return nil
+	}
+
+	if !comment.Pending {
+		query := `UPDATE comments SET reply_count = reply_count + $2 WHERE id = $1`
+		if _, err := tx.Exec(ctx, query, *comment.ParentID, sign); err != nil {
+			return fmt.Errorf("failed update reply count %w", err)
+		}
+	}
+
+	query := `UPDATE comments SET descendant_count = descendant_count + $2 WHERE id = ANY($1)`
+	if _, err := tx.Exec(ctx, query, comment.AncestorIDs(), sign*visible); err != nil {
+		return fmt.Errorf("failed update descendant count %w", err)
+	}
+	return nil
+}
+
+// recountPost пересчитывает счетчики поста по его комментариям
+func recountPost(ctx context.Context, tx pgx.Tx, postID string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM post_participants WHERE post_id = $1`, postID); err != nil {
+		return fmt.Errorf("failed clear participants %w", err)
+	}
+
+	query := `INSERT INTO post_participants (post_id, author)
+			  SELECT DISTINCT post_id, author FROM comments WHERE post_id = $1 AND NOT pending`
+	if _, err := tx.Exec(ctx, query, postID); err != nil {
+		return fmt.Errorf("failed recount participants %w", err)
+	}
+
+	query = `INSERT INTO post_stats (post_id, comment_count, participant_count, last_comment_at)
+			 SELECT $1::varchar, COUNT(*), COUNT(DISTINCT author), MAX(created_at)
+			 FROM comments WHERE post_id = $1 AND NOT pending
+			 ON CONFLICT (post_id) DO UPDATE SET
+			 	comment_count = EXCLUDED.comment_count,
+			 	participant_count = EXCLUDED.participant_count,
+			 	last_comment_at = EXCLUDED.last_comment_at`
+	if _, err := tx.Exec(ctx, query, postID); err != nil {
+		return fmt.Errorf("failed recount post stats %w", err)
+	}
+	return nil
+}
+
+// recordVisible обновляет счетчики поста и предков комментария,
+// который стал виден в ветке
+func recordVisible(ctx context.Context, tx pgx.Tx, comment *domain.Comment) error {
+	if comment.ParentID != nil {
+		query := `UPDATE comments SET reply_count = reply_count + 1 WHERE id = $1`
+		if _, err := tx.Exec(ctx, query, *comment.ParentID); err != nil {
+			return fmt.Errorf("failed update reply count %w", err)
+		}
+
+		query = `UPDATE comments SET descendant_count = descendant_count + 1 WHERE id = ANY($1)`
+		if _, err := tx.Exec(ctx, query, comment.AncestorIDs()); err != nil {
+			return fmt.Errorf("failed update descendant count %w", err)
+		}
+	}
+
+	query := `INSERT INTO post_participants (post_id, author) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	commandTag, err := tx.Exec(ctx, query, comment.PostID, comment.Author)
+	if err != nil {
+		return fmt.Errorf("failed add participant %w", err)
+	}
+
+	query = `INSERT INTO post_stats (post_id, comment_count, participant_count, last_comment_at)
+			 VALUES ($1, 1, $2, $3)
+			 ON CONFLICT (post_id) DO UPDATE SET
+			 	comment_count = post_stats.comment_count + 1,
+			 	participant_count = post_stats.participant_count + EXCLUDED.participant_count,
+			 	last_comment_at = GREATEST(post_stats.last_comment_at, EXCLUDED.last_comment_at)`
+	if _, err := tx.Exec(ctx, query, comment.PostID, commandTag.RowsAffected(), comment.CreatedAt); err != nil {
+		return fmt.Errorf("failed update post stats %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresCommentRepository) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	query := `SELECT comment_count, participant_count, last_comment_at FROM post_stats WHERE post_id = $1`
+
+	var stats domain.PostStats
+	err := conn(ctx, r.db).QueryRow(ctx, query, postID).Scan(&stats.CommentCount, &stats.ParticipantCount, &stats.LastCommentAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return domain.PostStats{}, fmt.Errorf("failed get post stats %w", err)
+	}
+
+	// строки нет, пока у поста нет комментариев
+	return stats, nil
+}
+
+func (r *PostgresCommentRepository) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	query := `SELECT reply_count, descendant_count FROM comments WHERE id = $1`
+
+	var stats domain.CommentStats
+	err := conn(ctx, r.db).QueryRow(ctx, query, commentID).Scan(&stats.ReplyCount, &stats.DescendantCount)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.CommentStats{}, domain.ErrCommentNotFound
+		}
+		return domain.CommentStats{}, fmt.Errorf("failed get comment stats %w", err)
+	}
+
+	return stats, nil
+}
+
+func (r *PostgresCommentRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	query := `SELECT DISTINCT author FROM comments WHERE author = ANY($1)`
+
+	return queryAuthors(ctx, conn(ctx, r.db), query, authors)
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/internal/repository/repotest"
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+// TestConformance запускается, если задан PG_URL. Миграции применяются
+// в отдельной схеме, которая удаляется после теста
+func TestConformance(t *testing.T) {
+	pgURL := os.Getenv("PG_URL")
+	if pgURL == "" {
+		t.Skip("PG_URL is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, pgURL)
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+
+	schema := "conformance_" + strings.ReplaceAll(utils.GenerateID(), "-", "")
+	_, err = admin.Exec(ctx, `CREATE SCHEMA `+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	cfg, err := pgxpool.ParseConfig(pgURL)
+	require.NoError(t, err)
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	files, err := filepath.Glob("../../../migrations/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sort.Strings(files)
+	for _, file := range files {
+		script, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, string(script))
+		require.NoError(t, err, file)
+	}
+
+	repotest.Run(t, func(t *testing.T) (repository.PostRepository, repository.CommentRepository) {
+		return NewPostgresPostRepository(db), NewPostgresCommentRepository(db)
+	})
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLock - сессионная advisory-блокировка Postgres. Блокировка живет
+// на конкретном соединении, поэтому оно держится до вызова unlock
+type AdvisoryLock struct {
+	db  *pgxpool.Pool
+	key int64
+}
+
+func NewAdvisoryLock(db *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{db: db, key: key}
+}
+
+func (l *AdvisoryLock) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed acquire connection %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("failed take advisory lock %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		defer conn.Release()
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+			// закрытое соединение снимает блокировку, и пул его не вернет
+			log.Printf("failed release advisory lock %v", err)
+			conn.Conn().Close(context.Background())
+		}
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const notificationColumns = `id, recipient, kind, actor, post_id, comment_id, created_at, read_at`
+
+type PostgresNotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresNotificationRepository(db *pgxpool.Pool) *PostgresNotificationRepository {
+	return &PostgresNotificationRepository{db: db}
+}
+
+func (r *PostgresNotificationRepository) Create(ctx context.Context, notification *domain.Notification) error {
+	query := `INSERT INTO notifications (` + notificationColumns + `)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.Exec(ctx, query,
+		notification.ID,
+		notification.Recipient,
+		notification.Kind,
+		notification.Actor,
+		notification.PostID,
+		notification.CommentID,
+		notification.CreatedAt,
+		notification.ReadAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed create notification %w", err)
+	}
+
+	return nil
+}
+
+func scanNotification(row rowScanner) (*domain.Notification, error) {
+	var n domain.Notification
+
+	err := row.Scan(
+		&n.ID,
+		&n.Recipient,
+		&n.Kind,
+		&n.Actor,
+		&n.PostID,
+		&n.CommentID,
+		&n.CreatedAt,
+		&n.ReadAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+func (r *PostgresNotificationRepository) List(ctx context.Context, recipient string, filter domain.NotificationFilter) ([]*domain.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE recipient = $1`
+	args := []any{recipient}
+
+	if filter.UnreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed get notifications %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]*domain.Notification, 0, filter.Limit)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan notification %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (r *PostgresNotificationRepository) CountUnread(ctx context.Context, recipient string) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE recipient = $1 AND read_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, recipient).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed count unread notifications %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *PostgresNotificationRepository) MarkRead(ctx context.Context, recipient string, ids []string) (int, error) {
+	query := `UPDATE notifications SET read_at = NOW()
+			  WHERE recipient = $1 AND read_at IS NULL AND ($2::text[] IS NULL OR id = ANY($2))`
+
+	// nil передается как NULL и отмечает все, пустой массив - ничего
+
+	commandTag, err := r.db.Exec(ctx, query, recipient, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed mark notifications read %w", err)
+	}
+
+	return int(commandTag.RowsAffected()), nil
+}
@@ -2,14 +2,55 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/tmozzze/SasPosts/internal/domain"
 	"github.com/tmozzze/SasPosts/utils"
 )
 
+const postColumns = `id, title, content, author, allow_comments, created_at,
+	max_reply_depth, max_comment_length, slow_mode_seconds, require_approval, status, publish_at, vote_score`
+
+// postSelect добавляет к колонкам поста его теги
+const postSelect = postColumns + `,
+	ARRAY(SELECT t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		  WHERE pt.post_id = posts.id ORDER BY t.name) AS tags`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner) (*domain.Post, error) {
+	var post domain.Post
+
+	err := row.Scan(
+		&post.ID,
+		&post.Title,
+		&post.Content,
+		&post.Author,
+		&post.AllowComments,
+		&post.CreatedAt,
+		&post.Settings.MaxReplyDepth,
+		&post.Settings.MaxCommentLength,
+		&post.Settings.SlowModeSeconds,
+		&post.Settings.RequireApproval,
+		&post.Status,
+		&post.PublishAt,
+		&post.VoteScore,
+		&post.Tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
 type PostgresPostRepository struct {
 	db *pgxpool.Pool
 }
@@ -22,19 +63,36 @@ func (r *PostgresPostRepository) Create(ctx context.Context, post *domain.Post)
 	if post.ID == "" {
 		post.ID = utils.GenerateID()
 	}
-	post.CreatedAt = time.Now()
+	// время уже задано у постов, восстановленных из архива
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
 
-	query := `INSERT INTO posts (id, title, content, author, allow_comments, created_at)
-			  VALUES ($1, $2, $3, $4, $5, $6)`
+	query := `INSERT INTO posts (` + postColumns + `)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query,
+			post.ID,
+			post.Title,
+			post.Content,
+			post.Author,
+			post.AllowComments,
+			post.CreatedAt,
+			post.Settings.MaxReplyDepth,
+			post.Settings.MaxCommentLength,
+			post.Settings.SlowModeSeconds,
+			post.Settings.RequireApproval,
+			post.Status,
+			post.PublishAt,
+			post.VoteScore,
+		)
+		if err != nil {
+			return err
+		}
 
-	_, err := r.db.Exec(ctx, query,
-		post.ID,
-		post.Title,
-		post.Content,
-		post.Author,
-		post.AllowComments,
-		post.CreatedAt,
-	)
+		return setPostTags(ctx, tx, post.ID, post.Tags)
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to create post: %w", err)
@@ -44,30 +102,35 @@ func (r *PostgresPostRepository) Create(ctx context.Context, post *domain.Post)
 }
 
 func (r *PostgresPostRepository) GetByID(ctx context.Context, id string) (*domain.Post, error) {
-	query := `SELECT id, title, content, author, allow_comments, created_at 
-	          FROM posts WHERE id = $1`
-	var post domain.Post
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.Author,
-		&post.AllowComments,
-		&post.CreatedAt,
-	)
+	query := `SELECT ` + postSelect + ` FROM posts WHERE id = $1`
 
+	post, err := scanPost(conn(ctx, r.db).QueryRow(ctx, query, id))
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrPostNotFound
+		}
 		return nil, fmt.Errorf("id search post failed: %w", err)
 	}
 
-	return &post, nil
+	return post, nil
 
 }
 
-func (r *PostgresPostRepository) GetAll(ctx context.Context) ([]*domain.Post, error) {
-	query := `SELECT id, title, content, author, allow_comments, created_at FROM posts`
+func (r *PostgresPostRepository) GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error) {
+	// для ALL пост должен содержать все теги, для ANY - хотя бы один
+	query := `SELECT ` + postSelect + ` FROM posts
+			  WHERE ($1 = '' OR status = $1) AND ($2 = '' OR author = $2)
+			  AND (cardinality($3::text[]) = 0 OR (
+				  SELECT count(*) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+				  WHERE pt.post_id = posts.id AND t.name = ANY($3)
+			  ) >= CASE WHEN $4 = 'ALL' THEN cardinality($3::text[]) ELSE 1 END)`
+
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
+	}
 
-	rows, err := r.db.Query(ctx, query)
+	rows, err := conn(ctx, r.db).Query(ctx, query, string(filter.Status), filter.Author, tags, string(filter.TagMatch))
 	if err != nil {
 		return nil, fmt.Errorf("failed get all posts %w", err)
 	}
@@ -76,22 +139,12 @@ func (r *PostgresPostRepository) GetAll(ctx context.Context) ([]*domain.Post, er
 	var posts []*domain.Post
 
 	for rows.Next() {
-		var post domain.Post
-
-		err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Content,
-			&post.Author,
-			&post.AllowComments,
-			&post.CreatedAt,
-		)
-
+		post, err := scanPost(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed scan posts %w", err)
 		}
 
-		posts = append(posts, &post)
+		posts = append(posts, post)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error %w", err)
@@ -103,7 +156,7 @@ func (r *PostgresPostRepository) GetAll(ctx context.Context) ([]*domain.Post, er
 func (r *PostgresPostRepository) ToggleComments(ctx context.Context, postID string, allow bool) error {
 	query := `UPDATE posts SET allow_comments = $1 WHERE id = $2`
 
-	commandTag, err := r.db.Exec(ctx, query, allow, postID)
+	commandTag, err := conn(ctx, r.db).Exec(ctx, query, allow, postID)
 	if err != nil {
 		return fmt.Errorf("failed toggle comments %w", err)
 	}
@@ -116,32 +169,45 @@ func (r *PostgresPostRepository) ToggleComments(ctx context.Context, postID stri
 }
 
 func (r *PostgresPostRepository) Update(ctx context.Context, post *domain.Post) error {
-	query := `UPDATE posts SET title = $1, content = $2, author = $3, allow_comments = $4
-			  WHERE id = $5`
+	query := `UPDATE posts SET title = $1, content = $2, author = $3, allow_comments = $4,
+			  status = $5, publish_at = $6
+			  WHERE id = $7`
+
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		commantTag, err := tx.Exec(ctx, query,
+			post.Title,
+			post.Content,
+			post.Author,
+			post.AllowComments,
+			post.Status,
+			post.PublishAt,
+			post.ID,
+		)
+		if err != nil {
+			return err
+		}
 
-	commantTag, err := r.db.Exec(ctx, query,
-		post.Title,
-		post.Content,
-		post.Author,
-		post.AllowComments,
-		post.ID,
-	)
+		if commantTag.RowsAffected() == 0 {
+			return domain.ErrPostNotFound
+		}
+
+		return setPostTags(ctx, tx, post.ID, post.Tags)
+	})
 
+	if errors.Is(err, domain.ErrPostNotFound) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed update post %w", err)
 	}
 
-	if commantTag.RowsAffected() == 0 {
-		return domain.ErrPostNotFound
-	}
-
 	return nil
 }
 
 func (r *PostgresPostRepository) Delete(ctx context.Context, postID string) error {
 	query := `DELETE FROM posts WHERE id = $1`
 
-	commandTag, err := r.db.Exec(ctx, query, postID)
+	commandTag, err := conn(ctx, r.db).Exec(ctx, query, postID)
 
 	if err != nil {
 		return fmt.Errorf("failed delete post %w", err)
@@ -155,14 +221,173 @@ func (r *PostgresPostRepository) Delete(ctx context.Context, postID string) erro
 }
 
 func (r *PostgresPostRepository) CheckAllowedComments(ctx context.Context, postID string) (bool, error) {
-	query := `SELECT allow_comments FROM posts WHERE id = $1`
+	// в единице работы пост блокируется до коммита: toggleComments
+	// не пройдет между проверкой и созданием комментария
+	query := `SELECT allow_comments FROM posts WHERE id = $1 FOR SHARE`
 
 	var allowComments bool
-	err := r.db.QueryRow(ctx, query, postID).Scan(&allowComments)
+	err := conn(ctx, r.db).QueryRow(ctx, query, postID).Scan(&allowComments)
 
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, domain.ErrPostNotFound
+		}
 		return false, fmt.Errorf("failed check allow comments %w", err)
 	}
 
 	return allowComments, nil
 }
+
+func (r *PostgresPostRepository) UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error {
+	query := `UPDATE posts SET max_reply_depth = $1, max_comment_length = $2, slow_mode_seconds = $3, require_approval = $4
+			  WHERE id = $5`
+
+	commandTag, err := conn(ctx, r.db).Exec(ctx, query,
+		settings.MaxReplyDepth,
+		settings.MaxCommentLength,
+		settings.SlowModeSeconds,
+		settings.RequireApproval,
+		postID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed update thread settings %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return domain.ErrPostNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresPostRepository) PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error) {
+	query := `UPDATE posts SET status = 'PUBLISHED'
+			  WHERE status = 'SCHEDULED' AND publish_at <= $1
+			  RETURNING ` + postSelect
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed publish due posts %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*domain.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan posts %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return posts, nil
+}
+
+func (r *PostgresPostRepository) Vote(ctx context.Context, postID, voter string, value int) (int, error) {
+	var delta int
+	err := pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		// строка голоса создается заранее, чтобы параллельные голоса
+		// одного автора ждали друг друга на ее блокировке
+		query := `INSERT INTO post_votes (post_id, voter, value) VALUES ($1, $2, 0) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, postID, voter); err != nil {
+			return err
+		}
+
+		var previous int
+		query = `SELECT value FROM post_votes WHERE post_id = $1 AND voter = $2 FOR UPDATE`
+		if err := tx.QueryRow(ctx, query, postID, voter).Scan(&previous); err != nil {
+			return err
+		}
+
+		delta = value - previous
+		if delta == 0 {
+			return nil
+		}
+
+		query = `UPDATE post_votes SET value = $3 WHERE post_id = $1 AND voter = $2`
+		if _, err := tx.Exec(ctx, query, postID, voter, value); err != nil {
+			return err
+		}
+
+		query = `UPDATE posts SET vote_score = vote_score + $1 WHERE id = $2`
+		_, err := tx.Exec(ctx, query, delta, postID)
+		return err
+	})
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return 0, domain.ErrPostNotFound
+		}
+		return 0, fmt.Errorf("failed vote post %w", err)
+	}
+
+	return delta, nil
+}
+
+func (r *PostgresPostRepository) ListTags(ctx context.Context, limit int) ([]*domain.Tag, error) {
+	query := `SELECT t.name, count(*) FROM tags t
+			  JOIN post_tags pt ON pt.tag_id = t.id
+			  JOIN posts p ON p.id = pt.post_id
+			  WHERE p.status = 'PUBLISHED'
+			  GROUP BY t.name
+			  ORDER BY count(*) DESC, t.name
+			  LIMIT $1`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed list tags %w", err)
+	}
+
+	tags, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[domain.Tag])
+	if err != nil {
+		return nil, fmt.Errorf("failed scan tags %w", err)
+	}
+
+	return tags, nil
+}
+
+// setPostTags заменяет теги поста, создавая новые теги при необходимости
+func setPostTags(ctx context.Context, tx pgx.Tx, postID string, tags []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM post_tags WHERE post_id = $1`, postID); err != nil {
+		return fmt.Errorf("failed clear post tags %w", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+	if _, err := tx.Exec(ctx, query, tags); err != nil {
+		return fmt.Errorf("failed create tags %w", err)
+	}
+
+	query = `INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`
+	if _, err := tx.Exec(ctx, query, postID, tags); err != nil {
+		return fmt.Errorf("failed set post tags %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresPostRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	query := `SELECT DISTINCT author FROM posts WHERE author = ANY($1)`
+
+	return queryAuthors(ctx, conn(ctx, r.db), query, authors)
+}
+
+func queryAuthors(ctx context.Context, db dbtx, query string, authors []string) ([]string, error) {
+	rows, err := db.Query(ctx, query, authors)
+	if err != nil {
+		return nil, fmt.Errorf("failed get known authors %w", err)
+	}
+
+	known, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed scan known authors %w", err)
+	}
+
+	return known, nil
+}
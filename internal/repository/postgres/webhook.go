@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const webhookColumns = `id, owner, url, events, secret, created_at`
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at,
+	last_error, response_status, created_at, delivered_at`
+
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	var events []string
+
+	err := row.Scan(&webhook.ID, &webhook.Owner, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range events {
+		webhook.Events = append(webhook.Events, domain.WebhookEvent(e))
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.Event,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastError,
+		&d.ResponseStatus,
+		&d.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+func scanDeliveries(rows pgx.Rows) ([]*domain.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan delivery %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return deliveries, nil
+}
+
+type PostgresWebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresWebhookRepository(db *pgxpool.Pool) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+func (r *PostgresWebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	query := `INSERT INTO webhooks (` + webhookColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	events := make([]string, 0, len(webhook.Events))
+	for _, e := range webhook.Events {
+		events = append(events, string(e))
+	}
+
+	_, err := r.db.Exec(ctx, query, webhook.ID, webhook.Owner, webhook.URL, events, webhook.Secret, webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed create webhook %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresWebhookRepository) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed get webhook %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *PostgresWebhookRepository) List(ctx context.Context, owner string) ([]*domain.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE owner = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed get webhooks %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan webhook %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *PostgresWebhookRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM webhooks WHERE id = $1`
+
+	commandTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed delete webhook %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (` + deliveryColumns + `)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	batch := &pgx.Batch{}
+	for _, d := range deliveries {
+		batch.Queue(query,
+			d.ID,
+			d.WebhookID,
+			d.Event,
+			d.Payload,
+			d.Status,
+			d.Attempts,
+			d.NextAttemptAt,
+			d.LastError,
+			d.ResponseStatus,
+			d.CreatedAt,
+			d.DeliveredAt,
+		)
+	}
+
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed create webhook deliveries %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error) {
+	// SKIP LOCKED не дает двум воркерам взять одну доставку
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $2
+			  WHERE id IN (
+				  SELECT id FROM webhook_deliveries
+				  WHERE status IN ('PENDING', 'FAILED') AND next_attempt_at <= $1
+				  ORDER BY next_attempt_at
+				  LIMIT $3
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + deliveryColumns
+
+	rows, err := r.db.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed claim webhook deliveries %w", err)
+	}
+
+	return scanDeliveries(rows)
+}
+
+func (r *PostgresWebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries
+			  SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4,
+				  response_status = $5, delivered_at = $6
+			  WHERE id = $7`
+
+	commandTag, err := r.db.Exec(ctx, query,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastError,
+		d.ResponseStatus,
+		d.DeliveredAt,
+		d.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed update webhook delivery %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresWebhookRepository) GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	d, err := scanDelivery(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed get webhook delivery %w", err)
+	}
+
+	return d, nil
+}
+
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, status *domain.DeliveryStatus, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+			  WHERE webhook_id = $1 AND ($2::text IS NULL OR status = $2)
+			  ORDER BY created_at DESC
+			  LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, webhookID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed get webhook deliveries %w", err)
+	}
+
+	return scanDeliveries(rows)
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/tmozzze/SasPosts/internal/domain"
 )
@@ -9,15 +11,138 @@ import (
 type PostRepository interface {
 	Create(ctx context.Context, post *domain.Post) error
 	GetByID(ctx context.Context, id string) (*domain.Post, error)
-	GetAll(ctx context.Context) ([]*domain.Post, error)
+	GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error)
 	Update(ctx context.Context, post *domain.Post) error
 	Delete(ctx context.Context, postID string) error
 	CheckAllowedComments(ctx context.Context, postID string) (bool, error)
 	ToggleComments(ctx context.Context, postID string, allow bool) error
+	UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error
+	// PublishDue публикует запланированные посты, время которых пришло,
+	// и возвращает их
+	PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error)
+	// KnownAuthors возвращает тех из authors, у кого есть посты
+	KnownAuthors(ctx context.Context, authors []string) ([]string, error)
+	// Vote сохраняет голос voter за пост и возвращает изменение суммы голосов
+	Vote(ctx context.Context, postID, voter string, value int) (int, error)
+	// ListTags возвращает теги опубликованных постов, самые частые первыми
+	ListTags(ctx context.Context, limit int) ([]*domain.Tag, error)
 }
 type CommentRepository interface {
 	Create(ctx context.Context, comment *domain.Comment) error
 	GetByID(ctx context.Context, id string) (*domain.Comment, error)
 	GetByPost(ctx context.Context, postID string, limit int, offset int) ([]*domain.Comment, error)
 	GetChildren(ctx context.Context, parentID string, limit int, offset int) ([]*domain.Comment, error)
+	// ListByPost возвращает все комментарии поста, включая ждущие одобрения.
+	// Родители идут раньше ответов
+	ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error)
+	// DeleteByPost удаляет все комментарии поста вместе со счетчиками
+	DeleteByPost(ctx context.Context, postID string) error
+	// GetWithAncestors возвращает не больше depth ближайших предков комментария
+	// от корня ветки, последним идет сам комментарий
+	GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error)
+	// LastCommentTime возвращает время последнего комментария автора в посте
+	// или нулевое время, если автор еще не комментировал. В единице работы
+	// параллельный вызов для того же автора и поста ждет ее конца
+	LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error)
+	// Approve снимает комментарий с модерации. Второе значение - был ли
+	// он на модерации до вызова
+	Approve(ctx context.Context, id string) (*domain.Comment, bool, error)
+	// SetLocked закрывает или открывает ветку начиная с комментария id
+	SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error)
+	// ThreadLocked сообщает, закрыт ли сам комментарий или кто-то из его предков
+	ThreadLocked(ctx context.Context, id string) (bool, error)
+	// Pin закрепляет комментарий, если в посте закреплено меньше maxPins.
+	// Повторное закрепление ничего не меняет
+	Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error)
+	Unpin(ctx context.Context, id string) (*domain.Comment, error)
+	// GetPinned возвращает закрепленные комментарии поста в порядке закрепления
+	GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error)
+	// Move переносит комментарий со всей веткой под parentID или, если он nil,
+	// в корень поста postID. Пути, глубина и счетчики меняются атомарно
+	Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error)
+	// PostStats и CommentStats читают счетчики, которые обновляются
+	// при создании видимого комментария или его одобрении
+	PostStats(ctx context.Context, postID string) (domain.PostStats, error)
+	CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error)
+	// KnownAuthors возвращает тех из authors, у кого есть комментарии
+	KnownAuthors(ctx context.Context, authors []string) ([]string, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, id string) (*domain.Webhook, error)
+	// List возвращает вебхуки автора owner
+	List(ctx context.Context, owner string) ([]*domain.Webhook, error)
+	Delete(ctx context.Context, id string) error
+
+	CreateDeliveries(ctx context.Context, deliveries []*domain.WebhookDelivery) error
+	// ClaimDue забирает доставки, время которых пришло, и откладывает их
+	// на lease, чтобы другие воркеры их не взяли. Если воркер упадет,
+	// доставка вернется в очередь после lease
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	// ListDeliveries возвращает журнал доставок вебхука от новых к старым
+	ListDeliveries(ctx context.Context, webhookID string, status *domain.DeliveryStatus, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *domain.Notification) error
+	// List возвращает уведомления получателя от новых к старым
+	List(ctx context.Context, recipient string, filter domain.NotificationFilter) ([]*domain.Notification, error)
+	CountUnread(ctx context.Context, recipient string) (int, error)
+	// MarkRead отмечает прочитанными уведомления с ids или все, если ids равен nil.
+	// Пустой список ничего не отмечает. Возвращает число отмеченных
+	MarkRead(ctx context.Context, recipient string, ids []string) (int, error)
+}
+
+// UnitOfWork выполняет fn как одну транзакцию: вызовы репозиториев с ctx,
+// который получает fn, видят согласованное состояние и не перемежаются
+// с изменениями из других запросов. Вложенный Do выполняется в той же
+// единице работы. Функции из AfterCommit выполняются после фиксации
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type afterCommitKey struct{}
+
+type afterCommitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// WithAfterCommit нужен реализациям UnitOfWork: возвращает ctx для fn и
+// функцию, которую надо вызвать после фиксации. При откате ее не вызывают
+func WithAfterCommit(ctx context.Context) (context.Context, func()) {
+	hooks := &afterCommitHooks{}
+	return context.WithValue(ctx, afterCommitKey{}, hooks), func() {
+		hooks.mu.Lock()
+		fns := hooks.fns
+		hooks.fns = nil
+		hooks.mu.Unlock()
+
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}
+
+// AfterCommit откладывает fn до фиксации единицы работы из ctx,
+// вне единицы работы fn выполняется сразу
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.mu.Lock()
+	hooks.fns = append(hooks.fns, fn)
+	hooks.mu.Unlock()
+}
+
+// InUnitOfWork сообщает, выполняется ли ctx внутри единицы работы
+func InUnitOfWork(ctx context.Context) bool {
+	_, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	return ok
 }
@@ -0,0 +1,762 @@
+// Package repotest - общий набор проверок поведения, который должна проходить
+// каждая реализация PostRepository и CommentRepository
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+// Factory возвращает связанные репозитории одного хранилища. Хранилище может
+// быть общим для всех проверок: они создают свои посты, теги и авторов
+type Factory func(t *testing.T) (repository.PostRepository, repository.CommentRepository)
+
+// Run запускает все проверки на репозиториях из newRepos
+func Run(t *testing.T, newRepos Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository)
+	}{
+		{"post not found", testPostNotFound},
+		{"post round trip", testPostRoundTrip},
+		{"post update", testPostUpdate},
+		{"post filter", testPostFilter},
+		{"post delete cascades", testPostDeleteCascades},
+		{"publish due", testPublishDue},
+		{"vote", testVote},
+		{"list tags", testListTags},
+		{"known authors", testKnownAuthors},
+		{"comment not found", testCommentNotFound},
+		{"comment tree", testCommentTree},
+		{"comment ordering", testCommentOrdering},
+		{"pending comments", testPendingComments},
+		{"thread lock", testThreadLock},
+		{"pins", testPins},
+		{"move", testMove},
+		{"concurrent moves", testConcurrentMoves},
+		{"returned comments are snapshots", testCommentSnapshots},
+		{"delete by post", testDeleteByPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, comments := newRepos(t)
+			tt.run(t, posts, comments)
+		})
+	}
+}
+
+// unique - имя, которое не встретится в общем хранилище у других проверок
+func unique(prefix string) string {
+	return prefix + utils.GenerateID()[:8]
+}
+
+func createPost(t *testing.T, posts repository.PostRepository, author string, tags ...string) *domain.Post {
+	t.Helper()
+
+	post, err := domain.NewPost("title", "content", author, true)
+	require.NoError(t, err)
+	if tags != nil {
+		post.Tags, err = domain.NormalizeTags(tags)
+		require.NoError(t, err)
+	}
+	require.NoError(t, posts.Create(context.Background(), post))
+	return post
+}
+
+// commentFactory создает комментарии поста с возрастающим временем, чтобы
+// порядок не зависел от точности часов хранилища
+func commentFactory(t *testing.T, comments repository.CommentRepository, postID string) func(author string, parentID *string) *domain.Comment {
+	createdAt := time.Now().Add(-time.Hour)
+
+	return func(author string, parentID *string) *domain.Comment {
+		t.Helper()
+
+		comment, err := domain.NewComment(postID, author, parentID, "text")
+		require.NoError(t, err)
+		createdAt = createdAt.Add(time.Second)
+		comment.CreatedAt = createdAt
+		require.NoError(t, comments.Create(context.Background(), comment))
+		return comment
+	}
+}
+
+func ids(comments []*domain.Comment) []string {
+	result := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, comment.ID)
+	}
+	return result
+}
+
+func testPostNotFound(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	missing := utils.GenerateID()
+
+	_, err := posts.GetByID(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+	_, err = posts.CheckAllowedComments(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+	assert.ErrorIs(t, posts.ToggleComments(ctx, missing, false), domain.ErrPostNotFound)
+	assert.ErrorIs(t, posts.UpdateSettings(ctx, missing, domain.DefaultThreadSettings()), domain.ErrPostNotFound)
+	assert.ErrorIs(t, posts.Delete(ctx, missing), domain.ErrPostNotFound)
+	_, err = posts.Vote(ctx, missing, "voter", 1)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+
+	post, err := domain.NewPost("title", "content", "author", true)
+	require.NoError(t, err)
+	assert.ErrorIs(t, posts.Update(ctx, post), domain.ErrPostNotFound)
+}
+
+func testPostRoundTrip(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"), "b-tag", "a-tag")
+
+	got, err := posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, post.Title, got.Title)
+	assert.Equal(t, post.Content, got.Content)
+	assert.Equal(t, post.Author, got.Author)
+	assert.Equal(t, domain.PostPublished, got.Status)
+	assert.Equal(t, []string{"a-tag", "b-tag"}, got.Tags)
+	assert.Equal(t, domain.DefaultThreadSettings(), got.Settings)
+	assert.WithinDuration(t, post.CreatedAt, got.CreatedAt, time.Millisecond)
+
+	allowed, err := posts.CheckAllowedComments(ctx, post.ID)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, posts.ToggleComments(ctx, post.ID, false))
+	allowed, err = posts.CheckAllowedComments(ctx, post.ID)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	settings := domain.ThreadSettings{MaxReplyDepth: 3, MaxCommentLength: 100, SlowModeSeconds: 10, RequireApproval: true}
+	require.NoError(t, posts.UpdateSettings(ctx, post.ID, settings))
+	got, err = posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, settings, got.Settings)
+}
+
+func testPostUpdate(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"), "old")
+	_, err := posts.Vote(ctx, post.ID, "voter", 1)
+	require.NoError(t, err)
+
+	// Update меняет содержимое и теги, но не голоса и не настройки
+	updated, err := posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	changed := *updated
+	changed.Title = "new title"
+	changed.Tags = []string{"new"}
+	changed.VoteScore = 100
+	changed.Settings.RequireApproval = true
+	require.NoError(t, posts.Update(ctx, &changed))
+
+	got, err := posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new title", got.Title)
+	assert.Equal(t, []string{"new"}, got.Tags)
+	assert.Equal(t, 1, got.VoteScore)
+	assert.False(t, got.Settings.RequireApproval)
+}
+
+func testPostFilter(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	author := unique("author")
+	go1, db1 := unique("go"), unique("db")
+
+	both := createPost(t, posts, author, go1, db1)
+	onlyGo := createPost(t, posts, author, go1)
+	draft := createPost(t, posts, author, go1)
+	draft.Status = domain.PostDraft
+	require.NoError(t, posts.Update(ctx, draft))
+
+	filter := func(f domain.PostFilter) []string {
+		t.Helper()
+
+		found, err := posts.GetAll(ctx, f)
+		require.NoError(t, err)
+		result := make([]string, 0, len(found))
+		for _, post := range found {
+			result = append(result, post.ID)
+		}
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{both.ID, onlyGo.ID, draft.ID}, filter(domain.PostFilter{Author: author}))
+	assert.ElementsMatch(t, []string{both.ID, onlyGo.ID},
+		filter(domain.PostFilter{Author: author, Status: domain.PostPublished}))
+	assert.ElementsMatch(t, []string{both.ID},
+		filter(domain.PostFilter{Tags: []string{go1, db1}, TagMatch: domain.TagMatchAll}))
+	assert.ElementsMatch(t, []string{both.ID, onlyGo.ID, draft.ID},
+		filter(domain.PostFilter{Tags: []string{go1, db1}, TagMatch: domain.TagMatchAny}))
+}
+
+func testPostDeleteCascades(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+	root := newComment("a", nil)
+	reply := newComment("b", &root.ID)
+
+	require.NoError(t, posts.Delete(ctx, post.ID))
+
+	_, err := posts.GetByID(ctx, post.ID)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+	for _, id := range []string{root.ID, reply.ID} {
+		_, err := comments.GetByID(ctx, id)
+		assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	}
+
+	listed, err := comments.ListByPost(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	stats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostStats{}, stats)
+}
+
+func testPublishDue(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	schedule := func(publishAt time.Time) *domain.Post {
+		t.Helper()
+
+		post := createPost(t, posts, unique("author"))
+		post.Status = domain.PostScheduled
+		post.PublishAt = &publishAt
+		require.NoError(t, posts.Update(ctx, post))
+		return post
+	}
+	due := schedule(now.Add(-time.Minute))
+	later := schedule(now.Add(time.Hour))
+
+	published, err := posts.PublishDue(ctx, now)
+	require.NoError(t, err)
+	var publishedIDs []string
+	for _, post := range published {
+		publishedIDs = append(publishedIDs, post.ID)
+	}
+	assert.Contains(t, publishedIDs, due.ID)
+	assert.NotContains(t, publishedIDs, later.ID)
+
+	got, err := posts.GetByID(ctx, due.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostPublished, got.Status)
+	got, err = posts.GetByID(ctx, later.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostScheduled, got.Status)
+}
+
+func testVote(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+
+	for _, step := range []struct {
+		voter       string
+		value       int
+		delta, want int
+	}{
+		{"a", 1, 1, 1},
+		{"a", 1, 0, 1},
+		{"b", 1, 1, 2},
+		{"a", -1, -2, 0},
+		{"b", 0, -1, -1},
+	} {
+		delta, err := posts.Vote(ctx, post.ID, step.voter, step.value)
+		require.NoError(t, err)
+		assert.Equal(t, step.delta, delta)
+
+		got, err := posts.GetByID(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, step.want, got.VoteScore)
+	}
+}
+
+func testListTags(t *testing.T, posts repository.PostRepository, _ repository.CommentRepository) {
+	ctx := context.Background()
+	author := unique("author")
+	popular, rare, hidden := unique("popular"), unique("rare"), unique("hidden")
+
+	createPost(t, posts, author, popular, rare)
+	createPost(t, posts, author, popular)
+	draft := createPost(t, posts, author, hidden)
+	draft.Status = domain.PostDraft
+	require.NoError(t, posts.Update(ctx, draft))
+
+	tags, err := posts.ListTags(ctx, 1_000_000)
+	require.NoError(t, err)
+
+	var ours []domain.Tag
+	for _, tag := range tags {
+		switch tag.Name {
+		case popular, rare, hidden:
+			ours = append(ours, *tag)
+		}
+	}
+	// теги черновиков не считаются, частые идут первыми
+	assert.Equal(t, []domain.Tag{{Name: popular, PostCount: 2}, {Name: rare, PostCount: 1}}, ours)
+}
+
+func testKnownAuthors(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	postAuthor, commentAuthor, stranger := unique("post"), unique("comment"), unique("stranger")
+
+	post := createPost(t, posts, postAuthor)
+	commentFactory(t, comments, post.ID)(commentAuthor, nil)
+
+	known, err := posts.KnownAuthors(ctx, []string{postAuthor, commentAuthor, stranger})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{postAuthor}, known)
+
+	known, err = comments.KnownAuthors(ctx, []string{postAuthor, commentAuthor, stranger})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{commentAuthor}, known)
+}
+
+func testCommentNotFound(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	missing := utils.GenerateID()
+
+	orphan, err := domain.NewComment(missing, "author", nil, "text")
+	require.NoError(t, err)
+	assert.ErrorIs(t, comments.Create(ctx, orphan), domain.ErrPostNotFound)
+
+	post := createPost(t, posts, unique("author"))
+	noParent, err := domain.NewComment(post.ID, "author", &missing, "text")
+	require.NoError(t, err)
+	assert.ErrorIs(t, comments.Create(ctx, noParent), domain.ErrParentCommentNotFound)
+
+	_, err = comments.GetByID(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.GetWithAncestors(ctx, missing, 1)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, _, err = comments.Approve(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.SetLocked(ctx, missing, true)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.ThreadLocked(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.Pin(ctx, missing, 1)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.Unpin(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.Move(ctx, missing, nil, post.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.CommentStats(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+	// у поста без комментариев нулевые счетчики, а не ошибка
+	stats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PostStats{}, stats)
+	last, err := comments.LastCommentTime(ctx, post.ID, "author")
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+}
+
+func testCommentTree(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	root := newComment("a", nil)
+	reply := newComment("b", &root.ID)
+	nested := newComment("c", &reply.ID)
+	sibling := newComment("a", &root.ID)
+
+	got, err := comments.GetByID(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Equal(t, root.ID, got.Path)
+	assert.Equal(t, 0, got.Depth)
+	assert.Nil(t, got.ParentID)
+
+	got, err = comments.GetByID(ctx, nested.ID)
+	require.NoError(t, err)
+	assert.Equal(t, root.ID+"."+reply.ID+"."+nested.ID, got.Path)
+	assert.Equal(t, 2, got.Depth)
+	require.NotNil(t, got.ParentID)
+	assert.Equal(t, reply.ID, *got.ParentID)
+	assert.WithinDuration(t, nested.CreatedAt, got.CreatedAt, time.Millisecond)
+
+	chain, err := comments.GetWithAncestors(ctx, nested.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{root.ID, reply.ID, nested.ID}, ids(chain))
+	chain, err = comments.GetWithAncestors(ctx, nested.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{reply.ID, nested.ID}, ids(chain))
+
+	postStats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, postStats.CommentCount)
+	assert.Equal(t, 3, postStats.ParticipantCount)
+	require.NotNil(t, postStats.LastCommentAt)
+	assert.WithinDuration(t, sibling.CreatedAt, *postStats.LastCommentAt, time.Millisecond)
+
+	stats, err := comments.CommentStats(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{ReplyCount: 2, DescendantCount: 3}, stats)
+	stats, err = comments.CommentStats(ctx, nested.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{}, stats)
+
+	last, err := comments.LastCommentTime(ctx, post.ID, "a")
+	require.NoError(t, err)
+	assert.WithinDuration(t, sibling.CreatedAt, last, time.Millisecond)
+}
+
+func testCommentOrdering(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	first := newComment("a", nil)
+	reply := newComment("a", &first.ID)
+	second := newComment("a", nil)
+	secondReply := newComment("a", &first.ID)
+	third := newComment("a", nil)
+
+	roots, err := comments.GetByPost(ctx, post.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID, second.ID, third.ID}, ids(roots))
+	roots, err = comments.GetByPost(ctx, post.ID, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.ID}, ids(roots))
+
+	children, err := comments.GetChildren(ctx, first.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{reply.ID, secondReply.ID}, ids(children))
+	children, err = comments.GetChildren(ctx, first.ID, 10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{secondReply.ID}, ids(children))
+
+	// родители раньше ответов, внутри уровня - по времени
+	all, err := comments.ListByPost(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID, second.ID, third.ID, reply.ID, secondReply.ID}, ids(all))
+}
+
+func testPendingComments(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	root := commentFactory(t, comments, post.ID)("a", nil)
+
+	pending, err := domain.NewComment(post.ID, "b", &root.ID, "text")
+	require.NoError(t, err)
+	pending.Pending = true
+	require.NoError(t, comments.Create(ctx, pending))
+
+	children, err := comments.GetChildren(ctx, root.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+	all, err := comments.ListByPost(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	postStats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, postStats.CommentCount)
+	assert.Equal(t, 1, postStats.ParticipantCount)
+
+	// повторное одобрение не меняет счетчики
+	for i := range 2 {
+		approved, changed, err := comments.Approve(ctx, pending.ID)
+		require.NoError(t, err)
+		assert.False(t, approved.Pending)
+		assert.Equal(t, i == 0, changed, "only the first approve moves comment out of pending")
+	}
+
+	children, err = comments.GetChildren(ctx, root.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pending.ID}, ids(children))
+	postStats, err = comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, postStats.CommentCount)
+	assert.Equal(t, 2, postStats.ParticipantCount)
+	stats, err := comments.CommentStats(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 1}, stats)
+}
+
+func testThreadLock(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	root := newComment("a", nil)
+	reply := newComment("b", &root.ID)
+	nested := newComment("c", &reply.ID)
+	other := newComment("d", nil)
+
+	locked, err := comments.SetLocked(ctx, reply.ID, true)
+	require.NoError(t, err)
+	assert.True(t, locked.Locked)
+
+	for id, want := range map[string]bool{root.ID: false, reply.ID: true, nested.ID: true, other.ID: false} {
+		got, err := comments.ThreadLocked(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, id)
+	}
+
+	blocked, err := domain.NewComment(post.ID, "e", &nested.ID, "text")
+	require.NoError(t, err)
+	assert.ErrorIs(t, comments.Create(ctx, blocked), domain.ErrThreadLocked)
+	_, err = comments.GetByID(ctx, blocked.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+	// ответ в открытую соседнюю ветку разрешен
+	newComment("e", &root.ID)
+
+	_, err = comments.SetLocked(ctx, reply.ID, false)
+	require.NoError(t, err)
+	newComment("e", &nested.ID)
+}
+
+func testPins(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	first := newComment("a", nil)
+	second := newComment("b", nil)
+	third := newComment("c", nil)
+
+	pinned, err := comments.Pin(ctx, second.ID, 2)
+	require.NoError(t, err)
+	require.NotNil(t, pinned.PinnedAt)
+	_, err = comments.Pin(ctx, first.ID, 2)
+	require.NoError(t, err)
+	// повторное закрепление не считается в лимит
+	_, err = comments.Pin(ctx, first.ID, 2)
+	require.NoError(t, err)
+
+	_, err = comments.Pin(ctx, third.ID, 2)
+	var pinsErr *domain.TooManyPinsError
+	require.ErrorAs(t, err, &pinsErr)
+	assert.Equal(t, 2, pinsErr.MaxPins)
+
+	list, err := comments.GetPinned(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.ID, first.ID}, ids(list))
+
+	unpinned, err := comments.Unpin(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Nil(t, unpinned.PinnedAt)
+	_, err = comments.Pin(ctx, third.ID, 2)
+	require.NoError(t, err)
+
+	list, err = comments.GetPinned(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID, third.ID}, ids(list))
+}
+
+func testMove(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	target := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	root := newComment("a", nil)
+	reply := newComment("b", &root.ID)
+	nested := newComment("c", &reply.ID)
+	other := newComment("d", nil)
+
+	_, err := comments.Move(ctx, root.ID, &nested.ID, post.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentCycle)
+	missing := utils.GenerateID()
+	_, err = comments.Move(ctx, reply.ID, &missing, post.ID)
+	assert.ErrorIs(t, err, domain.ErrParentCommentNotFound)
+	_, err = comments.Move(ctx, reply.ID, nil, missing)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+
+	moved, err := comments.Move(ctx, reply.ID, &other.ID, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID+"."+reply.ID, moved.Path)
+	require.NotNil(t, moved.ParentID)
+	assert.Equal(t, other.ID, *moved.ParentID)
+
+	got, err := comments.GetByID(ctx, nested.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID+"."+reply.ID+"."+nested.ID, got.Path)
+	assert.Equal(t, 2, got.Depth)
+
+	stats, err := comments.CommentStats(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{}, stats)
+	stats, err = comments.CommentStats(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 2}, stats)
+
+	// перенос в корень другого поста снимает закрепление и пересчитывает посты
+	_, err = comments.Pin(ctx, reply.ID, 1)
+	require.NoError(t, err)
+	moved, err = comments.Move(ctx, reply.ID, nil, target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reply.ID, moved.Path)
+	assert.Equal(t, 0, moved.Depth)
+	assert.Nil(t, moved.ParentID)
+	assert.Nil(t, moved.PinnedAt)
+
+	got, err = comments.GetByID(ctx, nested.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, got.PostID)
+	assert.Equal(t, reply.ID+"."+nested.ID, got.Path)
+	assert.Equal(t, 1, got.Depth)
+
+	postStats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, postStats.CommentCount)
+	postStats, err = comments.PostStats(ctx, target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, postStats.CommentCount)
+	assert.Equal(t, 2, postStats.ParticipantCount)
+	stats, err = comments.CommentStats(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CommentStats{}, stats)
+
+	pinned, err := comments.GetPinned(ctx, target.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pinned)
+}
+
+// testConcurrentMoves переносит две ветки навстречу друг другу и одновременно
+// отвечает внутри одной из них: цикла быть не должно, а пути - устаревать
+func testConcurrentMoves(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	newComment := commentFactory(t, comments, post.ID)
+
+	for i := 0; i < 10; i++ {
+		x := newComment("a", nil)
+		xChild := newComment("b", &x.ID)
+		y := newComment("a", nil)
+		yChild := newComment("b", &y.ID)
+		reply, err := domain.NewComment(post.ID, "c", &xChild.ID, "text")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 3)
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, errs[0] = comments.Move(ctx, x.ID, &yChild.ID, "")
+		}()
+		go func() {
+			defer wg.Done()
+			_, errs[1] = comments.Move(ctx, y.ID, &xChild.ID, "")
+		}()
+		go func() {
+			defer wg.Done()
+			errs[2] = comments.Create(ctx, reply)
+		}()
+		wg.Wait()
+
+		require.NoError(t, errs[2])
+		require.True(t, (errs[0] == nil) != (errs[1] == nil), "exactly one move must succeed: %v", errs)
+		for _, err := range errs[:2] {
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrCommentCycle)
+			}
+		}
+
+		for _, id := range []string{x.ID, xChild.ID, y.ID, yChild.ID, reply.ID} {
+			got, err := comments.GetByID(ctx, id)
+			require.NoError(t, err)
+			if got.ParentID == nil {
+				assert.Equal(t, got.ID, got.Path)
+				continue
+			}
+			parent, err := comments.GetByID(ctx, *got.ParentID)
+			require.NoError(t, err)
+			assert.Equal(t, parent.Path+"."+got.ID, got.Path)
+			assert.Equal(t, parent.Depth+1, got.Depth)
+		}
+	}
+}
+
+func testDeleteByPost(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	other := createPost(t, posts, unique("author"))
+
+	root := commentFactory(t, comments, post.ID)("a", nil)
+	kept := commentFactory(t, comments, other.ID)("a", nil)
+
+	require.NoError(t, comments.DeleteByPost(ctx, post.ID))
+
+	_, err := comments.GetByID(ctx, root.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+	_, err = comments.GetByID(ctx, kept.ID)
+	assert.NoError(t, err)
+
+	stats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.CommentCount)
+	_, err = posts.GetByID(ctx, post.ID)
+	assert.NoError(t, err)
+
+	// пост снова принимает комментарии
+	commentFactory(t, comments, post.ID)("b", nil)
+	stats, err = comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.CommentCount)
+}
+
+// testCommentSnapshots проверяет, что изменения не пишутся в комментарии,
+// уже отданные вызывающему коду: он читает их без блокировок хранилища
+func testCommentSnapshots(t *testing.T, posts repository.PostRepository, comments repository.CommentRepository) {
+	ctx := context.Background()
+	post := createPost(t, posts, unique("author"))
+	create := commentFactory(t, comments, post.ID)
+	root := create("a", nil)
+
+	read, err := comments.GetByID(ctx, root.ID)
+	require.NoError(t, err)
+
+	locked, err := comments.SetLocked(ctx, root.ID, true)
+	require.NoError(t, err)
+	assert.True(t, locked.Locked)
+	assert.False(t, read.Locked)
+	assert.False(t, root.Locked)
+
+	pinned, err := comments.Pin(ctx, root.ID, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, pinned.PinnedAt)
+	assert.Nil(t, locked.PinnedAt)
+
+	unpinned, err := comments.Unpin(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Nil(t, unpinned.PinnedAt)
+	assert.NotNil(t, pinned.PinnedAt)
+
+	target := create("a", nil)
+	moved, err := comments.Move(ctx, root.ID, &target.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved.Depth)
+	assert.Zero(t, read.Depth)
+	assert.Nil(t, unpinned.ParentID)
+
+	pending, err := domain.NewComment(post.ID, "b", nil, "held")
+	require.NoError(t, err)
+	pending.Pending = true
+	require.NoError(t, comments.Create(ctx, pending))
+	held, err := comments.GetByID(ctx, pending.ID)
+	require.NoError(t, err)
+
+	approved, _, err := comments.Approve(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.False(t, approved.Pending)
+	assert.True(t, held.Pending)
+	assert.True(t, pending.Pending)
+}
@@ -0,0 +1,661 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+const commentColumns = `id, post_id, parent_id, author, content, path, depth, created_at, pending, mentions, locked, pinned_at`
+
+// inSubtree - условие на ветку с корнем по пути ?1. Диапазон строк вместо
+// LIKE использует индекс по path и не путает регистр
+const inSubtree = `(path = ?1 OR (path >= ?1 || '.' AND path < ?1 || '/'))`
+
+func scanComment(row rowScanner) (*domain.Comment, error) {
+	var comment domain.Comment
+	var scannedParentID, pinnedAt sql.NullString
+	var createdAt, mentions string
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&scannedParentID,
+		&comment.Author,
+		&comment.Content,
+		&comment.Path,
+		&comment.Depth,
+		&createdAt,
+		&comment.Pending,
+		&mentions,
+		&comment.Locked,
+		&pinnedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if scannedParentID.Valid {
+		comment.ParentID = &scannedParentID.String
+	}
+	if comment.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if comment.PinnedAt, err = parseNullTime(pinnedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(mentions), &comment.Mentions); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func scanComments(rows *sql.Rows) ([]*domain.Comment, error) {
+	defer rows.Close()
+
+	var comments []*domain.Comment
+
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan comment %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return comments, nil
+}
+
+type SQLiteCommentRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteCommentRepository(db *sql.DB) *SQLiteCommentRepository {
+	return &SQLiteCommentRepository{db: db}
+}
+
+func (r *SQLiteCommentRepository) Create(ctx context.Context, comment *domain.Comment) error {
+	if utf8.RuneCountInString(comment.Content) > domain.MaxCommentLength {
+		return domain.ErrCommentTooLong
+	}
+
+	insertQuery := `INSERT INTO comments (` + commentColumns + `)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	// nil сохранился бы как JSON null
+	mentions := comment.Mentions
+	if mentions == nil {
+		mentions = []domain.Mention{}
+	}
+	mentionsJSON, err := json.Marshal(mentions)
+	if err != nil {
+		return err
+	}
+
+	err = withTx(ctx, r.db, func(tx *sql.Tx) error {
+		if comment.ParentID == nil {
+			comment.Depth = 0
+			comment.Path = comment.ID
+		} else {
+			var parentPath string
+			var parentDepth int
+
+			query := `SELECT path, depth FROM comments WHERE id = ?`
+			err := tx.QueryRowContext(ctx, query, *comment.ParentID).Scan(&parentPath, &parentDepth)
+			if err == sql.ErrNoRows {
+				return domain.ErrParentCommentNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			locked, err := pathLocked(ctx, tx, parentPath)
+			if err != nil {
+				return err
+			}
+			if locked {
+				return domain.ErrThreadLocked
+			}
+
+			comment.Depth = parentDepth + 1
+			comment.Path = parentPath + "." + comment.ID
+		}
+
+		_, err := tx.ExecContext(ctx, insertQuery,
+			comment.ID,
+			comment.PostID,
+			comment.ParentID,
+			comment.Author,
+			comment.Content,
+			comment.Path,
+			comment.Depth,
+			formatTime(comment.CreatedAt),
+			comment.Pending,
+			string(mentionsJSON),
+			comment.Locked,
+			formatNullTime(comment.PinnedAt),
+		)
+		if err != nil {
+			return err
+		}
+
+		if comment.Pending {
+			return nil
+		}
+		return recordVisible(ctx, tx, comment)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrParentCommentNotFound), errors.Is(err, domain.ErrThreadLocked):
+			return err
+		case isForeignKeyViolation(err):
+			return domain.ErrPostNotFound
+		}
+		return fmt.Errorf("failed create comment %w", err)
+	}
+
+	return nil
+}
+
+// pathLocked проверяет блокировку у всех комментариев пути по первичному ключу
+func pathLocked(ctx context.Context, tx *sql.Tx, path string) (bool, error) {
+	ids, err := jsonArray(strings.Split(path, "."))
+	if err != nil {
+		return false, err
+	}
+
+	var locked bool
+	query := `SELECT EXISTS (SELECT 1 FROM comments WHERE id IN (SELECT value FROM json_each(?)) AND locked)`
+	err = tx.QueryRowContext(ctx, query, ids).Scan(&locked)
+	return locked, err
+}
+
+func (r *SQLiteCommentRepository) GetByID(ctx context.Context, id string) (*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE id = ?`
+
+	comment, err := scanComment(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed get comment by id %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *SQLiteCommentRepository) GetByPost(ctx context.Context, postID string, limit int, offset int) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + `
+			  FROM comments WHERE post_id = ? AND parent_id IS NULL AND NOT pending
+			  ORDER BY created_at ASC
+			  LIMIT ? OFFSET ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed get comments by post %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *SQLiteCommentRepository) GetChildren(ctx context.Context, parentID string, limit int, offset int) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + `
+			  FROM comments WHERE parent_id = ? AND NOT pending
+			  ORDER BY created_at ASC
+			  LIMIT ? OFFSET ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, parentID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed get children comments %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *SQLiteCommentRepository) ListByPost(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments
+			  WHERE post_id = ?
+			  ORDER BY depth, created_at`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed list comments %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *SQLiteCommentRepository) DeleteByPost(ctx context.Context, postID string) error {
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE post_id = ?`, postID); err != nil {
+			return err
+		}
+		return recountPost(ctx, tx, postID)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed delete comments %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteCommentRepository) GetWithAncestors(ctx context.Context, id string, depth int) ([]*domain.Comment, error) {
+	comment, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// предки берутся из материализованного пути комментария
+	ids, err := jsonArray(comment.AncestorIDs())
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + commentColumns + ` FROM comments
+			  WHERE id IN (SELECT value FROM json_each(?)) AND depth >= ?
+			  ORDER BY depth ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, ids, comment.Depth-depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed get comment ancestors %w", err)
+	}
+
+	ancestors, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ancestors, comment), nil
+}
+
+func (r *SQLiteCommentRepository) LastCommentTime(ctx context.Context, postID string, author string) (time.Time, error) {
+	query := `SELECT MAX(created_at) FROM comments WHERE post_id = ? AND author = ?`
+
+	var last sql.NullString
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID, author).Scan(&last)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed get last comment time %w", err)
+	}
+
+	if !last.Valid {
+		return time.Time{}, nil
+	}
+	return parseTime(last.String)
+}
+
+func (r *SQLiteCommentRepository) Approve(ctx context.Context, id string) (*domain.Comment, bool, error) {
+	query := `UPDATE comments SET pending = FALSE WHERE id = ? AND pending
+			  RETURNING ` + commentColumns
+
+	var comment *domain.Comment
+	approved := false
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		comment, err = scanComment(tx.QueryRowContext(ctx, query, id))
+		if err == sql.ErrNoRows {
+			// уже одобрен - счетчики не меняются
+			comment, err = scanComment(tx.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+			if err == sql.ErrNoRows {
+				return domain.ErrCommentNotFound
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		approved = true
+		return recordVisible(ctx, tx, comment)
+	})
+	if errors.Is(err, domain.ErrCommentNotFound) {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed approve comment %w", err)
+	}
+
+	return comment, approved, nil
+}
+
+func (r *SQLiteCommentRepository) SetLocked(ctx context.Context, id string, locked bool) (*domain.Comment, error) {
+	query := `UPDATE comments SET locked = ? WHERE id = ?
+			  RETURNING ` + commentColumns
+
+	comment, err := scanComment(conn(ctx, r.db).QueryRowContext(ctx, query, locked, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed lock thread %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *SQLiteCommentRepository) ThreadLocked(ctx context.Context, id string) (bool, error) {
+	var locked bool
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var path string
+		err := tx.QueryRowContext(ctx, `SELECT path FROM comments WHERE id = ?`, id).Scan(&path)
+		if err == sql.ErrNoRows {
+			return domain.ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		locked, err = pathLocked(ctx, tx, path)
+		return err
+	})
+
+	if errors.Is(err, domain.ErrCommentNotFound) {
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed check thread lock %w", err)
+	}
+
+	return locked, nil
+}
+
+func (r *SQLiteCommentRepository) Pin(ctx context.Context, id string, maxPins int) (*domain.Comment, error) {
+	var comment *domain.Comment
+	// запись в SQLite одна на базу, поэтому лимит не обойти параллельным закреплением
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		comment, err = scanComment(tx.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+		if err == sql.ErrNoRows {
+			return domain.ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if comment.PinnedAt != nil {
+			return nil
+		}
+
+		var pinned int
+		query := `SELECT COUNT(*) FROM comments WHERE post_id = ? AND pinned_at IS NOT NULL`
+		if err := tx.QueryRowContext(ctx, query, comment.PostID).Scan(&pinned); err != nil {
+			return err
+		}
+		if pinned >= maxPins {
+			return &domain.TooManyPinsError{MaxPins: maxPins}
+		}
+
+		query = `UPDATE comments SET pinned_at = ? WHERE id = ? RETURNING ` + commentColumns
+		comment, err = scanComment(tx.QueryRowContext(ctx, query, formatTime(time.Now()), id))
+		return err
+	})
+
+	if err != nil {
+		var pinsErr *domain.TooManyPinsError
+		if errors.Is(err, domain.ErrCommentNotFound) || errors.As(err, &pinsErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed pin comment %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *SQLiteCommentRepository) Unpin(ctx context.Context, id string) (*domain.Comment, error) {
+	query := `UPDATE comments SET pinned_at = NULL WHERE id = ?
+			  RETURNING ` + commentColumns
+
+	comment, err := scanComment(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed unpin comment %w", err)
+	}
+
+	return comment, nil
+}
+
+func (r *SQLiteCommentRepository) GetPinned(ctx context.Context, postID string) ([]*domain.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments
+			  WHERE post_id = ? AND pinned_at IS NOT NULL
+			  ORDER BY pinned_at`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed get pinned comments %w", err)
+	}
+
+	return scanComments(rows)
+}
+
+func (r *SQLiteCommentRepository) Move(ctx context.Context, id string, parentID *string, postID string) (*domain.Comment, error) {
+	selectQuery := `SELECT ` + commentColumns + ` FROM comments WHERE id = ?`
+
+	var comment *domain.Comment
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		comment, err = scanComment(tx.QueryRowContext(ctx, selectQuery, id))
+		if err == sql.ErrNoRows {
+			return domain.ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var parent *domain.Comment
+		if parentID != nil {
+			parent, err = scanComment(tx.QueryRowContext(ctx, selectQuery, *parentID))
+			if err == sql.ErrNoRows {
+				return domain.ErrParentCommentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			postID = parent.PostID
+		}
+
+		newPath, newDepth, err := comment.MoveUnder(parent)
+		if err != nil {
+			return err
+		}
+
+		var visible int
+		query := `SELECT COUNT(*) FROM comments WHERE ` + inSubtree + ` AND NOT pending`
+		if err := tx.QueryRowContext(ctx, query, comment.Path).Scan(&visible); err != nil {
+			return err
+		}
+		if err := shiftCounters(ctx, tx, comment, visible, -1); err != nil {
+			return err
+		}
+
+		// вся ветка переносится одним UPDATE по префиксу пути
+		query = `UPDATE comments SET
+					path = ?2 || substr(path, length(?1) + 1),
+					depth = depth + ?3,
+					pinned_at = CASE WHEN post_id = ?4 THEN pinned_at END,
+					post_id = ?4,
+					parent_id = CASE WHEN id = ?5 THEN ?6 ELSE parent_id END
+				 WHERE ` + inSubtree
+		_, err = tx.ExecContext(ctx, query, comment.Path, newPath, newDepth-comment.Depth, postID, comment.ID, parentID)
+		if err != nil {
+			return err
+		}
+
+		oldPostID := comment.PostID
+		comment.Path, comment.Depth, comment.PostID, comment.ParentID = newPath, newDepth, postID, parentID
+		if err := shiftCounters(ctx, tx, comment, visible, 1); err != nil {
+			return err
+		}
+
+		if oldPostID == postID {
+			return nil
+		}
+		comment.PinnedAt = nil
+		if err := recountPost(ctx, tx, oldPostID); err != nil {
+			return err
+		}
+		return recountPost(ctx, tx, postID)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrCommentNotFound),
+			errors.Is(err, domain.ErrParentCommentNotFound),
+			errors.Is(err, domain.ErrCommentCycle):
+			return nil, err
+		case isForeignKeyViolation(err):
+			return nil, domain.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed move comment %w", err)
+	}
+
+	return comment, nil
+}
+
+// shiftCounters добавляет ветку из visible видимых комментариев к счетчикам
+// предков комментария (sign = 1) или убирает ее (sign = -1)
+func shiftCounters(ctx context.Context, tx *sql.Tx, comment *domain.Comment, visible int, sign int) error {
+	if comment.ParentID == nil {
+		return nil
+	}
+
+	if !comment.Pending {
+		query := `UPDATE comments SET reply_count = reply_count + ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, sign, *comment.ParentID); err != nil {
+			return fmt.Errorf("failed update reply count %w", err)
+		}
+	}
+
+	ids, err := jsonArray(comment.AncestorIDs())
+	if err != nil {
+		return err
+	}
+	query := `UPDATE comments SET descendant_count = descendant_count + ? WHERE id IN (SELECT value FROM json_each(?))`
+	if _, err := tx.ExecContext(ctx, query, sign*visible, ids); err != nil {
+		return fmt.Errorf("failed update descendant count %w", err)
+	}
+	return nil
+}
+
+// recountPost пересчитывает счетчики поста по его комментариям
+func recountPost(ctx context.Context, tx *sql.Tx, postID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_participants WHERE post_id = ?`, postID); err != nil {
+		return fmt.Errorf("failed clear participants %w", err)
+	}
+
+	query := `INSERT INTO post_participants (post_id, author)
+			  SELECT DISTINCT post_id, author FROM comments WHERE post_id = ? AND NOT pending`
+	if _, err := tx.ExecContext(ctx, query, postID); err != nil {
+		return fmt.Errorf("failed recount participants %w", err)
+	}
+
+	// пост может быть уже удален, тогда строку счетчиков создавать нельзя
+	query = `INSERT INTO post_stats (post_id, comment_count, participant_count, last_comment_at)
+			 SELECT ?1, COUNT(*), COUNT(DISTINCT author), MAX(created_at)
+			 FROM comments WHERE post_id = ?1 AND NOT pending
+			 AND EXISTS (SELECT 1 FROM posts WHERE id = ?1)
+			 ON CONFLICT (post_id) DO UPDATE SET
+			 	comment_count = excluded.comment_count,
+			 	participant_count = excluded.participant_count,
+			 	last_comment_at = excluded.last_comment_at`
+	if _, err := tx.ExecContext(ctx, query, postID); err != nil {
+		return fmt.Errorf("failed recount post stats %w", err)
+	}
+	return nil
+}
+
+// recordVisible обновляет счетчики поста и предков комментария,
+// который стал виден в ветке
+func recordVisible(ctx context.Context, tx *sql.Tx, comment *domain.Comment) error {
+	if comment.ParentID != nil {
+		query := `UPDATE comments SET reply_count = reply_count + 1 WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, *comment.ParentID); err != nil {
+			return fmt.Errorf("failed update reply count %w", err)
+		}
+
+		ids, err := jsonArray(comment.AncestorIDs())
+		if err != nil {
+			return err
+		}
+		query = `UPDATE comments SET descendant_count = descendant_count + 1 WHERE id IN (SELECT value FROM json_each(?))`
+		if _, err := tx.ExecContext(ctx, query, ids); err != nil {
+			return fmt.Errorf("failed update descendant count %w", err)
+		}
+	}
+
+	query := `INSERT INTO post_participants (post_id, author) VALUES (?, ?) ON CONFLICT DO NOTHING`
+	result, err := tx.ExecContext(ctx, query, comment.PostID, comment.Author)
+	if err != nil {
+		return fmt.Errorf("failed add participant %w", err)
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed add participant %w", err)
+	}
+
+	// MAX от двух аргументов в SQLite - скалярная функция, NULL надо убрать заранее
+	query = `INSERT INTO post_stats (post_id, comment_count, participant_count, last_comment_at)
+			 VALUES (?, 1, ?, ?)
+			 ON CONFLICT (post_id) DO UPDATE SET
+			 	comment_count = post_stats.comment_count + 1,
+			 	participant_count = post_stats.participant_count + excluded.participant_count,
+			 	last_comment_at = MAX(COALESCE(post_stats.last_comment_at, ''), excluded.last_comment_at)`
+	if _, err := tx.ExecContext(ctx, query, comment.PostID, added, formatTime(comment.CreatedAt)); err != nil {
+		return fmt.Errorf("failed update post stats %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteCommentRepository) PostStats(ctx context.Context, postID string) (domain.PostStats, error) {
+	query := `SELECT comment_count, participant_count, last_comment_at FROM post_stats WHERE post_id = ?`
+
+	var stats domain.PostStats
+	var lastCommentAt sql.NullString
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID).Scan(&stats.CommentCount, &stats.ParticipantCount, &lastCommentAt)
+	if err == sql.ErrNoRows {
+		// строки нет, пока у поста нет комментариев
+		return domain.PostStats{}, nil
+	}
+	if err != nil {
+		return domain.PostStats{}, fmt.Errorf("failed get post stats %w", err)
+	}
+
+	if stats.LastCommentAt, err = parseNullTime(lastCommentAt); err != nil {
+		return domain.PostStats{}, fmt.Errorf("failed get post stats %w", err)
+	}
+	return stats, nil
+}
+
+func (r *SQLiteCommentRepository) CommentStats(ctx context.Context, commentID string) (domain.CommentStats, error) {
+	query := `SELECT reply_count, descendant_count FROM comments WHERE id = ?`
+
+	var stats domain.CommentStats
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, commentID).Scan(&stats.ReplyCount, &stats.DescendantCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.CommentStats{}, domain.ErrCommentNotFound
+		}
+		return domain.CommentStats{}, fmt.Errorf("failed get comment stats %w", err)
+	}
+
+	return stats, nil
+}
+
+func (r *SQLiteCommentRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	query := `SELECT DISTINCT author FROM comments WHERE author IN (SELECT value FROM json_each(?))`
+
+	return queryAuthors(ctx, conn(ctx, r.db), query, authors)
+}
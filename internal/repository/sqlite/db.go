@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.up.sql
+var migrations embed.FS
+
+// Open открывает файл базы и применяет новые миграции. Транзакции берут
+// блокировку на запись сразу, чтобы параллельные записи ждали друг друга,
+// а не падали с SQLITE_BUSY
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed open sqlite %w", err)
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrate применяет по порядку миграции, версии которых еще нет в schema_migrations
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed create schema_migrations %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		version, err := strconv.Atoi(name[:strings.IndexByte(name, '_')])
+		if err != nil {
+			return fmt.Errorf("bad migration name %s", name)
+		}
+
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		err = withTx(ctx, db, func(tx *sql.Tx) error {
+			var applied bool
+			query := `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`
+			if err := tx.QueryRowContext(ctx, query, version).Scan(&applied); err != nil || applied {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed apply migration %s %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// withTx - аналог pgx.BeginFunc: коммит, если fn не вернула ошибку.
+// В единице работы fn выполняется в ее транзакции под точкой сохранения
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return withSavepoint(ctx, tx, fn)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT repository`); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.ExecContext(ctx, `ROLLBACK TO repository`)
+		tx.ExecContext(ctx, `RELEASE repository`)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `RELEASE repository`)
+	return err
+}
+
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+// timeLayout - время в UTC фиксированной ширины: строки сравниваются и
+// сортируются так же, как время
+const timeLayout = "2006-01-02 15:04:05.000000000"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(timeLayout, s, time.UTC)
+}
+
+func parseNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
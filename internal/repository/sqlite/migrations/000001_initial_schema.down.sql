@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS post_votes;
+DROP TABLE IF EXISTS post_participants;
+DROP TABLE IF EXISTS post_stats;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
\ No newline at end of file
@@ -0,0 +1,78 @@
+-- схема SQLite повторяет миграции Postgres 000001-000011 для постов и комментариев.
+-- Время хранится текстом в UTC фиксированной ширины, чтобы сравнение строк совпадало со сравнением времени
+CREATE TABLE IF NOT EXISTS posts (
+    id                 TEXT PRIMARY KEY,
+    title              TEXT NOT NULL,
+    content            TEXT NOT NULL,
+    author             TEXT NOT NULL,
+    allow_comments     INTEGER NOT NULL DEFAULT 1,
+    created_at         TEXT NOT NULL,
+    max_reply_depth    INTEGER NOT NULL DEFAULT 0,
+    max_comment_length INTEGER NOT NULL DEFAULT 2000,
+    slow_mode_seconds  INTEGER NOT NULL DEFAULT 0,
+    require_approval   INTEGER NOT NULL DEFAULT 0,
+    status             TEXT NOT NULL DEFAULT 'PUBLISHED',
+    publish_at         TEXT,
+    vote_score         INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_author ON posts(author);
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status);
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'SCHEDULED';
+
+CREATE TABLE IF NOT EXISTS comments (
+    id               TEXT PRIMARY KEY,
+    post_id          TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id        TEXT REFERENCES comments(id) ON DELETE CASCADE,
+    author           TEXT NOT NULL,
+    content          TEXT NOT NULL,
+    path             TEXT NOT NULL,
+    depth            INTEGER NOT NULL DEFAULT 0,
+    created_at       TEXT NOT NULL,
+    pending          INTEGER NOT NULL DEFAULT 0,
+    mentions         TEXT NOT NULL DEFAULT '[]',
+    locked           INTEGER NOT NULL DEFAULT 0,
+    pinned_at        TEXT,
+    reply_count      INTEGER NOT NULL DEFAULT 0,
+    descendant_count INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_path ON comments(path);
+CREATE INDEX IF NOT EXISTS idx_comments_post_author ON comments(post_id, author, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_author ON comments(author);
+CREATE INDEX IF NOT EXISTS idx_comments_pinned ON comments(post_id, pinned_at) WHERE pinned_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id   INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id  INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS post_stats (
+    post_id           TEXT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    comment_count     INTEGER NOT NULL DEFAULT 0,
+    participant_count INTEGER NOT NULL DEFAULT 0,
+    last_comment_at   TEXT
+);
+
+CREATE TABLE IF NOT EXISTS post_participants (
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author  TEXT NOT NULL,
+    PRIMARY KEY (post_id, author)
+);
+
+CREATE TABLE IF NOT EXISTS post_votes (
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    voter   TEXT NOT NULL,
+    value   INTEGER NOT NULL,
+    PRIMARY KEY (post_id, voter)
+);
\ No newline at end of file
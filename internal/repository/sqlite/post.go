@@ -0,0 +1,423 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+const postColumns = `id, title, content, author, allow_comments, created_at,
+	max_reply_depth, max_comment_length, slow_mode_seconds, require_approval, status, publish_at, vote_score`
+
+// postSelect добавляет к колонкам поста его теги JSON-массивом
+const postSelect = postColumns + `,
+	(SELECT json_group_array(name) FROM (
+		SELECT t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id = posts.id ORDER BY t.name
+	)) AS tags`
+
+func scanPost(row rowScanner) (*domain.Post, error) {
+	var post domain.Post
+	var createdAt, tags string
+	var publishAt sql.NullString
+
+	err := row.Scan(
+		&post.ID,
+		&post.Title,
+		&post.Content,
+		&post.Author,
+		&post.AllowComments,
+		&createdAt,
+		&post.Settings.MaxReplyDepth,
+		&post.Settings.MaxCommentLength,
+		&post.Settings.SlowModeSeconds,
+		&post.Settings.RequireApproval,
+		&post.Status,
+		&publishAt,
+		&post.VoteScore,
+		&tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if post.PublishAt, err = parseNullTime(publishAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &post.Tags); err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+func scanPosts(rows *sql.Rows) ([]*domain.Post, error) {
+	defer rows.Close()
+
+	var posts []*domain.Post
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed scan posts %w", err)
+		}
+
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error %w", err)
+	}
+
+	return posts, nil
+}
+
+type SQLitePostRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePostRepository(db *sql.DB) *SQLitePostRepository {
+	return &SQLitePostRepository{db: db}
+}
+
+func (r *SQLitePostRepository) Create(ctx context.Context, post *domain.Post) error {
+	if post.ID == "" {
+		post.ID = utils.GenerateID()
+	}
+	// время уже задано у постов, восстановленных из архива
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO posts (` + postColumns + `)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query,
+			post.ID,
+			post.Title,
+			post.Content,
+			post.Author,
+			post.AllowComments,
+			formatTime(post.CreatedAt),
+			post.Settings.MaxReplyDepth,
+			post.Settings.MaxCommentLength,
+			post.Settings.SlowModeSeconds,
+			post.Settings.RequireApproval,
+			post.Status,
+			formatNullTime(post.PublishAt),
+			post.VoteScore,
+		)
+		if err != nil {
+			return err
+		}
+
+		return setPostTags(ctx, tx, post.ID, post.Tags)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLitePostRepository) GetByID(ctx context.Context, id string) (*domain.Post, error) {
+	query := `SELECT ` + postSelect + ` FROM posts WHERE id = ?`
+
+	post, err := scanPost(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("id search post failed: %w", err)
+	}
+
+	return post, nil
+}
+
+func (r *SQLitePostRepository) GetAll(ctx context.Context, filter domain.PostFilter) ([]*domain.Post, error) {
+	// для ALL пост должен содержать все теги, для ANY - хотя бы один
+	query := `SELECT ` + postSelect + ` FROM posts
+			  WHERE (?1 = '' OR status = ?1) AND (?2 = '' OR author = ?2)
+			  AND (json_array_length(?3) = 0 OR (
+				  SELECT count(*) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+				  WHERE pt.post_id = posts.id AND t.name IN (SELECT value FROM json_each(?3))
+			  ) >= CASE WHEN ?4 = 'ALL' THEN json_array_length(?3) ELSE 1 END)`
+
+	tags, err := jsonArray(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, string(filter.Status), filter.Author, tags, string(filter.TagMatch))
+	if err != nil {
+		return nil, fmt.Errorf("failed get all posts %w", err)
+	}
+
+	return scanPosts(rows)
+}
+
+func (r *SQLitePostRepository) ToggleComments(ctx context.Context, postID string, allow bool) error {
+	query := `UPDATE posts SET allow_comments = ? WHERE id = ?`
+
+	return execOnPost(ctx, conn(ctx, r.db), "failed toggle comments", query, allow, postID)
+}
+
+func (r *SQLitePostRepository) Update(ctx context.Context, post *domain.Post) error {
+	query := `UPDATE posts SET title = ?, content = ?, author = ?, allow_comments = ?,
+			  status = ?, publish_at = ?
+			  WHERE id = ?`
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query,
+			post.Title,
+			post.Content,
+			post.Author,
+			post.AllowComments,
+			post.Status,
+			formatNullTime(post.PublishAt),
+			post.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			if err != nil {
+				return err
+			}
+			return domain.ErrPostNotFound
+		}
+
+		return setPostTags(ctx, tx, post.ID, post.Tags)
+	})
+
+	if errors.Is(err, domain.ErrPostNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed update post %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLitePostRepository) Delete(ctx context.Context, postID string) error {
+	query := `DELETE FROM posts WHERE id = ?`
+
+	return execOnPost(ctx, conn(ctx, r.db), "failed delete post", query, postID)
+}
+
+func (r *SQLitePostRepository) CheckAllowedComments(ctx context.Context, postID string) (bool, error) {
+	query := `SELECT allow_comments FROM posts WHERE id = ?`
+
+	var allowComments bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID).Scan(&allowComments)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, domain.ErrPostNotFound
+		}
+		return false, fmt.Errorf("failed check allow comments %w", err)
+	}
+
+	return allowComments, nil
+}
+
+func (r *SQLitePostRepository) UpdateSettings(ctx context.Context, postID string, settings domain.ThreadSettings) error {
+	query := `UPDATE posts SET max_reply_depth = ?, max_comment_length = ?, slow_mode_seconds = ?, require_approval = ?
+			  WHERE id = ?`
+
+	return execOnPost(ctx, conn(ctx, r.db), "failed update thread settings", query,
+		settings.MaxReplyDepth,
+		settings.MaxCommentLength,
+		settings.SlowModeSeconds,
+		settings.RequireApproval,
+		postID,
+	)
+}
+
+// execOnPost выполняет изменение одного поста и возвращает ErrPostNotFound,
+// если поста нет
+func execOnPost(ctx context.Context, db querier, message string, query string, args ...any) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s %w", message, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s %w", message, err)
+	}
+	if affected == 0 {
+		return domain.ErrPostNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLitePostRepository) PublishDue(ctx context.Context, now time.Time) ([]*domain.Post, error) {
+	query := `UPDATE posts SET status = 'PUBLISHED'
+			  WHERE status = 'SCHEDULED' AND publish_at <= ?
+			  RETURNING ` + postSelect
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, formatTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed publish due posts %w", err)
+	}
+
+	return scanPosts(rows)
+}
+
+func (r *SQLitePostRepository) Vote(ctx context.Context, postID, voter string, value int) (int, error) {
+	var delta int
+	// транзакция берет блокировку на запись сразу, поэтому голоса не гонятся
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO post_votes (post_id, voter, value) VALUES (?, ?, 0) ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, postID, voter); err != nil {
+			return err
+		}
+
+		var previous int
+		query = `SELECT value FROM post_votes WHERE post_id = ? AND voter = ?`
+		if err := tx.QueryRowContext(ctx, query, postID, voter).Scan(&previous); err != nil {
+			return err
+		}
+
+		delta = value - previous
+		if delta == 0 {
+			return nil
+		}
+
+		query = `UPDATE post_votes SET value = ? WHERE post_id = ? AND voter = ?`
+		if _, err := tx.ExecContext(ctx, query, value, postID, voter); err != nil {
+			return err
+		}
+
+		query = `UPDATE posts SET vote_score = vote_score + ? WHERE id = ?`
+		_, err := tx.ExecContext(ctx, query, delta, postID)
+		return err
+	})
+
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return 0, domain.ErrPostNotFound
+		}
+		return 0, fmt.Errorf("failed vote post %w", err)
+	}
+
+	return delta, nil
+}
+
+func (r *SQLitePostRepository) ListTags(ctx context.Context, limit int) ([]*domain.Tag, error) {
+	query := `SELECT t.name, count(*) FROM tags t
+			  JOIN post_tags pt ON pt.tag_id = t.id
+			  JOIN posts p ON p.id = pt.post_id
+			  WHERE p.status = 'PUBLISHED'
+			  GROUP BY t.name
+			  ORDER BY count(*) DESC, t.name
+			  LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed list tags %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, fmt.Errorf("failed scan tags %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed scan tags %w", err)
+	}
+
+	return tags, nil
+}
+
+// setPostTags заменяет теги поста, создавая новые теги при необходимости
+func setPostTags(ctx context.Context, tx *sql.Tx, postID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_tags WHERE post_id = ?`, postID); err != nil {
+		return fmt.Errorf("failed clear post tags %w", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	names, err := jsonArray(tags)
+	if err != nil {
+		return err
+	}
+
+	// WHERE true нужен SQLite, чтобы отличить ON CONFLICT от JOIN ... ON
+	query := `INSERT INTO tags (name) SELECT value FROM json_each(?) WHERE true ON CONFLICT (name) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, names); err != nil {
+		return fmt.Errorf("failed create tags %w", err)
+	}
+
+	query = `INSERT INTO post_tags (post_id, tag_id) SELECT ?, id FROM tags WHERE name IN (SELECT value FROM json_each(?))`
+	if _, err := tx.ExecContext(ctx, query, postID, names); err != nil {
+		return fmt.Errorf("failed set post tags %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLitePostRepository) KnownAuthors(ctx context.Context, authors []string) ([]string, error) {
+	query := `SELECT DISTINCT author FROM posts WHERE author IN (SELECT value FROM json_each(?))`
+
+	return queryAuthors(ctx, conn(ctx, r.db), query, authors)
+}
+
+func queryAuthors(ctx context.Context, db querier, query string, authors []string) ([]string, error) {
+	list, err := jsonArray(authors)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed get known authors %w", err)
+	}
+	defer rows.Close()
+
+	known := []string{}
+	for rows.Next() {
+		var author string
+		if err := rows.Scan(&author); err != nil {
+			return nil, fmt.Errorf("failed scan known authors %w", err)
+		}
+		known = append(known, author)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed scan known authors %w", err)
+	}
+
+	return known, nil
+}
+
+// jsonArray передает список параметром: в SQLite нет массивов,
+// запрос разворачивает его через json_each
+func jsonArray(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
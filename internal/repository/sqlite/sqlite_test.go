@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+)
+
+func openTestDB(t *testing.T) (*SQLitePostRepository, *SQLiteCommentRepository) {
+	t.Helper()
+
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewSQLitePostRepository(db), NewSQLiteCommentRepository(db)
+}
+
+func TestSQLitePostRepository(t *testing.T) {
+	ctx := context.Background()
+	posts, _ := openTestDB(t)
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	post.Tags = []string{"go", "sql"}
+	require.NoError(t, posts.Create(ctx, post))
+
+	other, err := domain.NewPost("t", "c", "b", true)
+	require.NoError(t, err)
+	other.Tags = []string{"go"}
+	require.NoError(t, posts.Create(ctx, other))
+
+	got, err := posts.GetByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"go", "sql"}, got.Tags)
+	assert.True(t, got.CreatedAt.Equal(post.CreatedAt))
+
+	all, err := posts.GetAll(ctx, domain.PostFilter{Tags: []string{"go", "sql"}, TagMatch: domain.TagMatchAll})
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, post.ID, all[0].ID)
+
+	tags, err := posts.ListTags(ctx, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, tags)
+	assert.Equal(t, "go", tags[0].Name)
+
+	delta, err := posts.Vote(ctx, post.ID, "b", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delta)
+	delta, err = posts.Vote(ctx, post.ID, "b", -1)
+	require.NoError(t, err)
+	assert.Equal(t, -2, delta)
+
+	require.NoError(t, posts.Delete(ctx, post.ID))
+	_, err = posts.GetByID(ctx, post.ID)
+	assert.ErrorIs(t, err, domain.ErrPostNotFound)
+}
+
+func TestSQLiteCommentRepository(t *testing.T) {
+	ctx := context.Background()
+	posts, comments := openTestDB(t)
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	require.NoError(t, posts.Create(ctx, post))
+
+	newComment := func(author string, parentID *string) *domain.Comment {
+		comment, err := domain.NewComment(post.ID, author, parentID, "text")
+		require.NoError(t, err)
+		require.NoError(t, comments.Create(ctx, comment))
+		// разное время создания, чтобы порядок был однозначным
+		time.Sleep(time.Millisecond)
+		return comment
+	}
+
+	root := newComment("a", nil)
+	reply := newComment("b", &root.ID)
+	nested := newComment("c", &reply.ID)
+	second := newComment("b", nil)
+
+	t.Run("path and depth", func(t *testing.T) {
+		got, err := comments.GetByID(ctx, nested.ID)
+		require.NoError(t, err)
+		assert.Equal(t, root.ID+"."+reply.ID+"."+nested.ID, got.Path)
+		assert.Equal(t, 2, got.Depth)
+
+		chain, err := comments.GetWithAncestors(ctx, nested.ID, 1)
+		require.NoError(t, err)
+		require.Len(t, chain, 2)
+		assert.Equal(t, reply.ID, chain[0].ID)
+		assert.Equal(t, nested.ID, chain[1].ID)
+	})
+
+	t.Run("ordering", func(t *testing.T) {
+		roots, err := comments.GetByPost(ctx, post.ID, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, roots, 2)
+		assert.Equal(t, root.ID, roots[0].ID)
+		assert.Equal(t, second.ID, roots[1].ID)
+
+		all, err := comments.ListByPost(ctx, post.ID)
+		require.NoError(t, err)
+		require.Len(t, all, 4)
+		assert.Equal(t, nested.ID, all[3].ID)
+	})
+
+	t.Run("counters", func(t *testing.T) {
+		postStats, err := comments.PostStats(ctx, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 4, postStats.CommentCount)
+		assert.Equal(t, 3, postStats.ParticipantCount)
+
+		stats, err := comments.CommentStats(ctx, root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 2}, stats)
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		_, err := comments.SetLocked(ctx, reply.ID, true)
+		require.NoError(t, err)
+
+		locked, err := comments.ThreadLocked(ctx, nested.ID)
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		blocked, err := domain.NewComment(post.ID, "d", &nested.ID, "text")
+		require.NoError(t, err)
+		assert.ErrorIs(t, comments.Create(ctx, blocked), domain.ErrThreadLocked)
+
+		_, err = comments.SetLocked(ctx, reply.ID, false)
+		require.NoError(t, err)
+	})
+
+	t.Run("pin", func(t *testing.T) {
+		_, err := comments.Pin(ctx, root.ID, 1)
+		require.NoError(t, err)
+		_, err = comments.Pin(ctx, second.ID, 1)
+		var pinsErr *domain.TooManyPinsError
+		assert.ErrorAs(t, err, &pinsErr)
+
+		pinned, err := comments.GetPinned(ctx, post.ID)
+		require.NoError(t, err)
+		require.Len(t, pinned, 1)
+		assert.Equal(t, root.ID, pinned[0].ID)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		_, err := comments.Move(ctx, root.ID, &nested.ID, post.ID)
+		assert.ErrorIs(t, err, domain.ErrCommentCycle)
+
+		moved, err := comments.Move(ctx, reply.ID, &second.ID, post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID+"."+reply.ID, moved.Path)
+
+		got, err := comments.GetByID(ctx, nested.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID+"."+reply.ID+"."+nested.ID, got.Path)
+		assert.Equal(t, 2, got.Depth)
+
+		stats, err := comments.CommentStats(ctx, root.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{}, stats)
+		stats, err = comments.CommentStats(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.CommentStats{ReplyCount: 1, DescendantCount: 2}, stats)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		orphan, err := domain.NewComment("missing", "a", nil, "text")
+		require.NoError(t, err)
+		assert.ErrorIs(t, comments.Create(ctx, orphan), domain.ErrPostNotFound)
+	})
+}
+
+func TestSQLiteUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	posts, comments := NewSQLitePostRepository(db), NewSQLiteCommentRepository(db)
+	units := NewUnitOfWork(db)
+
+	post, err := domain.NewPost("t", "c", "a", true)
+	require.NoError(t, err)
+	require.NoError(t, posts.Create(ctx, post))
+
+	failed := errors.New("failed")
+	var kept, dropped *domain.Comment
+	err = units.Do(ctx, func(ctx context.Context) error {
+		kept, err = domain.NewComment(post.ID, "a", nil, "text")
+		require.NoError(t, err)
+		require.NoError(t, comments.Create(ctx, kept))
+
+		// ошибка репозитория откатывает только его точку сохранения
+		missing := "missing"
+		orphan, err := domain.NewComment(post.ID, "a", &missing, "text")
+		require.NoError(t, err)
+		require.ErrorIs(t, comments.Create(ctx, orphan), domain.ErrParentCommentNotFound)
+
+		_, err = comments.GetByID(ctx, kept.ID)
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = units.Do(ctx, func(ctx context.Context) error {
+		dropped, err = domain.NewComment(post.ID, "a", nil, "text")
+		require.NoError(t, err)
+		require.NoError(t, comments.Create(ctx, dropped))
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	_, err = comments.GetByID(ctx, kept.ID)
+	assert.NoError(t, err)
+	_, err = comments.GetByID(ctx, dropped.ID)
+	assert.ErrorIs(t, err, domain.ErrCommentNotFound)
+
+	stats, err := comments.PostStats(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.CommentCount)
+}
@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+// LockKey - ключ advisory lock планировщика в Postgres
+const LockKey int64 = 0x5a5_9057
+
+// Locker дает право на запуск только одной реплике сервера
+type Locker interface {
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
+
+// LocalLocker подходит, когда сервер запущен в одном экземпляре
+type LocalLocker struct {
+	mu sync.Mutex
+}
+
+func (l *LocalLocker) TryLock(ctx context.Context) (func(), bool, error) {
+	if !l.mu.TryLock() {
+		return nil, false, nil
+	}
+	return l.mu.Unlock, true, nil
+}
+
+// Scheduler публикует запланированные посты, время которых пришло
+type Scheduler struct {
+	posts     repository.PostRepository
+	locker    Locker
+	interval  time.Duration
+	onPublish func(ctx context.Context, post *domain.Post)
+	now       func() time.Time
+}
+
+func New(posts repository.PostRepository, locker Locker, interval time.Duration, onPublish func(ctx context.Context, post *domain.Post)) *Scheduler {
+	return &Scheduler{
+		posts:     posts,
+		locker:    locker,
+		interval:  interval,
+		onPublish: onPublish,
+		now:       time.Now,
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Tick(ctx); err != nil {
+			log.Printf("failed publish scheduled posts %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick публикует посты, если удалось взять блокировку, и возвращает их число
+func (s *Scheduler) Tick(ctx context.Context) (int, error) {
+	unlock, ok, err := s.locker.TryLock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		// другая реплика уже публикует
+		return 0, nil
+	}
+	defer unlock()
+
+	posts, err := s.posts.PublishDue(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range posts {
+		s.onPublish(ctx, post)
+	}
+	return len(posts), nil
+}
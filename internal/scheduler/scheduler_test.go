@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+)
+
+func TestScheduler_Tick(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := inmemory.NewInMemoryPostRepository()
+
+	due := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+	for _, publishAt := range []time.Time{due, later} {
+		at := publishAt
+		post := &domain.Post{Title: "t", Content: "c", Author: "a", Status: domain.PostScheduled, PublishAt: &at}
+		require.NoError(t, repo.Create(ctx, post))
+	}
+
+	var published []*domain.Post
+	locker := &LocalLocker{}
+	s := New(repo, locker, time.Second, func(ctx context.Context, post *domain.Post) {
+		published = append(published, post)
+	})
+	s.now = func() time.Time { return now }
+
+	t.Run("skip, if lock is taken", func(t *testing.T) {
+		unlock, ok, err := locker.TryLock(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		n, err := s.Tick(ctx)
+		unlock()
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("publish due posts once", func(t *testing.T) {
+		n, err := s.Tick(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.Len(t, published, 1)
+		assert.Equal(t, domain.PostPublished, published[0].Status)
+		assert.Equal(t, due, *published[0].PublishAt)
+
+		n, err = s.Tick(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+}
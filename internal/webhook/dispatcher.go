@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+// Envelope - тело запроса к вебхуку
+type Envelope struct {
+	ID        string              `json:"id"`
+	Event     domain.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Data      any                 `json:"data"`
+}
+
+// Dispatcher ставит событие в очередь доставки подписанным вебхукам автора поста.
+// Отправкой занимается Worker
+type Dispatcher struct {
+	repo repository.WebhookRepository
+}
+
+func NewDispatcher(repo repository.WebhookRepository) *Dispatcher {
+	return &Dispatcher{repo: repo}
+}
+
+// Enqueue ставит событие в очередь вебхукам owner - автора поста, к которому оно относится
+func (d *Dispatcher) Enqueue(ctx context.Context, event domain.WebhookEvent, owner string, data any) error {
+	webhooks, err := d.repo.List(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	envelope := Envelope{
+		ID:        utils.GenerateID(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed marshal webhook payload %w", err)
+	}
+
+	var deliveries []*domain.WebhookDelivery
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(event) {
+			deliveries = append(deliveries, domain.NewWebhookDelivery(webhook.ID, event, payload))
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return d.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// Publish безопасен для nil-диспетчера. Мутация уже выполнена,
+// поэтому ошибка постановки в очередь только логируется
+func (d *Dispatcher) Publish(ctx context.Context, event domain.WebhookEvent, owner string, data any) {
+	if d == nil {
+		return
+	}
+	if err := d.Enqueue(ctx, event, owner, data); err != nil {
+		log.Printf("failed enqueue webhook %s %v", event, err)
+	}
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	HeaderEvent     = "X-SasPosts-Event"
+	HeaderDelivery  = "X-SasPosts-Delivery"
+	HeaderTimestamp = "X-SasPosts-Timestamp"
+	HeaderSignature = "X-SasPosts-Signature"
+)
+
+// Sign подписывает "timestamp.body", чтобы получатель мог отбросить
+// повтор старого запроса. Формат подписи: sha256=<hex>
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись за постоянное время. Нужен получателям и тестам
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository/inmemory"
+	"github.com/tmozzze/SasPosts/utils"
+)
+
+const testSecret = "0123456789abcdef"
+
+func newTestWorker(repo *inmemory.InMemoryWebhookRepository, now *time.Time) *Worker {
+	w := NewWorker(repo, WorkerConfig{
+		PollInterval: time.Second,
+		BatchSize:    10,
+		MaxAttempts:  3,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   15 * time.Second,
+		Timeout:      time.Second,
+	})
+	w.now = func() time.Time { return *now }
+	// httptest слушает loopback, поэтому проверка адресов в тестах отключена
+	w.client = &http.Client{Timeout: time.Second}
+	return w
+}
+
+// testWebhook создается в обход domain.NewWebhook, который не пропускает loopback
+func testWebhook(url string, events ...domain.WebhookEvent) *domain.Webhook {
+	return &domain.Webhook{
+		ID:        utils.GenerateID(),
+		Owner:     "alice",
+		URL:       url,
+		Events:    events,
+		Secret:    testSecret,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestWorker_Delivers(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewInMemoryWebhookRepository()
+
+	received := make(chan Envelope, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify(testSecret, timestamp, body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var envelope Envelope
+		_ = json.Unmarshal(body, &envelope)
+		received <- envelope
+	}))
+	defer receiver.Close()
+
+	webhook := testWebhook(receiver.URL, domain.EventPostCreated)
+	require.NoError(t, repo.Create(ctx, webhook))
+
+	dispatcher := NewDispatcher(repo)
+	require.NoError(t, dispatcher.Enqueue(ctx, domain.EventPostCreated, "alice", map[string]string{"id": "post-1"}))
+	require.NoError(t, dispatcher.Enqueue(ctx, domain.EventCommentCreated, "alice", map[string]string{"id": "comment-1"}))
+	require.NoError(t, dispatcher.Enqueue(ctx, domain.EventPostCreated, "bob", map[string]string{"id": "post-2"}))
+
+	now := time.Now()
+	processed, err := newTestWorker(repo, &now).ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed, "webhook is not subscribed to comment.created and posts of other authors")
+
+	envelope := <-received
+	assert.Equal(t, domain.EventPostCreated, envelope.Event)
+
+	deliveries, err := repo.ListDeliveries(ctx, webhook.ID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.DeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestWorker_RetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewInMemoryWebhookRepository()
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	webhook := testWebhook(receiver.URL, domain.EventCommentsToggled)
+	require.NoError(t, repo.Create(ctx, webhook))
+	require.NoError(t, NewDispatcher(repo).Enqueue(ctx, domain.EventCommentsToggled, "alice", nil))
+
+	now := time.Now()
+	worker := newTestWorker(repo, &now)
+
+	_, err := worker.ProcessDue(ctx)
+	require.NoError(t, err)
+	deliveries, _ := repo.ListDeliveries(ctx, webhook.ID, nil, 10)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, now.Add(10*time.Second), deliveries[0].NextAttemptAt)
+
+	// до следующей попытки доставка не берется
+	processed, _ := worker.ProcessDue(ctx)
+	assert.Equal(t, 0, processed)
+
+	now = now.Add(10 * time.Second)
+	_, _ = worker.ProcessDue(ctx)
+	deliveries, _ = repo.ListDeliveries(ctx, webhook.ID, nil, 10)
+	assert.Equal(t, now.Add(15*time.Second), deliveries[0].NextAttemptAt, "backoff is capped")
+
+	now = now.Add(15 * time.Second)
+	_, _ = worker.ProcessDue(ctx)
+	dead := domain.DeliveryDead
+	deliveries, _ = repo.ListDeliveries(ctx, webhook.ID, &dead, 10)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, "unexpected status 500", deliveries[0].LastError)
+
+	now = now.Add(time.Hour)
+	processed, _ = worker.ProcessDue(ctx)
+	assert.Equal(t, 0, processed)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWorker_RejectsInternalAddress(t *testing.T) {
+	ctx := context.Background()
+	repo := inmemory.NewInMemoryWebhookRepository()
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	// адрес проверяется при соединении, даже если вебхук прошел валидацию
+	webhook := testWebhook(receiver.URL, domain.EventPostCreated)
+	require.NoError(t, repo.Create(ctx, webhook))
+	require.NoError(t, NewDispatcher(repo).Enqueue(ctx, domain.EventPostCreated, "alice", nil))
+
+	worker := NewWorker(repo, WorkerConfig{BatchSize: 10, MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Second, Timeout: time.Second})
+	_, err := worker.ProcessDue(ctx)
+	require.NoError(t, err)
+
+	deliveries, _ := repo.ListDeliveries(ctx, webhook.ID, nil, 10)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.DeliveryFailed, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].LastError, errInternalAddr.Error())
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign(testSecret, 1700000000, []byte(`{}`))
+
+	assert.True(t, Verify(testSecret, 1700000000, []byte(`{}`), signature))
+	assert.False(t, Verify(testSecret, 1700000001, []byte(`{}`), signature))
+	assert.False(t, Verify("another-secret-value", 1700000000, []byte(`{}`), signature))
+}
@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tmozzze/SasPosts/internal/domain"
+	"github.com/tmozzze/SasPosts/internal/repository"
+)
+
+type WorkerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts - после стольких неудач доставка уходит в DEAD
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+}
+
+// Worker периодически забирает доставки из очереди и отправляет их.
+// Неудачные повторяются с экспоненциальной задержкой
+type Worker struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    WorkerConfig
+	now    func() time.Time
+}
+
+func NewWorker(repo repository.WebhookRepository, cfg WorkerConfig) *Worker {
+	return &Worker{
+		repo:   repo,
+		client: newClient(cfg.Timeout),
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+var errInternalAddr = errors.New("webhook target resolves to a loopback, private or link-local address")
+
+// newClient не соединяется с адресами внутренней сети. Проверка идет после
+// резолва имени, поэтому ее не обойти DNS-записью или редиректом
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if domain.InternalAddr(addrPort.Addr()) {
+				return errInternalAddr
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// через прокси проверялся бы адрес прокси, а не вебхука
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessDue(ctx); err != nil {
+			log.Printf("failed process webhook deliveries %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue отправляет одну пачку доставок и возвращает их число
+func (w *Worker) ProcessDue(ctx context.Context) (int, error) {
+	// lease с запасом покрывает таймаут всех запросов пачки
+	lease := w.cfg.Timeout*time.Duration(w.cfg.BatchSize) + time.Minute
+	deliveries, err := w.repo.ClaimDue(ctx, w.now(), lease, w.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[string]*domain.Webhook)
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = w.repo.GetByID(ctx, delivery.WebhookID)
+			if err != nil {
+				// вебхук удалили вместе с доставками
+				log.Printf("failed get webhook for delivery %s %v", delivery.ID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		w.deliver(ctx, webhook, delivery)
+
+		if err := w.repo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Printf("failed save webhook delivery %s %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+
+	status, err := w.send(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		now := w.now()
+		delivery.Status = domain.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= w.cfg.MaxAttempts {
+		delivery.Status = domain.DeliveryDead
+		return
+	}
+	delivery.Status = domain.DeliveryFailed
+	delivery.NextAttemptAt = w.now().Add(w.backoff(delivery.Attempts))
+}
+
+func (w *Worker) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := w.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff удваивает задержку с каждой попыткой: base, 2*base, 4*base...
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < w.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.cfg.MaxBackoff {
+		delay = w.cfg.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_comments_post_author;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS pending;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS max_reply_depth,
+    DROP COLUMN IF EXISTS max_comment_length,
+    DROP COLUMN IF EXISTS slow_mode_seconds,
+    DROP COLUMN IF EXISTS require_approval;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS max_reply_depth    INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS max_comment_length INT NOT NULL DEFAULT 2000,
+    ADD COLUMN IF NOT EXISTS slow_mode_seconds  INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS require_approval   BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_author ON comments(post_id, author, created_at);
\ No newline at end of file
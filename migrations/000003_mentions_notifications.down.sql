@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS notifications;
+
+DROP INDEX IF EXISTS idx_comments_author;
+DROP INDEX IF EXISTS idx_posts_author;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS mentions;
\ No newline at end of file
@@ -0,0 +1,18 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS mentions JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_posts_author ON posts(author);
+CREATE INDEX IF NOT EXISTS idx_comments_author ON comments(author);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id         VARCHAR(255) PRIMARY KEY,
+    recipient  VARCHAR(255) NOT NULL,
+    kind       VARCHAR(32) NOT NULL,
+    actor      VARCHAR(255) NOT NULL,
+    post_id    VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id VARCHAR(255) NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    read_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient ON notifications(recipient, created_at DESC);
\ No newline at end of file
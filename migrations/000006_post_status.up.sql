@@ -0,0 +1,8 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS status     VARCHAR(16) NOT NULL DEFAULT 'PUBLISHED',
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE posts SET publish_at = created_at WHERE publish_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status);
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'SCHEDULED';
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS post_participants;
+DROP TABLE IF EXISTS post_stats;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS reply_count,
+    DROP COLUMN IF EXISTS descendant_count;
\ No newline at end of file
@@ -0,0 +1,30 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS reply_count      INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS descendant_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_stats (
+    post_id           VARCHAR(255) PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    comment_count     INT NOT NULL DEFAULT 0,
+    participant_count INT NOT NULL DEFAULT 0,
+    last_comment_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS post_participants (
+    post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author  VARCHAR(255) NOT NULL,
+    PRIMARY KEY (post_id, author)
+);
+
+-- счетчики для уже существующих комментариев
+INSERT INTO post_participants (post_id, author)
+SELECT DISTINCT post_id, author FROM comments WHERE NOT pending
+ON CONFLICT DO NOTHING;
+
+INSERT INTO post_stats (post_id, comment_count, participant_count, last_comment_at)
+SELECT post_id, COUNT(*), COUNT(DISTINCT author), MAX(created_at) FROM comments WHERE NOT pending
+GROUP BY post_id
+ON CONFLICT (post_id) DO NOTHING;
+
+UPDATE comments c SET
+    reply_count = (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND NOT r.pending),
+    descendant_count = (SELECT COUNT(*) FROM comments d WHERE d.path LIKE c.path || '.%' AND NOT d.pending);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_votes;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS vote_score;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS vote_score INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_votes (
+    post_id VARCHAR(255) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    voter   VARCHAR(255) NOT NULL,
+    value   SMALLINT NOT NULL,
+    PRIMARY KEY (post_id, voter)
+);
\ No newline at end of file
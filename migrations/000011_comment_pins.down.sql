@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_comments_pinned;
+ALTER TABLE comments DROP COLUMN IF EXISTS pinned_at;
\ No newline at end of file
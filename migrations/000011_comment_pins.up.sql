@@ -0,0 +1,3 @@
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_comments_pinned ON comments (post_id, pinned_at) WHERE pinned_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_webhooks_owner;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS owner;
\ No newline at end of file
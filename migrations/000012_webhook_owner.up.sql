@@ -0,0 +1,4 @@
+-- вебхуки без владельца остаются в базе, но не получают события и не видны в API
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks (owner, created_at);
\ No newline at end of file